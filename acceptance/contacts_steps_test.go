@@ -12,7 +12,7 @@ import (
 
 // noContactExistsWithEmail verifies that no contact with the given email exists in the system.
 func (a *ApiFeature) noContactExistsWithEmail(email string) error {
-	response, err := a.ListContacts()
+	response, err := a.ListContacts(email)
 	if err != nil {
 		return err
 	}
@@ -38,10 +38,17 @@ func (a *ApiFeature) noContactExistsWithEmail(email string) error {
 
 // iSubmitAContactRequest submits a new contact request with the provided details.
 func (a *ApiFeature) iSubmitAContactRequest(name, email, message string) error {
+	challenge, nonce, err := a.SolveChallenge()
+	if err != nil {
+		return err
+	}
+
 	payload := map[string]string{
-		"name":    name,
-		"email":   email,
-		"message": message,
+		"name":      name,
+		"email":     email,
+		"message":   message,
+		"challenge": challenge,
+		"nonce":     nonce,
 	}
 	encoded, err := json.Marshal(payload)
 	if err != nil {
@@ -66,7 +73,7 @@ func (a *ApiFeature) iSubmitAContactRequest(name, email, message string) error {
 
 // theContactShouldBeAdded verifies that a contact with the given email has been added to the system.
 func (a *ApiFeature) theContactShouldBeAdded(email string) error {
-	response, err := a.ListContacts()
+	response, err := a.ListContacts(email)
 	if err != nil {
 		return err
 	}
@@ -92,7 +99,7 @@ func (a *ApiFeature) theContactShouldBeAdded(email string) error {
 
 // noDuplicateContact verifies that there is only one contact with the given email.
 func (a *ApiFeature) noDuplicateContact(email string) error {
-	response, err := a.ListContacts()
+	response, err := a.ListContacts(email)
 	if err != nil {
 		return err
 	}
@@ -122,7 +129,7 @@ func (a *ApiFeature) noDuplicateContact(email string) error {
 
 // contactExistsWithEmail verifies that a contact with the given email already exists.
 func (a *ApiFeature) contactExistsWithEmail(email string) error {
-	response, err := a.ListContacts()
+	response, err := a.ListContacts(email)
 	if err != nil {
 		return err
 	}
@@ -2,15 +2,30 @@ package main
 
 import (
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/cucumber/godog"
 )
 
 const (
-	API_BASE_URL = "https://api-dev.alpn-software.com"
+	DEFAULT_API_BASE_URL = "https://api-dev.alpn-software.com"
 )
 
+// API_BASE_URL is the base URL of the API under test. Defaults to the
+// DEV environment, but can be pointed at a local server (e.g. one using
+// the in-memory persistence backend) via the API_BASE_URL variable.
+var API_BASE_URL = apiBaseURLFromEnv()
+
+// apiBaseURLFromEnv returns the API base URL set in the environment,
+// falling back to DEFAULT_API_BASE_URL if not set.
+func apiBaseURLFromEnv() string {
+	if url := os.Getenv("API_BASE_URL"); url != "" {
+		return strings.TrimSuffix(url, "/")
+	}
+	return DEFAULT_API_BASE_URL
+}
+
 // TestMain is the entry point for running the acceptance tests using Godog.
 func TestMain(m *testing.M) {
 	opts := godog.Options{
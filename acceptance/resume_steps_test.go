@@ -61,10 +61,13 @@ func (a *ApiFeature) verifyJsonResume() error {
 
 	var payload struct {
 		Data struct {
+			Basics struct {
+				Name string `json:"name"`
+			} `json:"basics"`
 			Education []struct {
 			} `json:"education"`
-			Experience []struct {
-			} `json:"experience"`
+			Work []struct {
+			} `json:"work"`
 			Skills []struct {
 			} `json:"skills"`
 		} `json:"data"`
@@ -73,6 +76,9 @@ func (a *ApiFeature) verifyJsonResume() error {
 		return err
 	}
 
+	if payload.Data.Basics.Name == "" {
+		return fmt.Errorf("expected resume to follow the JSON Resume schema")
+	}
 	return nil
 }
 
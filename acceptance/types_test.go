@@ -1,10 +1,15 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math/bits"
 	"net/http"
+	neturl "net/url"
 	"os"
+	"strconv"
 )
 
 type ApiFeature struct {
@@ -28,18 +33,59 @@ func (a *ApiFeature) ExecuteRequest(req *http.Request) (*http.Response, error) {
 	return a.client.Do(req)
 }
 
-// ListContacts makes a request to the admin list contacts endpoint.
-func (a *ApiFeature) ListContacts() (*http.Response, error) {
+// ListContacts makes a request to the admin list contacts endpoint,
+// filtering results by the given email. Filtering is required as the
+// endpoint is paginated, so a contact may not be on the first page.
+func (a *ApiFeature) ListContacts(email string) (*http.Response, error) {
 	key := os.Getenv("ADMIN_API_KEY")
 	if key == "" {
 		return nil, errors.New("ADMIN_API_KEY environment variable is not set")
 	}
 	a.apiKey = &key
 
-	url := fmt.Sprintf("%s/api/v1/admin/contacts", API_BASE_URL)
+	url := fmt.Sprintf("%s/api/v1/admin/contacts?email=%s", API_BASE_URL, neturl.QueryEscape(email))
 	request, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 	return a.ExecuteRequest(request)
 }
+
+// SolveChallenge requests a proof-of-work challenge from the public
+// challenge endpoint and returns it along with the nonce solving it.
+func (a *ApiFeature) SolveChallenge() (string, string, error) {
+	url := fmt.Sprintf("%s/api/v1/public/challenge", API_BASE_URL)
+	response, err := a.client.Get(url)
+	if err != nil {
+		return "", "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("expected status code 200 for challenge, got %d", response.StatusCode)
+	}
+
+	var challenge struct {
+		Data struct {
+			Challenge  string `json:"challenge"`
+			Difficulty int    `json:"difficulty"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&challenge); err != nil {
+		return "", "", err
+	}
+
+	for nonce := 0; ; nonce++ {
+		hash := sha256.Sum256([]byte(challenge.Data.Challenge + ":" + strconv.Itoa(nonce)))
+		zeros := 0
+		for _, b := range hash {
+			zeros += bits.LeadingZeros8(b)
+			if b != 0 {
+				break
+			}
+		}
+		if zeros >= challenge.Data.Difficulty {
+			return challenge.Data.Challenge, strconv.Itoa(nonce), nil
+		}
+	}
+}
@@ -0,0 +1,42 @@
+"""added redeemed challenges
+
+Revision ID: 3f8a1c5e9b27
+Revises: 9d2c6b18e7a4
+Create Date: 2026-10-18 18:12:37.540118
+
+"""
+
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision: str = "3f8a1c5e9b27"
+down_revision: Union[str, None] = "9d2c6b18e7a4"
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    """Upgrade schema."""
+
+    op.create_table(
+        "redeemed_challenges",
+        sa.Column("id", sa.String, primary_key=True, nullable=False),
+        sa.Column("expires_at", sa.DateTime(), nullable=False),
+        schema="base",
+    )
+    op.create_index(
+        "redeemed_challenges_expires_at_idx",
+        "redeemed_challenges",
+        ["expires_at"],
+        schema="base",
+    )
+
+
+def downgrade() -> None:
+    """Downgrade schema."""
+
+    op.drop_table("redeemed_challenges", schema="base")
@@ -0,0 +1,63 @@
+"""added resume versions
+
+Revision ID: 5c9e2d7a4b18
+Revises: 3f8a1c5e9b27
+Create Date: 2026-10-18 21:40:12.318067
+
+"""
+
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision: str = "5c9e2d7a4b18"
+down_revision: Union[str, None] = "3f8a1c5e9b27"
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    """Upgrade schema."""
+
+    op.create_table(
+        "resume_versions",
+        sa.Column("id", sa.String, primary_key=True, nullable=False),
+        sa.Column("variant", sa.String, nullable=False),
+        sa.Column("locale", sa.String, nullable=False),
+        sa.Column("format", sa.String, nullable=False),
+        sa.Column("content", sa.LargeBinary(), nullable=False),
+        sa.Column("hash", sa.String, nullable=False),
+        sa.Column("author", sa.String, nullable=False),
+        sa.Column("active", sa.Boolean, server_default=sa.false(), nullable=False),
+        sa.Column(
+            "created_at", sa.DateTime(), server_default=sa.func.now(), nullable=False
+        ),
+        sa.Column(
+            "activated_at",
+            sa.DateTime(),
+            server_default=sa.func.now(),
+            nullable=False,
+        ),
+        sa.CheckConstraint(
+            "format IN ('json', 'pdf')",
+            name="resume_versions_format_check",
+        ),
+        schema="base",
+    )
+    op.create_index(
+        "resume_versions_active_idx",
+        "resume_versions",
+        ["variant", "locale", "format"],
+        unique=True,
+        schema="base",
+        postgresql_where=sa.text("active"),
+    )
+
+
+def downgrade() -> None:
+    """Downgrade schema."""
+
+    op.drop_table("resume_versions", schema="base")
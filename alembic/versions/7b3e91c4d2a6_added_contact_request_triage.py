@@ -0,0 +1,55 @@
+"""added contact request triage
+
+Revision ID: 7b3e91c4d2a6
+Revises: 224fdf859c42
+Create Date: 2026-10-18 09:12:41.518203
+
+"""
+
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision: str = "7b3e91c4d2a6"
+down_revision: Union[str, None] = "224fdf859c42"
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    """Upgrade schema."""
+
+    op.add_column(
+        "contact_requests",
+        sa.Column("status", sa.String, server_default="new", nullable=False),
+        schema="base",
+    )
+    op.add_column(
+        "contact_requests",
+        sa.Column("notes", sa.Text(), server_default="", nullable=False),
+        schema="base",
+    )
+
+    op.create_check_constraint(
+        "contact_requests_status_check",
+        "contact_requests",
+        "status IN ('new', 'read', 'replied', 'archived', 'spam')",
+        schema="base",
+    )
+    op.create_index(
+        "contact_requests_status_idx",
+        "contact_requests",
+        ["status", "created_at"],
+        schema="base",
+    )
+
+
+def downgrade() -> None:
+    """Downgrade schema."""
+
+    op.drop_index("contact_requests_status_idx", "contact_requests", schema="base")
+    op.drop_column("contact_requests", "notes", schema="base")
+    op.drop_column("contact_requests", "status", schema="base")
@@ -0,0 +1,54 @@
+"""added resume shares
+
+Revision ID: 8e4b6f1a3c92
+Revises: 5c9e2d7a4b18
+Create Date: 2026-10-18 23:05:47.902115
+
+"""
+
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision: str = "8e4b6f1a3c92"
+down_revision: Union[str, None] = "5c9e2d7a4b18"
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    """Upgrade schema."""
+
+    op.create_table(
+        "resume_shares",
+        sa.Column("id", sa.String, primary_key=True, nullable=False),
+        sa.Column("variant", sa.String, nullable=False),
+        sa.Column("locale", sa.String, nullable=False),
+        sa.Column("format", sa.String, nullable=False),
+        sa.Column("recipient", sa.String, server_default="", nullable=False),
+        sa.Column(
+            "single_use", sa.Boolean, server_default=sa.false(), nullable=False
+        ),
+        sa.Column("created_by", sa.String, nullable=False),
+        sa.Column(
+            "created_at", sa.DateTime(), server_default=sa.func.now(), nullable=False
+        ),
+        sa.Column("expires_at", sa.DateTime(), nullable=False),
+        sa.Column("opens", sa.Integer, server_default="0", nullable=False),
+        sa.Column("first_opened_at", sa.DateTime(), nullable=True),
+        sa.Column("last_opened_at", sa.DateTime(), nullable=True),
+        sa.CheckConstraint(
+            "format IN ('json', 'pdf', 'html', 'markdown', 'txt')",
+            name="resume_shares_format_check",
+        ),
+        schema="base",
+    )
+
+
+def downgrade() -> None:
+    """Downgrade schema."""
+
+    op.drop_table("resume_shares", schema="base")
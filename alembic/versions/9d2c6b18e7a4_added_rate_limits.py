@@ -0,0 +1,48 @@
+"""added rate limits
+
+Revision ID: 9d2c6b18e7a4
+Revises: e41a7d93b5f0
+Create Date: 2026-10-18 16:48:52.209377
+
+"""
+
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision: str = "9d2c6b18e7a4"
+down_revision: Union[str, None] = "e41a7d93b5f0"
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    """Upgrade schema."""
+
+    op.create_table(
+        "rate_limits",
+        sa.Column("key", sa.String, primary_key=True, nullable=False),
+        sa.Column("tokens", sa.Float(), nullable=False),
+        sa.Column(
+            "updated_at", sa.DateTime(), server_default=sa.func.now(), nullable=False
+        ),
+        sa.Column(
+            "full_at", sa.DateTime(), server_default=sa.func.now(), nullable=False
+        ),
+        schema="base",
+    )
+    op.create_index(
+        "rate_limits_full_at_idx",
+        "rate_limits",
+        ["full_at"],
+        schema="base",
+    )
+
+
+def downgrade() -> None:
+    """Downgrade schema."""
+
+    op.drop_table("rate_limits", schema="base")
@@ -0,0 +1,53 @@
+"""added resume downloads
+
+Revision ID: b7d25e8c0f41
+Revises: 8e4b6f1a3c92
+Create Date: 2026-10-19 09:12:31.584230
+
+"""
+
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision: str = "b7d25e8c0f41"
+down_revision: Union[str, None] = "8e4b6f1a3c92"
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    """Upgrade schema."""
+
+    op.create_table(
+        "resume_downloads",
+        sa.Column("id", sa.String, primary_key=True, nullable=False),
+        sa.Column("format", sa.String, nullable=False),
+        sa.Column("variant", sa.String, nullable=False),
+        sa.Column("locale", sa.String, nullable=False),
+        sa.Column("referrer", sa.String, server_default="", nullable=False),
+        sa.Column("user_agent", sa.String, server_default="", nullable=False),
+        sa.Column("ip_address", sa.String, nullable=False),
+        sa.Column(
+            "downloaded_at",
+            sa.DateTime(),
+            server_default=sa.func.now(),
+            nullable=False,
+        ),
+        schema="base",
+    )
+    op.create_index(
+        "resume_downloads_downloaded_at_idx",
+        "resume_downloads",
+        ["downloaded_at"],
+        schema="base",
+    )
+
+
+def downgrade() -> None:
+    """Downgrade schema."""
+
+    op.drop_table("resume_downloads", schema="base")
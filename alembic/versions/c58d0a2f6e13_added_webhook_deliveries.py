@@ -0,0 +1,64 @@
+"""added webhook deliveries
+
+Revision ID: c58d0a2f6e13
+Revises: 7b3e91c4d2a6
+Create Date: 2026-10-18 11:02:17.904412
+
+"""
+
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision: str = "c58d0a2f6e13"
+down_revision: Union[str, None] = "7b3e91c4d2a6"
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    """Upgrade schema."""
+
+    op.create_table(
+        "webhook_deliveries",
+        sa.Column("id", sa.String, primary_key=True, nullable=False),
+        sa.Column("subscription", sa.String, nullable=False),
+        sa.Column("event_id", sa.String, nullable=False),
+        sa.Column("event_type", sa.String, nullable=False),
+        sa.Column("payload", sa.Text(), nullable=False),
+        sa.Column("status", sa.String, server_default="pending", nullable=False),
+        sa.Column("attempts", sa.Integer, server_default="0", nullable=False),
+        sa.Column("response_status", sa.Integer, server_default="0", nullable=False),
+        sa.Column("last_error", sa.Text(), server_default="", nullable=False),
+        sa.Column(
+            "next_attempt_at",
+            sa.DateTime(),
+            server_default=sa.func.now(),
+            nullable=False,
+        ),
+        sa.Column("last_attempt_at", sa.DateTime(), nullable=True),
+        sa.Column(
+            "created_at", sa.DateTime(), server_default=sa.func.now(), nullable=False
+        ),
+        sa.CheckConstraint(
+            "status IN ('pending', 'delivered', 'failed')",
+            name="webhook_deliveries_status_check",
+        ),
+        schema="base",
+    )
+    op.create_index(
+        "webhook_deliveries_pending_idx",
+        "webhook_deliveries",
+        ["next_attempt_at"],
+        schema="base",
+        postgresql_where=sa.text("status = 'pending'"),
+    )
+
+
+def downgrade() -> None:
+    """Downgrade schema."""
+
+    op.drop_table("webhook_deliveries", schema="base")
@@ -0,0 +1,35 @@
+"""added contact request spam score
+
+Revision ID: e41a7d93b5f0
+Revises: c58d0a2f6e13
+Create Date: 2026-10-18 14:26:05.734918
+
+"""
+
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision: str = "e41a7d93b5f0"
+down_revision: Union[str, None] = "c58d0a2f6e13"
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    """Upgrade schema."""
+
+    op.add_column(
+        "contact_requests",
+        sa.Column("spam_score", sa.Integer(), server_default="0", nullable=False),
+        schema="base",
+    )
+
+
+def downgrade() -> None:
+    """Downgrade schema."""
+
+    op.drop_column("contact_requests", "spam_score", schema="base")
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// challengeAlgorithm is the hash that solutions are checked against
+	challengeAlgorithm = "sha256"
+	// challengeMaxNonceLength limits the size of submitted nonces
+	challengeMaxNonceLength = 64
+)
+
+// Challenge is a hashcash style proof-of-work challenge. Clients solve
+// it by finding a nonce for which the hash of "{challenge}:{nonce}"
+// starts with at least Difficulty zero bits.
+type Challenge struct {
+	Challenge  string    `json:"challenge"`
+	Difficulty int       `json:"difficulty"`
+	Algorithm  string    `json:"algorithm"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// ProofOfWork issues and verifies signed proof-of-work challenges. The
+// challenges are stateless, so that any replica sharing the secret can
+// verify them, and are only redeemed once they have been solved.
+type ProofOfWork struct {
+	// Secret is used to sign challenges
+	Secret     []byte
+	Difficulty int
+	TTL        time.Duration
+}
+
+// NewProofOfWork creates the ProofOfWork configured in the given config.
+func NewProofOfWork(cfg *Config) (*ProofOfWork, error) {
+	if cfg.ChallengeTTL <= 0 {
+		return nil, fmt.Errorf("invalid challenge ttl %s", cfg.ChallengeTTL)
+	}
+
+	secret, err := loadSigningSecret("challenge", cfg.ChallengeSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	pow := &ProofOfWork{
+		Secret:     secret,
+		Difficulty: cfg.ChallengeDifficulty,
+		TTL:        cfg.ChallengeTTL,
+	}
+	return pow, nil
+}
+
+// Issue returns a new challenge of the form "{id}.{timestamp}.{difficulty}.{signature}".
+// The difficulty is signed along with the challenge, so that outstanding
+// challenges remain valid if the configured difficulty is changed.
+func (p *ProofOfWork) Issue(now time.Time) (Challenge, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return Challenge{}, err
+	}
+
+	payload := fmt.Sprintf("%s.%d.%d", hex.EncodeToString(id), now.Unix(), p.Difficulty)
+	challenge := Challenge{
+		Challenge:  payload + "." + signPayload(p.Secret, "challenge", payload),
+		Difficulty: p.Difficulty,
+		Algorithm:  challengeAlgorithm,
+		ExpiresAt:  time.Unix(now.Unix(), 0).Add(p.TTL).UTC(),
+	}
+	return challenge, nil
+}
+
+// Verify checks that the challenge was issued by this server, has not
+// expired and is solved by the given nonce. The challenge ID and expiry
+// are returned so that the challenge can be redeemed, as verifying a
+// challenge does not prevent it from being used again.
+func (p *ProofOfWork) Verify(challenge string, nonce string, now time.Time) (string, time.Time, error) {
+	fields := strings.Split(challenge, ".")
+	if len(fields) != 4 {
+		return "", time.Time{}, InvalidChallengeError{Reason: "malformed challenge"}
+	}
+
+	payload := strings.Join(fields[:3], ".")
+	if !verifyPayload(p.Secret, "challenge", payload, fields[3]) {
+		return "", time.Time{}, InvalidChallengeError{Reason: "invalid signature"}
+	}
+
+	// the fields are signed, so they are only
+	// malformed if the secret has been leaked
+	issued, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return "", time.Time{}, InvalidChallengeError{Reason: "malformed challenge"}
+	}
+	difficulty, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return "", time.Time{}, InvalidChallengeError{Reason: "malformed challenge"}
+	}
+
+	expiresAt := time.Unix(issued, 0).Add(p.TTL)
+	if !now.Before(expiresAt) {
+		return "", time.Time{}, InvalidChallengeError{Reason: "challenge has expired"}
+	}
+
+	if nonce == "" || len(nonce) > challengeMaxNonceLength {
+		return "", time.Time{}, InvalidChallengeError{Reason: "invalid nonce"}
+	}
+
+	hash := sha256.Sum256([]byte(challenge + ":" + nonce))
+	if leadingZeroBits(hash[:]) < difficulty {
+		return "", time.Time{}, InvalidChallengeError{Reason: "challenge not solved"}
+	}
+	return fields[0], expiresAt, nil
+}
+
+// leadingZeroBits counts the zero bits at the start of the given hash.
+func leadingZeroBits(hash []byte) int {
+	count := 0
+	for _, b := range hash {
+		count += bits.LeadingZeros8(b)
+		if b != 0 {
+			break
+		}
+	}
+	return count
+}
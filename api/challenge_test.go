@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestProofOfWork() *ProofOfWork {
+	return &ProofOfWork{
+		Secret:     []byte("secret"),
+		Difficulty: 8,
+		TTL:        10 * time.Minute,
+	}
+}
+
+// solveChallenge finds the first nonce solving the given challenge.
+func solveChallenge(challenge Challenge) string {
+	for nonce := 0; ; nonce++ {
+		hash := sha256.Sum256([]byte(challenge.Challenge + ":" + strconv.Itoa(nonce)))
+		if leadingZeroBits(hash[:]) >= challenge.Difficulty {
+			return strconv.Itoa(nonce)
+		}
+	}
+}
+
+// newSolvedChallenge issues and solves a challenge.
+func newSolvedChallenge(t *testing.T, pow *ProofOfWork) (string, string) {
+	t.Helper()
+
+	challenge, err := pow.Issue(time.Now())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return challenge.Challenge, solveChallenge(challenge)
+}
+
+func TestProofOfWork(t *testing.T) {
+	pow := newTestProofOfWork()
+	now := time.Now()
+
+	challenge, err := pow.Issue(now)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	nonce := solveChallenge(challenge)
+
+	t.Run("Solved Challenge", func(t *testing.T) {
+		id, expiresAt, err := pow.Verify(challenge.Challenge, nonce, now)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if !strings.HasPrefix(challenge.Challenge, id+".") || !expiresAt.Equal(challenge.ExpiresAt) {
+			t.Errorf("Expected challenge ID and expiry, got %s %s", id, expiresAt)
+		}
+	})
+
+	t.Run("Issued Challenges Are Unique", func(t *testing.T) {
+		other, _ := pow.Issue(now)
+		if other.Challenge == challenge.Challenge {
+			t.Errorf("Expected unique challenges, got %s twice", other.Challenge)
+		}
+	})
+
+	// difficulty is signed along with the challenge
+	harder := *pow
+	harder.Difficulty = 12
+	hardChallenge, _ := harder.Issue(now)
+
+	fields := strings.Split(challenge.Challenge, ".")
+	// change the last character of the signature
+	tampered := challenge.Challenge[:len(challenge.Challenge)-1]
+	if challenge.Challenge[len(challenge.Challenge)-1] == '0' {
+		tampered += "1"
+	} else {
+		tampered += "0"
+	}
+	cases := []struct {
+		name      string
+		challenge string
+		nonce     string
+		now       time.Time
+	}{
+		{"Malformed Challenge", "abc", nonce, now},
+		{"Invalid Signature", tampered, nonce, now},
+		{"Lowered Difficulty", strings.Join([]string{fields[0], fields[1], "0", fields[3]}, "."), nonce, now},
+		{"Expired Challenge", challenge.Challenge, nonce, now.Add(pow.TTL + time.Second)},
+		{"Missing Nonce", challenge.Challenge, "", now},
+		{"Long Nonce", challenge.Challenge, strings.Repeat("0", challengeMaxNonceLength+1), now},
+		{"Unsolved Challenge", hardChallenge.Challenge, "not-a-solution", now},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := pow.Verify(tc.challenge, tc.nonce, tc.now)
+
+			var errInvalid InvalidChallengeError
+			if !errors.As(err, &errInvalid) {
+				t.Errorf("Expected InvalidChallengeError, got %v", err)
+			}
+		})
+	}
+
+	t.Run("Other Secret", func(t *testing.T) {
+		other := *pow
+		other.Secret = []byte("other")
+		if _, _, err := other.Verify(challenge.Challenge, nonce, now); err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+}
+
+func TestLeadingZeroBits(t *testing.T) {
+	tests := []struct {
+		hash     []byte
+		expected int
+	}{
+		{[]byte{0x80, 0x00}, 0},
+		{[]byte{0x0f, 0xff}, 4},
+		{[]byte{0x00, 0x01}, 15},
+		{[]byte{0x00, 0x00}, 16},
+	}
+	for _, tc := range tests {
+		if count := leadingZeroBits(tc.hash); count != tc.expected {
+			t.Errorf("Expected %d leading zero bits for %x, got %d", tc.expected, tc.hash, count)
+		}
+	}
+}
+
+func TestNewProofOfWork(t *testing.T) {
+	pow, err := NewProofOfWork(&Config{ChallengeDifficulty: 16, ChallengeTTL: time.Minute})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(pow.Secret) == 0 || pow.Difficulty != 16 {
+		t.Errorf("Expected random secret with difficulty 16, got %+v", pow)
+	}
+
+	if _, err := NewProofOfWork(&Config{}); err == nil {
+		t.Errorf("Expected error without challenge ttl")
+	}
+}
@@ -1,22 +1,135 @@
 package main
 
 import (
+	"strings"
+	"time"
+
 	"github.com/go-playground/validator/v10"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Port             int    `validate:"omitempty,min=1,max=65535"`
-	LogLevel         string `validate:"omitempty,oneof=debug info warn error fatal panic"`
-	PostgresHost     string `validate:"required"`
-	PostgresPort     int    `validate:"required"`
-	PostgresDatabase string `validate:"required"`
-	PostgresUser     string `validate:"required"`
-	PostgresPassword string `validate:"required"`
-	APIVersion       string `validate:"required"`
-	ResumePathPDF    string `validate:"omitempty,file"`
-	ResumePathJSON   string `validate:"required,file"`
+	Port     int    `validate:"omitempty,min=1,max=65535"`
+	LogLevel string `validate:"omitempty,oneof=debug info warn error fatal panic"`
+	// persistence backend used to store contacts,
+	// request logs and API keys
+	PersistenceBackend PersistenceBackend `validate:"required,oneof=postgres memory sqlite"`
+	PostgresHost       string             `validate:"required_if=PersistenceBackend postgres"`
+	PostgresPort       int                `validate:"required_if=PersistenceBackend postgres"`
+	PostgresDatabase   string             `validate:"required_if=PersistenceBackend postgres"`
+	PostgresUser       string             `validate:"required_if=PersistenceBackend postgres"`
+	PostgresPassword   string             `validate:"required_if=PersistenceBackend postgres"`
+	// connection pool settings for the shared pgxpool
+	PostgresMaxConns          int           `validate:"omitempty,min=1"`
+	PostgresMaxConnIdleTime   time.Duration `validate:"omitempty,min=0"`
+	PostgresHealthCheckPeriod time.Duration `validate:"omitempty,min=0"`
+	// timeout applied to each individual query
+	PostgresQueryTimeout time.Duration `validate:"omitempty,min=0"`
+	// optional JSON file used to restore and persist
+	// the in-memory backend across restarts
+	MemorySnapshotPath string `validate:"omitempty"`
+	// path to the database file used by the sqlite backend
+	SQLitePath string `validate:"required_if=PersistenceBackend sqlite"`
+	// apply pending postgres migrations on startup
+	AutoMigrate bool
+	// SMTP server used to send contact request notifications.
+	// notifications are disabled if no host is configured
+	SMTPHost     string
+	SMTPPort     int `validate:"omitempty,min=1,max=65535"`
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string        `validate:"required_with=SMTPHost,omitempty,email"`
+	SMTPTimeout  time.Duration `validate:"omitempty,min=0"`
+	// owner address notified about new contact requests, and
+	// whether submitters receive an automatic acknowledgement
+	NotifyEmail       string `validate:"required_with=SMTPHost,omitempty,email"`
+	NotifyAcknowledge bool
+	// optional directory of templates overriding
+	// the default email subjects and bodies
+	NotifyTemplateDir  string        `validate:"omitempty,dir"`
+	NotifyQueueSize    int           `validate:"omitempty,min=1"`
+	NotifyMaxAttempts  int           `validate:"omitempty,min=1"`
+	NotifyRetryBackoff time.Duration `validate:"omitempty,min=0"`
+	// optional JSON file defining webhook subscriptions.
+	// webhooks are disabled if no file is configured
+	WebhooksPath        string        `validate:"omitempty,file"`
+	WebhookTimeout      time.Duration `validate:"omitempty,min=0"`
+	WebhookMaxAttempts  int           `validate:"omitempty,min=1"`
+	WebhookRetryBackoff time.Duration `validate:"omitempty,min=0"`
+	WebhookPollInterval time.Duration `validate:"omitempty,min=0"`
+	// spam filter applied to contact form submissions. the
+	// token secret signs the form tokens used to measure
+	// the time taken to fill in the form, and must be shared
+	// by all replicas of the postgres backend
+	SpamTokenSecret   string        `validate:"required_if=PersistenceBackend postgres"`
+	SpamMinFillTime   time.Duration `validate:"omitempty,min=0"`
+	SpamMaxTokenAge   time.Duration `validate:"omitempty,min=0"`
+	SpamMaxLinks      int           `validate:"omitempty,min=0"`
+	SpamKeywords      []string
+	SpamThreshold     int    `validate:"omitempty,min=1"`
+	SpamBlocklistPath string `validate:"omitempty,file"`
+	// proof-of-work challenges solved before submitting the
+	// contact form. the difficulty is the number of leading
+	// zero bits required in the hash of the solution. like
+	// the token secret, the secret must be shared by replicas
+	ChallengeSecret     string        `validate:"required_if=PersistenceBackend postgres"`
+	ChallengeDifficulty int           `validate:"omitempty,min=0,max=32"`
+	ChallengeTTL        time.Duration `validate:"omitempty,min=1s"`
+	// requests to public routes are logged to the database
+	// in batches by a background worker. requests logged
+	// while the queue is full are dropped
+	RequestLogQueueSize     int           `validate:"omitempty,min=1"`
+	RequestLogBatchSize     int           `validate:"omitempty,min=1,max=1000"`
+	RequestLogFlushInterval time.Duration `validate:"omitempty,min=1ms"`
+	// rate limits applied to each route group. limits are kept
+	// in memory unless the postgres store is used, which shares
+	// limits between replicas
+	RateLimitEnabled bool
+	RateLimitStore   RateLimitStoreType `validate:"required_if=RateLimitEnabled true,omitempty,oneof=memory postgres"`
+	RateLimitsPath   string             `validate:"omitempty,file"`
+	// IP addresses and CIDR ranges of reverse proxies trusted
+	// to set X-Forwarded-For. client IPs used for rate limits
+	// and blocklists are spoofable if any proxy is trusted
+	// that does not overwrite the header
+	TrustedProxies []string `validate:"omitempty,dive,ip|cidr"`
+
+	APIVersion string `validate:"required"`
+	// optional static PDF served instead of the
+	// PDF generated from the JSON resume
+	ResumePathPDF  string `validate:"omitempty,file"`
+	ResumePathJSON string `validate:"required_without=ResumeDir,omitempty,file"`
+	// optional directory of resume variants, used
+	// instead of ResumePathPDF and ResumePathJSON
+	ResumeDir string `validate:"omitempty,dir"`
+	// variant and locale served if the client does
+	// not request a variant or accepted language
+	ResumeDefaultVariant string
+	ResumeDefaultLocale  string `validate:"omitempty,bcp47_language_tag"`
+	// reload the resume files when they change
+	ResumeWatch bool
+	// interval at which resume versions activated by
+	// other instances are loaded. zero disables polling
+	ResumePollInterval time.Duration `validate:"omitempty,min=0"`
+	// maximum size of resumes uploaded by admins in bytes
+	ResumeMaxUploadSize int64 `validate:"omitempty,min=1"`
+	// signed links to the resume shared with recipients.
+	// links expire after the ttl unless an expiry of at
+	// most the max ttl is requested. the secret is
+	// required so that sent links survive restarts
+	ResumeShareSecret string        `validate:"required"`
+	ResumeShareTTL    time.Duration `validate:"omitempty,min=1m"`
+	ResumeShareMaxTTL time.Duration `validate:"omitempty,gtefield=ResumeShareTTL"`
+	// optional directory of templates overriding the
+	// HTML, Markdown and plain text resume templates
+	ResumeTemplateDir string `validate:"omitempty,dir"`
+	// theme used to generate the PDF resume
+	ResumePDFTitle       string
+	ResumePDFPageSize    string   `validate:"omitempty,oneof=a4 letter"`
+	ResumePDFMargin      float64  `validate:"omitempty,min=0"`
+	ResumePDFFontSize    float64  `validate:"omitempty,min=4,max=32"`
+	ResumePDFAccentColor string   `validate:"omitempty,hexcolor,len=7"`
+	ResumePDFSections    []string `validate:"omitempty,dive,oneof=experience education skills"`
 }
 
 // Validate checks the Config struct for required fields
@@ -31,25 +144,123 @@ func (c *Config) Validate() error {
 func LoadConfig() *Config {
 	viper.AutomaticEnv()
 	// Set default values for optional variables
+	viper.SetDefault("PERSISTENCE_BACKEND", "postgres")
 	viper.SetDefault("POSTGRES_PORT", 5432)
 	viper.SetDefault("POSTGRES_DATABASE", "postgres")
+	viper.SetDefault("POSTGRES_MAX_CONNS", 10)
+	viper.SetDefault("POSTGRES_MAX_CONN_IDLE_TIME", "5m")
+	viper.SetDefault("POSTGRES_HEALTH_CHECK_PERIOD", "1m")
+	viper.SetDefault("POSTGRES_QUERY_TIMEOUT", "5s")
+	viper.SetDefault("AUTO_MIGRATE", false)
+	viper.SetDefault("SMTP_PORT", 587)
+	viper.SetDefault("SMTP_TIMEOUT", "10s")
+	viper.SetDefault("NOTIFY_ACKNOWLEDGE", false)
+	viper.SetDefault("NOTIFY_QUEUE_SIZE", 100)
+	viper.SetDefault("NOTIFY_MAX_ATTEMPTS", 5)
+	viper.SetDefault("NOTIFY_RETRY_BACKOFF", "2s")
+	viper.SetDefault("WEBHOOK_TIMEOUT", "10s")
+	viper.SetDefault("WEBHOOK_MAX_ATTEMPTS", 8)
+	viper.SetDefault("WEBHOOK_RETRY_BACKOFF", "30s")
+	viper.SetDefault("WEBHOOK_POLL_INTERVAL", "5s")
+	viper.SetDefault("SPAM_MIN_FILL_TIME", "3s")
+	viper.SetDefault("SPAM_MAX_TOKEN_AGE", "2h")
+	viper.SetDefault("SPAM_MAX_LINKS", 2)
+	viper.SetDefault("SPAM_KEYWORDS", "casino,viagra,backlinks,seo services,payday loan,crypto investment")
+	viper.SetDefault("SPAM_THRESHOLD", 50)
+	viper.SetDefault("CHALLENGE_DIFFICULTY", 16)
+	viper.SetDefault("CHALLENGE_TTL", "10m")
+	viper.SetDefault("REQUEST_LOG_QUEUE_SIZE", 10000)
+	viper.SetDefault("REQUEST_LOG_BATCH_SIZE", 100)
+	viper.SetDefault("REQUEST_LOG_FLUSH_INTERVAL", "1s")
+	viper.SetDefault("RATE_LIMIT_ENABLED", true)
+	viper.SetDefault("RATE_LIMIT_STORE", "memory")
 	viper.SetDefault("API_VERSION", "v1")
 	viper.SetDefault("LOG_LEVEL", "info")
 	viper.SetDefault("PORT", 8080)
-	viper.SetDefault("RESUME_PATH_PDF", "etc/resume.pdf")
 	viper.SetDefault("RESUME_PATH_JSON", "etc/resume.json")
+	viper.SetDefault("RESUME_DEFAULT_VARIANT", DefaultResumeVariant)
+	viper.SetDefault("RESUME_DEFAULT_LOCALE", DefaultResumeLocale)
+	viper.SetDefault("RESUME_WATCH", true)
+	viper.SetDefault("RESUME_POLL_INTERVAL", "1m")
+	viper.SetDefault("RESUME_MAX_UPLOAD_SIZE", 5<<20)
+	viper.SetDefault("RESUME_SHARE_TTL", "168h")
+	viper.SetDefault("RESUME_SHARE_MAX_TTL", "2160h")
+	viper.SetDefault("RESUME_PDF_TITLE", "Resume")
+	viper.SetDefault("RESUME_PDF_PAGE_SIZE", "a4")
+	viper.SetDefault("RESUME_PDF_MARGIN", 48)
+	viper.SetDefault("RESUME_PDF_FONT_SIZE", 10)
+	viper.SetDefault("RESUME_PDF_ACCENT_COLOR", "#1f4f78")
+	viper.SetDefault("RESUME_PDF_SECTIONS", "experience,education,skills")
 
 	cfg := &Config{
-		PostgresHost:     viper.GetString("POSTGRES_HOST"),
-		PostgresPort:     viper.GetInt("POSTGRES_PORT"),
-		PostgresDatabase: viper.GetString("POSTGRES_DATABASE"),
-		PostgresUser:     viper.GetString("POSTGRES_USER"),
-		PostgresPassword: viper.GetString("POSTGRES_PASSWORD"),
-		APIVersion:       viper.GetString("API_VERSION"),
-		LogLevel:         viper.GetString("LOG_LEVEL"),
-		Port:             viper.GetInt("PORT"),
-		ResumePathPDF:    viper.GetString("RESUME_PATH_PDF"),
-		ResumePathJSON:   viper.GetString("RESUME_PATH_JSON"),
+		PersistenceBackend:        PersistenceBackend(viper.GetString("PERSISTENCE_BACKEND")),
+		PostgresHost:              viper.GetString("POSTGRES_HOST"),
+		PostgresPort:              viper.GetInt("POSTGRES_PORT"),
+		PostgresDatabase:          viper.GetString("POSTGRES_DATABASE"),
+		PostgresUser:              viper.GetString("POSTGRES_USER"),
+		PostgresPassword:          viper.GetString("POSTGRES_PASSWORD"),
+		PostgresMaxConns:          viper.GetInt("POSTGRES_MAX_CONNS"),
+		PostgresMaxConnIdleTime:   viper.GetDuration("POSTGRES_MAX_CONN_IDLE_TIME"),
+		PostgresHealthCheckPeriod: viper.GetDuration("POSTGRES_HEALTH_CHECK_PERIOD"),
+		PostgresQueryTimeout:      viper.GetDuration("POSTGRES_QUERY_TIMEOUT"),
+		MemorySnapshotPath:        viper.GetString("MEMORY_SNAPSHOT_PATH"),
+		SQLitePath:                viper.GetString("SQLITE_PATH"),
+		AutoMigrate:               viper.GetBool("AUTO_MIGRATE"),
+		SMTPHost:                  viper.GetString("SMTP_HOST"),
+		SMTPPort:                  viper.GetInt("SMTP_PORT"),
+		SMTPUsername:              viper.GetString("SMTP_USERNAME"),
+		SMTPPassword:              viper.GetString("SMTP_PASSWORD"),
+		SMTPFrom:                  viper.GetString("SMTP_FROM"),
+		SMTPTimeout:               viper.GetDuration("SMTP_TIMEOUT"),
+		NotifyEmail:               viper.GetString("NOTIFY_EMAIL"),
+		NotifyAcknowledge:         viper.GetBool("NOTIFY_ACKNOWLEDGE"),
+		NotifyTemplateDir:         viper.GetString("NOTIFY_TEMPLATE_DIR"),
+		NotifyQueueSize:           viper.GetInt("NOTIFY_QUEUE_SIZE"),
+		NotifyMaxAttempts:         viper.GetInt("NOTIFY_MAX_ATTEMPTS"),
+		NotifyRetryBackoff:        viper.GetDuration("NOTIFY_RETRY_BACKOFF"),
+		WebhooksPath:              viper.GetString("WEBHOOKS_PATH"),
+		WebhookTimeout:            viper.GetDuration("WEBHOOK_TIMEOUT"),
+		WebhookMaxAttempts:        viper.GetInt("WEBHOOK_MAX_ATTEMPTS"),
+		WebhookRetryBackoff:       viper.GetDuration("WEBHOOK_RETRY_BACKOFF"),
+		WebhookPollInterval:       viper.GetDuration("WEBHOOK_POLL_INTERVAL"),
+		SpamTokenSecret:           viper.GetString("SPAM_TOKEN_SECRET"),
+		SpamMinFillTime:           viper.GetDuration("SPAM_MIN_FILL_TIME"),
+		SpamMaxTokenAge:           viper.GetDuration("SPAM_MAX_TOKEN_AGE"),
+		SpamMaxLinks:              viper.GetInt("SPAM_MAX_LINKS"),
+		SpamKeywords:              strings.Split(viper.GetString("SPAM_KEYWORDS"), ","),
+		SpamThreshold:             viper.GetInt("SPAM_THRESHOLD"),
+		SpamBlocklistPath:         viper.GetString("SPAM_BLOCKLIST_PATH"),
+		ChallengeSecret:           viper.GetString("CHALLENGE_SECRET"),
+		ChallengeDifficulty:       viper.GetInt("CHALLENGE_DIFFICULTY"),
+		ChallengeTTL:              viper.GetDuration("CHALLENGE_TTL"),
+		RequestLogQueueSize:       viper.GetInt("REQUEST_LOG_QUEUE_SIZE"),
+		RequestLogBatchSize:       viper.GetInt("REQUEST_LOG_BATCH_SIZE"),
+		RequestLogFlushInterval:   viper.GetDuration("REQUEST_LOG_FLUSH_INTERVAL"),
+		RateLimitEnabled:          viper.GetBool("RATE_LIMIT_ENABLED"),
+		RateLimitStore:            RateLimitStoreType(viper.GetString("RATE_LIMIT_STORE")),
+		RateLimitsPath:            viper.GetString("RATE_LIMITS_PATH"),
+		TrustedProxies:            strings.Fields(strings.ReplaceAll(viper.GetString("TRUSTED_PROXIES"), ",", " ")),
+		APIVersion:                viper.GetString("API_VERSION"),
+		LogLevel:                  viper.GetString("LOG_LEVEL"),
+		Port:                      viper.GetInt("PORT"),
+		ResumePathPDF:             viper.GetString("RESUME_PATH_PDF"),
+		ResumePathJSON:            viper.GetString("RESUME_PATH_JSON"),
+		ResumeDir:                 viper.GetString("RESUME_DIR"),
+		ResumeDefaultVariant:      viper.GetString("RESUME_DEFAULT_VARIANT"),
+		ResumeDefaultLocale:       viper.GetString("RESUME_DEFAULT_LOCALE"),
+		ResumeWatch:               viper.GetBool("RESUME_WATCH"),
+		ResumePollInterval:        viper.GetDuration("RESUME_POLL_INTERVAL"),
+		ResumeMaxUploadSize:       viper.GetInt64("RESUME_MAX_UPLOAD_SIZE"),
+		ResumeShareSecret:         viper.GetString("RESUME_SHARE_SECRET"),
+		ResumeShareTTL:            viper.GetDuration("RESUME_SHARE_TTL"),
+		ResumeShareMaxTTL:         viper.GetDuration("RESUME_SHARE_MAX_TTL"),
+		ResumeTemplateDir:         viper.GetString("RESUME_TEMPLATE_DIR"),
+		ResumePDFTitle:            viper.GetString("RESUME_PDF_TITLE"),
+		ResumePDFPageSize:         viper.GetString("RESUME_PDF_PAGE_SIZE"),
+		ResumePDFMargin:           viper.GetFloat64("RESUME_PDF_MARGIN"),
+		ResumePDFFontSize:         viper.GetFloat64("RESUME_PDF_FONT_SIZE"),
+		ResumePDFAccentColor:      viper.GetString("RESUME_PDF_ACCENT_COLOR"),
+		ResumePDFSections:         strings.Split(viper.GetString("RESUME_PDF_SECTIONS"), ","),
 	}
 
 	if err := cfg.Validate(); err != nil {
@@ -0,0 +1,204 @@
+package main
+
+import "testing"
+
+func TestConfigValidate(t *testing.T) {
+
+	t.Run("Postgres Backend Requires Credentials", func(t *testing.T) {
+		config := &Config{
+			PersistenceBackend: PersistenceBackendPostgres,
+			APIVersion:         "v1",
+			ResumeShareSecret:  "secret",
+			ResumePathJSON:     "etc/resume.json",
+		}
+
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected validation error for missing postgres settings")
+		}
+	})
+
+	t.Run("Postgres Backend Requires Shared Secrets", func(t *testing.T) {
+		config := &Config{
+			PersistenceBackend: PersistenceBackendPostgres,
+			PostgresHost:       "localhost",
+			PostgresPort:       5432,
+			PostgresDatabase:   "postgres",
+			PostgresUser:       "postgres",
+			PostgresPassword:   "postgres",
+			APIVersion:         "v1",
+			ResumeShareSecret:  "secret",
+			ResumePathJSON:     "etc/resume.json",
+		}
+
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected validation error for missing spam token secret")
+		}
+
+		config.SpamTokenSecret = "secret"
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected validation error for missing challenge secret")
+		}
+
+		config.ChallengeSecret = "secret"
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected no validation error, got %v", err)
+		}
+	})
+
+	t.Run("Memory Backend Does Not Require Credentials", func(t *testing.T) {
+		config := &Config{
+			PersistenceBackend: PersistenceBackendMemory,
+			APIVersion:         "v1",
+			ResumeShareSecret:  "secret",
+			ResumePathJSON:     "etc/resume.json",
+		}
+
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected no validation error, got %v", err)
+		}
+	})
+
+	t.Run("Resume Share Secret Is Required", func(t *testing.T) {
+		config := &Config{
+			PersistenceBackend: PersistenceBackendMemory,
+			APIVersion:         "v1",
+			ResumePathJSON:     "etc/resume.json",
+		}
+
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected validation error for missing resume share secret")
+		}
+	})
+
+	t.Run("Unknown Backend", func(t *testing.T) {
+		config := &Config{
+			PersistenceBackend: "mongo",
+			APIVersion:         "v1",
+			ResumeShareSecret:  "secret",
+			ResumePathJSON:     "etc/resume.json",
+		}
+
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected validation error for unknown backend")
+		}
+	})
+
+	t.Run("SMTP Host Requires Addresses", func(t *testing.T) {
+		config := &Config{
+			PersistenceBackend: PersistenceBackendMemory,
+			APIVersion:         "v1",
+			ResumeShareSecret:  "secret",
+			ResumePathJSON:     "etc/resume.json",
+			SMTPHost:           "localhost",
+		}
+
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected validation error for missing SMTP addresses")
+		}
+
+		config.SMTPFrom = "website@example.com"
+		config.NotifyEmail = "owner@example.com"
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected no validation error, got %v", err)
+		}
+
+		config.NotifyEmail = "invalid"
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected validation error for invalid notify email")
+		}
+	})
+
+	t.Run("Trusted Proxies Must Be Addresses", func(t *testing.T) {
+		config := &Config{
+			PersistenceBackend: PersistenceBackendMemory,
+			APIVersion:         "v1",
+			ResumeShareSecret:  "secret",
+			ResumePathJSON:     "etc/resume.json",
+			TrustedProxies:     []string{"10.0.0.1", "192.168.0.0/16"},
+		}
+
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected no validation error, got %v", err)
+		}
+
+		config.TrustedProxies = append(config.TrustedProxies, "proxy.local")
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected validation error for invalid trusted proxy")
+		}
+	})
+
+	t.Run("Webhooks Path Must Exist", func(t *testing.T) {
+		config := &Config{
+			PersistenceBackend: PersistenceBackendMemory,
+			APIVersion:         "v1",
+			ResumeShareSecret:  "secret",
+			ResumePathJSON:     "etc/resume.json",
+			WebhooksPath:       "missing/webhooks.json",
+		}
+
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected validation error for missing webhooks file")
+		}
+	})
+
+	t.Run("Resume Template Dir Must Exist", func(t *testing.T) {
+		config := &Config{
+			PersistenceBackend: PersistenceBackendMemory,
+			APIVersion:         "v1",
+			ResumeShareSecret:  "secret",
+			ResumePathJSON:     "etc/resume.json",
+			ResumeTemplateDir:  "missing/templates",
+		}
+
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected validation error for missing template directory")
+		}
+
+		config.ResumeTemplateDir = "templates/resume"
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected no validation error, got %v", err)
+		}
+	})
+
+	t.Run("Resume Dir Replaces Resume Path", func(t *testing.T) {
+		config := &Config{
+			PersistenceBackend: PersistenceBackendMemory,
+			APIVersion:         "v1",
+			ResumeShareSecret:  "secret",
+		}
+
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected validation error without a resume")
+		}
+
+		config.ResumeDir = "etc"
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected no validation error, got %v", err)
+		}
+
+		config.ResumeDefaultLocale = "not a locale"
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected validation error for invalid locale")
+		}
+	})
+
+	t.Run("Unknown Rate Limit Store", func(t *testing.T) {
+		config := &Config{
+			PersistenceBackend: PersistenceBackendMemory,
+			APIVersion:         "v1",
+			ResumeShareSecret:  "secret",
+			ResumePathJSON:     "etc/resume.json",
+			RateLimitEnabled:   true,
+			RateLimitStore:     "redis",
+		}
+
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected validation error for unknown rate limit store")
+		}
+
+		config.RateLimitStore = RateLimitStoreMemory
+		if err := config.Validate(); err != nil {
+			t.Errorf("Expected no validation error, got %v", err)
+		}
+	})
+}
@@ -2,38 +2,89 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// pgUniqueViolation is the postgres error code
+// returned when a unique constraint is violated
+const pgUniqueViolation = "23505"
+
+// Persistence defines the storage operations used by the API. All methods
+// accept a context so that cancelled requests cancel their queries.
 type Persistence interface {
-	HealthCheck() error
-	GetContact(email string) (*Contact, error)
-	CreateContact(contact Contact) (string, error)
-	ListContacts() ([]Contact, error)
-	CreateContactRequest(entry ContactRequest) (string, error)
-	ListContactRequests() ([]ContactRequest, error)
-	LogRequest(request LoggedRequest) (string, error)
-	LogResponse(request LoggedResponse) error
-	GetRequestStats() (*RequestStats, error)
-	GetAPIKey(key string) (*APIKey, error)
+	HealthCheck(ctx context.Context) error
+	GetContact(ctx context.Context, email string) (*Contact, error)
+	GetContactById(ctx context.Context, id string) (*Contact, error)
+	UpdateContact(ctx context.Context, id string, update ContactUpdate) (*Contact, error)
+	DeleteContact(ctx context.Context, id string) error
+	CreateContact(ctx context.Context, contact Contact) (string, error)
+	ListContacts(ctx context.Context) ([]Contact, error)
+	QueryContacts(ctx context.Context, query ListQuery) ([]Contact, *ListCursor, error)
+	CreateContactRequest(ctx context.Context, entry ContactRequest) (string, error)
+	ListContactRequests(ctx context.Context) ([]ContactRequest, error)
+	QueryContactRequests(ctx context.Context, query ListQuery) ([]ContactRequest, *ListCursor, error)
+	UpdateContactRequest(ctx context.Context, id string, update ContactRequestUpdate) (*ContactRequest, error)
+	CreateWebhookDeliveries(ctx context.Context, deliveries []WebhookDelivery) error
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, delivery WebhookDelivery) error
+	QueryWebhookDeliveries(ctx context.Context, query ListQuery) ([]WebhookDelivery, *ListCursor, error)
+	RedeemChallenge(ctx context.Context, id string, expiresAt time.Time) error
+	CreateResumeVersion(ctx context.Context, version ResumeVersion) (*ResumeVersion, error)
+	ListResumeVersions(ctx context.Context) ([]ResumeVersion, error)
+	GetResumeVersion(ctx context.Context, id string) (*ResumeVersion, error)
+	ActivateResumeVersion(ctx context.Context, id string) (*ResumeVersion, error)
+	ListActiveResumeVersions(ctx context.Context) ([]ResumeVersion, error)
+	CreateResumeShare(ctx context.Context, share ResumeShare) (*ResumeShare, error)
+	ListResumeShares(ctx context.Context) ([]ResumeShare, error)
+	GetResumeShare(ctx context.Context, id string) (*ResumeShare, error)
+	OpenResumeShare(ctx context.Context, id string) (*ResumeShare, error)
+	LogRequests(ctx context.Context, requests []LoggedRequest, responses []LoggedResponse) error
+	GetRequestStats(ctx context.Context) (*RequestStats, error)
+	RecordResumeDownload(ctx context.Context, download ResumeDownload) error
+	GetResumeStats(ctx context.Context, query ResumeStatsQuery) (*ResumeStats, error)
+	GetAPIKey(ctx context.Context, key string) (*APIKey, error)
+	Close() error
 }
 
 type PGPersistence struct {
-	// Add fields for database connection if needed
 	Conn *pgxpool.Pool
+	// QueryTimeout is applied to every query on top of
+	// any deadline set on the incoming context. a zero
+	// value disables the per-query timeout
+	QueryTimeout time.Duration
+}
+
+// queryContext derives a context for a single query from
+// the given parent context, applying the configured timeout.
+func (db *PGPersistence) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if db.QueryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, db.QueryTimeout)
 }
 
-func (db *PGPersistence) HealthCheck() error {
-	return db.Conn.Ping(context.TODO())
+func (db *PGPersistence) HealthCheck(ctx context.Context) error {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	return db.Conn.Ping(ctx)
 }
 
-func (db *PGPersistence) GetContact(email string) (*Contact, error) {
+func (db *PGPersistence) GetContact(ctx context.Context, email string) (*Contact, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
 	var contact Contact
-	response, err := db.Conn.Query(context.TODO(),
+	response, err := db.Conn.Query(ctx,
 		"SELECT id, name, email, created_at FROM base.contacts WHERE email=$1", email)
 	if err != nil {
 		return nil, err
@@ -47,28 +98,104 @@ func (db *PGPersistence) GetContact(email string) (*Contact, error) {
 		}
 		return &contact, nil
 	}
+	// a cancelled or timed out query also ends
+	// iteration, so check before reporting not found
+	if err := response.Err(); err != nil {
+		return nil, err
+	}
 
 	return nil, ContactNotFoundError{Email: email}
 }
 
+// GetContactById retrieves a single contact by ID
+func (db *PGPersistence) GetContactById(ctx context.Context, id string) (*Contact, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	var contact Contact
+	err := db.Conn.QueryRow(ctx,
+		"SELECT id, name, email, created_at FROM base.contacts WHERE id=$1", id).
+		Scan(&contact.Id, &contact.Name, &contact.Email, &contact.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ContactNotFoundError{Id: id}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// UpdateContact updates the name and/or email of a contact
+// and returns the updated contact
+func (db *PGPersistence) UpdateContact(ctx context.Context, id string, update ContactUpdate) (*Contact, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	// fields not provided in the update are passed
+	// as NULL, so COALESCE keeps the current value
+	query := `
+		UPDATE base.contacts
+		SET name = COALESCE($2, name), email = COALESCE($3, email)
+		WHERE id=$1
+		RETURNING id, name, email, created_at;`
+
+	var contact Contact
+	err := db.Conn.QueryRow(ctx, query, id, update.Name, update.Email).
+		Scan(&contact.Id, &contact.Name, &contact.Email, &contact.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ContactNotFoundError{Id: id}
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+		return nil, ContactConflictError{Email: *update.Email}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// DeleteContact deletes a contact. All contact requests
+// for the contact are removed by the ON DELETE CASCADE
+func (db *PGPersistence) DeleteContact(ctx context.Context, id string) error {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	tag, err := db.Conn.Exec(ctx, "DELETE FROM base.contacts WHERE id=$1;", id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ContactNotFoundError{Id: id}
+	}
+	return nil
+}
+
 // CreateContact stores a new contact in the database
-func (db *PGPersistence) CreateContact(contact Contact) (string, error) {
+func (db *PGPersistence) CreateContact(ctx context.Context, contact Contact) (string, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
 	id := uuid.New().String()
 	id = strings.ReplaceAll(id, "-", "")
 
 	query := `
 		INSERT INTO base.contacts (id, name, email, created_at)
 		VALUES ($1, $2, $3, $4);`
-	_, err := db.Conn.Exec(context.TODO(), query,
+	_, err := db.Conn.Exec(ctx, query,
 		id, contact.Name, contact.Email, time.Now())
 	return id, err
 }
 
 // ListContacts retrieves all contacts from the database
-func (db *PGPersistence) ListContacts() ([]Contact, error) {
+func (db *PGPersistence) ListContacts(ctx context.Context) ([]Contact, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
 	var contacts []Contact
 	query := `SELECT id, name, email, created_at FROM base.contacts;`
-	rows, err := db.Conn.Query(context.TODO(), query)
+	rows, err := db.Conn.Query(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -81,38 +208,84 @@ func (db *PGPersistence) ListContacts() ([]Contact, error) {
 		}
 		contacts = append(contacts, contact)
 	}
-	return contacts, nil
+	return contacts, rows.Err()
+}
+
+// QueryContacts retrieves a page of contacts matching the given query
+func (db *PGPersistence) QueryContacts(ctx context.Context, query ListQuery) ([]Contact, *ListCursor, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	statement, args := buildListQuery(
+		"SELECT id, name, email, created_at FROM base.contacts",
+		listColumns{createdAt: "created_at", id: "id", email: "email"},
+		query, postgresPlaceholder)
+
+	rows, err := db.Conn.Query(ctx, statement, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var contacts []Contact
+	for rows.Next() {
+		var contact Contact
+		if err := rows.Scan(&contact.Id, &contact.Name, &contact.Email, &contact.CreatedAt); err != nil {
+			return nil, nil, err
+		}
+		contacts = append(contacts, contact)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	contacts, next := nextPage(contacts, query.Limit, contactCursor)
+	return contacts, next, nil
 }
 
 // CreateContactRequest stores a new contact request in the database
-func (db *PGPersistence) CreateContactRequest(entry ContactRequest) (string, error) {
+func (db *PGPersistence) CreateContactRequest(ctx context.Context, entry ContactRequest) (string, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
 	id := uuid.New().String()
 	id = strings.ReplaceAll(id, "-", "")
 
+	status := entry.Status
+	if status == "" {
+		status = ContactRequestStatusNew
+	}
+
 	query := `
-		INSERT INTO base.contact_requests (id, contact_id, message, created_at)
-		VALUES ($1, $2, $3, $4);`
-	_, err := db.Conn.Exec(context.TODO(), query,
-		id, entry.ContactId, entry.Message, time.Now())
+		INSERT INTO base.contact_requests (id, contact_id, message, status, notes, spam_score, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7);`
+	_, err := db.Conn.Exec(ctx, query,
+		id, entry.ContactId, entry.Message, status, entry.Notes, entry.SpamScore, time.Now())
 	return id, err
 }
 
 // ListContactRequests retrieves all contact requests from the database
-func (db *PGPersistence) ListContactRequests() ([]ContactRequest, error) {
+func (db *PGPersistence) ListContactRequests(ctx context.Context) ([]ContactRequest, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
 	var requests []ContactRequest
 	// inner join to contacts to get email
 	query := `SELECT
 		cr.id,
-		c.contact_id,
+		cr.contact_id,
 		c.email,
 		cr.message,
+		cr.status,
+		cr.notes,
+		cr.spam_score,
 		cr.created_at
 	FROM
 		base.contact_requests cr
 	INNER JOIN
 		base.contacts c ON cr.contact_id = c.id;`
 
-	rows, err := db.Conn.Query(context.TODO(), query)
+	rows, err := db.Conn.Query(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -120,39 +293,640 @@ func (db *PGPersistence) ListContactRequests() ([]ContactRequest, error) {
 
 	for rows.Next() {
 		var request ContactRequest
-		if err := rows.Scan(&request.Id, &request.ContactId, &request.Email, &request.Message, &request.CreatedAt); err != nil {
+		if err := rows.Scan(&request.Id, &request.ContactId, &request.Email, &request.Message,
+			&request.Status, &request.Notes, &request.SpamScore, &request.CreatedAt); err != nil {
 			return nil, err
 		}
 		requests = append(requests, request)
 	}
-	return requests, nil
+	return requests, rows.Err()
 }
 
-// LogRequest logs an incoming request to the database
-func (db *PGPersistence) LogRequest(request LoggedRequest) (string, error) {
-	id := uuid.New().String()
-	id = strings.ReplaceAll(id, "-", "")
+// QueryContactRequests retrieves a page of contact requests matching the given query
+func (db *PGPersistence) QueryContactRequests(ctx context.Context, query ListQuery) ([]ContactRequest, *ListCursor, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	// inner join to contacts to get email
+	statement, args := buildListQuery(`SELECT
+			cr.id,
+			cr.contact_id,
+			c.email,
+			cr.message,
+			cr.status,
+			cr.notes,
+			cr.spam_score,
+			cr.created_at
+		FROM
+			base.contact_requests cr
+		INNER JOIN
+			base.contacts c ON cr.contact_id = c.id`,
+		listColumns{createdAt: "cr.created_at", id: "cr.id", email: "c.email", contactId: "cr.contact_id", status: "cr.status"},
+		query, postgresPlaceholder)
+
+	rows, err := db.Conn.Query(ctx, statement, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var requests []ContactRequest
+	for rows.Next() {
+		var request ContactRequest
+		if err := rows.Scan(&request.Id, &request.ContactId, &request.Email, &request.Message,
+			&request.Status, &request.Notes, &request.SpamScore, &request.CreatedAt); err != nil {
+			return nil, nil, err
+		}
+		requests = append(requests, request)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	requests, next := nextPage(requests, query.Limit, contactRequestCursor)
+	return requests, next, nil
+}
+
+// UpdateContactRequest updates the status and/or notes of a contact
+// request and returns the updated request. The current status is locked
+// while the transition is validated so that concurrent updates cannot
+// move the request through a transition that is not allowed.
+func (db *PGPersistence) UpdateContactRequest(ctx context.Context, id string, update ContactRequestUpdate) (*ContactRequest, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
 
+	var current ContactRequestStatus
+	err = tx.QueryRow(ctx,
+		"SELECT status FROM base.contact_requests WHERE id=$1 FOR UPDATE;", id).Scan(&current)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ContactRequestNotFoundError{Id: id}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if update.Status != nil && !current.CanTransitionTo(*update.Status) {
+		return nil, InvalidStatusTransitionError{From: current, To: *update.Status}
+	}
+
+	// fields not provided in the update are passed
+	// as NULL, so COALESCE keeps the current value
 	query := `
-		INSERT INTO base.logged_requests (method, path, id, request_ts, ip_address)
-		VALUES ($1, $2, $3, $4, $5);`
-	_, err := db.Conn.Exec(context.TODO(), query,
-		request.Method, request.Path, id, request.RequestTs, request.IPAddress)
-	return id, err
+		UPDATE base.contact_requests cr
+		SET status = COALESCE($2, cr.status), notes = COALESCE($3, cr.notes)
+		FROM base.contacts c
+		WHERE cr.id=$1 AND cr.contact_id = c.id
+		RETURNING cr.id, cr.contact_id, c.email, cr.message, cr.status, cr.notes, cr.spam_score, cr.created_at;`
+
+	var request ContactRequest
+	err = tx.QueryRow(ctx, query, id, update.Status, update.Notes).
+		Scan(&request.Id, &request.ContactId, &request.Email, &request.Message,
+			&request.Status, &request.Notes, &request.SpamScore, &request.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &request, tx.Commit(ctx)
 }
 
-// LogResponse logs an outgoing response to the database
-func (db *PGPersistence) LogResponse(response LoggedResponse) error {
+// webhookDeliveryColumns are the columns scanned by scanWebhookDelivery
+const webhookDeliveryColumns = `id, subscription, event_id, event_type, payload, status,
+	attempts, response_status, last_error, next_attempt_at, last_attempt_at, created_at`
+
+// scanWebhookDelivery scans a row containing the webhookDeliveryColumns
+func scanWebhookDelivery(row interface{ Scan(...any) error }) (WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	var payload string
+	err := row.Scan(&delivery.Id, &delivery.Subscription, &delivery.EventId, &delivery.EventType,
+		&payload, &delivery.Status, &delivery.Attempts, &delivery.ResponseStatus, &delivery.LastError,
+		&delivery.NextAttemptAt, &delivery.LastAttemptAt, &delivery.CreatedAt)
+	delivery.Payload = json.RawMessage(payload)
+	return delivery, err
+}
+
+// CreateWebhookDeliveries queues the given deliveries in a single
+// transaction. Deliveries are due for their first attempt immediately
+func (db *PGPersistence) CreateWebhookDeliveries(ctx context.Context, deliveries []WebhookDelivery) error {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
 	query := `
-		INSERT INTO base.logged_responses (id, status, time_elapsed, response_ts)
-		VALUES ($1, $2, $3, $4);`
-	_, err := db.Conn.Exec(context.TODO(), query,
-		response.RequestId, response.Status, response.TimeElapsed, time.Now())
+		INSERT INTO base.webhook_deliveries (id, subscription, event_id, event_type, payload, status, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7);`
+
+	now := time.Now()
+	batch := &pgx.Batch{}
+	for _, delivery := range deliveries {
+		id := strings.ReplaceAll(uuid.New().String(), "-", "")
+		batch.Queue(query, id, delivery.Subscription, delivery.EventId, delivery.EventType,
+			string(delivery.Payload), WebhookDeliveryPending, now)
+	}
+	// SendBatch runs all queued statements in an implicit transaction
+	return db.Conn.SendBatch(ctx, batch).Close()
+}
+
+// ClaimWebhookDeliveries claims up to limit pending deliveries that are
+// due, and pushes back their next attempt by the lease so that they are
+// not claimed again while being delivered. Rows locked by other replicas
+// are skipped, so each delivery is only claimed by a single replica.
+func (db *PGPersistence) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE base.webhook_deliveries
+		SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM base.webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + webhookDeliveryColumns + `;`
+
+	now := time.Now()
+	rows, err := db.Conn.Query(ctx, query, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// UpdateWebhookDelivery records the outcome of a delivery attempt
+func (db *PGPersistence) UpdateWebhookDelivery(ctx context.Context, delivery WebhookDelivery) error {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE base.webhook_deliveries
+		SET status = $2, attempts = $3, response_status = $4, last_error = $5,
+			next_attempt_at = $6, last_attempt_at = $7
+		WHERE id=$1;`
+	_, err := db.Conn.Exec(ctx, query, delivery.Id, delivery.Status, delivery.Attempts,
+		delivery.ResponseStatus, delivery.LastError, delivery.NextAttemptAt, delivery.LastAttemptAt)
+	return err
+}
+
+// QueryWebhookDeliveries retrieves a page of webhook deliveries matching the given query
+func (db *PGPersistence) QueryWebhookDeliveries(ctx context.Context, query ListQuery) ([]WebhookDelivery, *ListCursor, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	statement, args := buildListQuery(
+		"SELECT "+webhookDeliveryColumns+" FROM base.webhook_deliveries",
+		listColumns{createdAt: "created_at", id: "id"},
+		query, postgresPlaceholder)
+
+	rows, err := db.Conn.Query(ctx, statement, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	deliveries, next := nextPage(deliveries, query.Limit, webhookDeliveryCursor)
+	return deliveries, next, nil
+}
+
+// RedeemChallenge marks a solved challenge as used, returning a
+// ChallengeUsedError if it has already been redeemed. Expired
+// challenges are removed, as they can no longer be verified
+func (db *PGPersistence) RedeemChallenge(ctx context.Context, id string, expiresAt time.Time) error {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	_, err := db.Conn.Exec(ctx, "DELETE FROM base.redeemed_challenges WHERE expires_at < $1;", time.Now())
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO base.redeemed_challenges (id, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (id) DO NOTHING;`
+
+	tag, err := db.Conn.Exec(ctx, query, id, expiresAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ChallengeUsedError{Id: id}
+	}
+	return nil
+}
+
+// resumeVersionColumns are the columns scanned by scanResumeVersion
+const resumeVersionColumns = `id, variant, locale, format, hash, octet_length(content), author, active, created_at, activated_at`
+
+// scanResumeVersion scans a row containing the resumeVersionColumns,
+// followed by the content if withContent is set
+func scanResumeVersion(row interface{ Scan(...any) error }, withContent bool) (ResumeVersion, error) {
+	var version ResumeVersion
+	dest := []any{&version.Id, &version.Variant, &version.Locale, &version.Format, &version.Hash,
+		&version.Size, &version.Author, &version.Active, &version.CreatedAt, &version.ActivatedAt}
+	if withContent {
+		dest = append(dest, &version.Content)
+	}
+	err := row.Scan(dest...)
+	return version, err
+}
+
+// CreateResumeVersion stores a new version of a resume and makes it
+// the active version of its variant, locale and format
+func (db *PGPersistence) CreateResumeVersion(ctx context.Context, version ResumeVersion) (*ResumeVersion, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE base.resume_versions SET active = FALSE
+		WHERE variant=$1 AND locale=$2 AND format=$3 AND active;`,
+		version.Variant, version.Locale, version.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO base.resume_versions (id, variant, locale, format, content, hash, author, active, created_at, activated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, TRUE, $8, $8)
+		RETURNING ` + resumeVersionColumns + `;`
+	id := strings.ReplaceAll(uuid.New().String(), "-", "")
+	created, err := scanResumeVersion(tx.QueryRow(ctx, query, id, version.Variant, version.Locale,
+		version.Format, version.Content, version.Hash, version.Author, time.Now()), false)
+	if err != nil {
+		return nil, err
+	}
+	return &created, tx.Commit(ctx)
+}
+
+// ListResumeVersions lists all versions of the resume without
+// their content, most recent first
+func (db *PGPersistence) ListResumeVersions(ctx context.Context) ([]ResumeVersion, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	query := `SELECT ` + resumeVersionColumns + ` FROM base.resume_versions ORDER BY created_at DESC, id DESC;`
+	rows, err := db.Conn.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []ResumeVersion{}
+	for rows.Next() {
+		version, err := scanResumeVersion(rows, false)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// GetResumeVersion retrieves a single version of the resume
+// along with its content
+func (db *PGPersistence) GetResumeVersion(ctx context.Context, id string) (*ResumeVersion, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	query := `SELECT ` + resumeVersionColumns + `, content FROM base.resume_versions WHERE id=$1;`
+	version, err := scanResumeVersion(db.Conn.QueryRow(ctx, query, id), true)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ResumeVersionNotFoundError{Id: id}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// ActivateResumeVersion makes the given version the active version
+// of its variant, locale and format, e.g. to roll back a change
+func (db *PGPersistence) ActivateResumeVersion(ctx context.Context, id string) (*ResumeVersion, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var variant, locale, format string
+	err = tx.QueryRow(ctx,
+		"SELECT variant, locale, format FROM base.resume_versions WHERE id=$1 FOR UPDATE;", id).
+		Scan(&variant, &locale, &format)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ResumeVersionNotFoundError{Id: id}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE base.resume_versions SET active = FALSE
+		WHERE variant=$1 AND locale=$2 AND format=$3 AND active AND id<>$4;`,
+		variant, locale, format, id)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE base.resume_versions SET active = TRUE, activated_at = $2 WHERE id=$1 RETURNING ` + resumeVersionColumns + `;`
+	version, err := scanResumeVersion(tx.QueryRow(ctx, query, id, time.Now()), false)
+	if err != nil {
+		return nil, err
+	}
+	return &version, tx.Commit(ctx)
+}
+
+// ListActiveResumeVersions lists the active version of each
+// variant, locale and format along with its content
+func (db *PGPersistence) ListActiveResumeVersions(ctx context.Context) ([]ResumeVersion, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	query := `SELECT ` + resumeVersionColumns + `, content FROM base.resume_versions WHERE active;`
+	rows, err := db.Conn.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []ResumeVersion
+	for rows.Next() {
+		version, err := scanResumeVersion(rows, true)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// resumeShareColumns are the columns scanned by scanResumeShare
+const resumeShareColumns = `id, variant, locale, format, recipient, single_use, created_by, created_at, expires_at, opens, first_opened_at, last_opened_at`
+
+// scanResumeShare scans a row containing the resumeShareColumns
+func scanResumeShare(row interface{ Scan(...any) error }) (ResumeShare, error) {
+	var share ResumeShare
+	err := row.Scan(&share.Id, &share.Variant, &share.Locale, &share.Format, &share.Recipient, &share.SingleUse,
+		&share.CreatedBy, &share.CreatedAt, &share.ExpiresAt, &share.Opens, &share.FirstOpenedAt, &share.LastOpenedAt)
+	return share, err
+}
+
+// CreateResumeShare stores a new link to the resume
+func (db *PGPersistence) CreateResumeShare(ctx context.Context, share ResumeShare) (*ResumeShare, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO base.resume_shares (id, variant, locale, format, recipient, single_use, created_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING ` + resumeShareColumns + `;`
+	id := strings.ReplaceAll(uuid.New().String(), "-", "")
+	created, err := scanResumeShare(db.Conn.QueryRow(ctx, query, id, share.Variant, share.Locale, share.Format,
+		share.Recipient, share.SingleUse, share.CreatedBy, time.Now(), share.ExpiresAt))
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// ListResumeShares lists all links to the resume, most recent first
+func (db *PGPersistence) ListResumeShares(ctx context.Context) ([]ResumeShare, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	query := `SELECT ` + resumeShareColumns + ` FROM base.resume_shares ORDER BY created_at DESC, id DESC;`
+	rows, err := db.Conn.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []ResumeShare{}
+	for rows.Next() {
+		share, err := scanResumeShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
+
+// GetResumeShare retrieves a link to the resume by id
+func (db *PGPersistence) GetResumeShare(ctx context.Context, id string) (*ResumeShare, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	query := `SELECT ` + resumeShareColumns + ` FROM base.resume_shares WHERE id=$1;`
+	share, err := scanResumeShare(db.Conn.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ResumeShareNotFoundError{Id: id}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// OpenResumeShare records that a link to the resume was opened. Single
+// use links that have already been opened return a ResumeShareUsedError
+func (db *PGPersistence) OpenResumeShare(ctx context.Context, id string) (*ResumeShare, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE base.resume_shares
+		SET opens = opens + 1, first_opened_at = COALESCE(first_opened_at, $2), last_opened_at = $2
+		WHERE id=$1 AND (NOT single_use OR opens = 0)
+		RETURNING ` + resumeShareColumns + `;`
+	share, err := scanResumeShare(db.Conn.QueryRow(ctx, query, id, time.Now()))
+	if errors.Is(err, pgx.ErrNoRows) {
+		var exists bool
+		err = db.Conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM base.resume_shares WHERE id=$1);", id).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, ResumeShareUsedError{Id: id}
+		}
+		return nil, ResumeShareNotFoundError{Id: id}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// LogRequests logs a batch of requests and their responses to the
+// database. Rows are written using COPY in a single transaction, so
+// that requests are always written before the responses referencing them
+func (db *PGPersistence) LogRequests(ctx context.Context, requests []LoggedRequest, responses []LoggedResponse) error {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"base", "logged_requests"},
+		[]string{"id", "method", "path", "request_ts", "ip_address"},
+		pgx.CopyFromSlice(len(requests), func(i int) ([]any, error) {
+			request := requests[i]
+			return []any{request.ID, request.Method, request.Path, request.RequestTs, request.IPAddress}, nil
+		}))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"base", "logged_responses"},
+		[]string{"id", "status", "time_elapsed", "response_ts"},
+		pgx.CopyFromSlice(len(responses), func(i int) ([]any, error) {
+			response := responses[i]
+			return []any{response.RequestId, response.Status, response.TimeElapsed, response.ResponseTs}, nil
+		}))
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// addDailyDownloads adds the number of downloads of a format on the
+// given date to the daily downloads, which must be ordered by date.
+func addDailyDownloads(daily []ResumeDailyDownloads, date string, format ResumeFileFormat, count int) []ResumeDailyDownloads {
+	if len(daily) == 0 || daily[len(daily)-1].Date != date {
+		daily = append(daily, ResumeDailyDownloads{Date: date, Formats: make(map[ResumeFileFormat]int)})
+	}
+	daily[len(daily)-1].Formats[format] += count
+	return daily
+}
+
+// RecordResumeDownload records a successful fetch of the resume
+func (db *PGPersistence) RecordResumeDownload(ctx context.Context, download ResumeDownload) error {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	id := strings.ReplaceAll(uuid.New().String(), "-", "")
+	query := `
+		INSERT INTO base.resume_downloads (id, format, variant, locale, referrer, user_agent, ip_address, downloaded_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8);`
+	_, err := db.Conn.Exec(ctx, query, id, download.Format, download.Variant, download.Locale,
+		download.Referrer, download.UserAgent, download.IPAddress, download.DownloadedAt.UTC())
 	return err
 }
 
+// GetResumeStats aggregates the resume downloads in the given period.
+// Downloads are stored in UTC, so days are UTC days
+func (db *PGPersistence) GetResumeStats(ctx context.Context, query ResumeStatsQuery) (*ResumeStats, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
+
+	since, until := query.Since.UTC(), query.Until.UTC()
+	stats := ResumeStats{
+		Since:        since,
+		Until:        until,
+		Daily:        []ResumeDailyDownloads{},
+		TopReferrers: []ReferrerCount{},
+	}
+
+	totalsQuery := `
+		SELECT COUNT(*), COUNT(DISTINCT ip_address)
+		FROM base.resume_downloads
+		WHERE downloaded_at >= $1 AND downloaded_at < $2;`
+	if err := db.Conn.QueryRow(ctx, totalsQuery, since, until).Scan(&stats.TotalDownloads, &stats.UniqueDownloaders); err != nil {
+		return nil, err
+	}
+
+	dailyQuery := `
+		SELECT to_char(downloaded_at, 'YYYY-MM-DD') AS day, format, COUNT(*)
+		FROM base.resume_downloads
+		WHERE downloaded_at >= $1 AND downloaded_at < $2
+		GROUP BY day, format
+		ORDER BY day;`
+	rows, err := db.Conn.Query(ctx, dailyQuery, since, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var date string
+		var format ResumeFileFormat
+		var count int
+		if err := rows.Scan(&date, &format, &count); err != nil {
+			return nil, err
+		}
+		stats.Daily = addDailyDownloads(stats.Daily, date, format, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	referrerQuery := `
+		SELECT referrer, COUNT(*) AS downloads
+		FROM base.resume_downloads
+		WHERE downloaded_at >= $1 AND downloaded_at < $2 AND referrer <> ''
+		GROUP BY referrer
+		ORDER BY downloads DESC, referrer
+		LIMIT $3;`
+	rows, err = db.Conn.Query(ctx, referrerQuery, since, until, query.Referrers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var referrer ReferrerCount
+		if err := rows.Scan(&referrer.Referrer, &referrer.Count); err != nil {
+			return nil, err
+		}
+		stats.TopReferrers = append(stats.TopReferrers, referrer)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
 // GetRequestStats retrieves aggregated request statistics from the database
-func (db *PGPersistence) GetRequestStats() (*RequestStats, error) {
+func (db *PGPersistence) GetRequestStats(ctx context.Context) (*RequestStats, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
 
 	var stats RequestStats
 
@@ -162,7 +936,7 @@ func (db *PGPersistence) GetRequestStats() (*RequestStats, error) {
 	FROM
 		base.logged_requests;`
 
-	if err := db.Conn.QueryRow(context.TODO(), statsQuery).Scan(&stats.TotalRequests, &stats.UniqueIPCount); err != nil {
+	if err := db.Conn.QueryRow(ctx, statsQuery).Scan(&stats.TotalRequests, &stats.UniqueIPCount); err != nil {
 		return nil, err
 	}
 
@@ -175,7 +949,7 @@ func (db *PGPersistence) GetRequestStats() (*RequestStats, error) {
 		ORDER BY
 			request_count DESC;`
 
-	rows, err := db.Conn.Query(context.TODO(), pathQuery)
+	rows, err := db.Conn.Query(ctx, pathQuery)
 	if err != nil {
 		return nil, err
 	}
@@ -191,6 +965,9 @@ func (db *PGPersistence) GetRequestStats() (*RequestStats, error) {
 		}
 		pathCounts[path] = count
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
 	stats.PathCounts = pathCounts
 
@@ -203,7 +980,7 @@ func (db *PGPersistence) GetRequestStats() (*RequestStats, error) {
 		ORDER BY
 			status_count DESC;`
 
-	rows, err = db.Conn.Query(context.TODO(), statusQuery)
+	rows, err = db.Conn.Query(ctx, statusQuery)
 	if err != nil {
 		return nil, err
 	}
@@ -219,6 +996,9 @@ func (db *PGPersistence) GetRequestStats() (*RequestStats, error) {
 		}
 		statusCounts[status] = count
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
 	stats.StatusCounts = statusCounts
 
@@ -226,10 +1006,12 @@ func (db *PGPersistence) GetRequestStats() (*RequestStats, error) {
 }
 
 // GetAPIKey retrieves an API key from the database
-func (db *PGPersistence) GetAPIKey(key string) (*APIKey, error) {
+func (db *PGPersistence) GetAPIKey(ctx context.Context, key string) (*APIKey, error) {
+	ctx, cancel := db.queryContext(ctx)
+	defer cancel()
 
 	var apiKey APIKey
-	response, err := db.Conn.Query(context.TODO(),
+	response, err := db.Conn.Query(ctx,
 		"SELECT key, owner, created_at, expires_at FROM base.api_keys WHERE key=$1", key)
 	if err != nil {
 		return nil, err
@@ -243,19 +1025,52 @@ func (db *PGPersistence) GetAPIKey(key string) (*APIKey, error) {
 		}
 		return &apiKey, nil
 	}
+	if err := response.Err(); err != nil {
+		return nil, err
+	}
 
 	return nil, APIKeyNotFoundError{Key: key}
 }
 
-func NewPGPersistence(dsn string) (*PGPersistence, error) {
+// Close releases all connections held by the underlying pool.
+func (db *PGPersistence) Close() error {
+	db.Conn.Close()
+	return nil
+}
+
+// NewPGPersistence creates a new PGPersistence backed by a connection
+// pool. The pool is long-lived and is intended to be shared by all
+// handlers and middlewares for the lifetime of the process.
+func NewPGPersistence(ctx context.Context, cfg *Config) (*PGPersistence, error) {
+	poolConfig, err := PostgresPoolConfigFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create a new PostgreSQL connection pool
 	// using the configuration parameters
-	pool, err := pgxpool.New(context.TODO(), dsn)
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return nil, err
 	}
 
 	return &PGPersistence{
-		Conn: pool,
+		Conn:         pool,
+		QueryTimeout: cfg.PostgresQueryTimeout,
 	}, nil
 }
+
+// NewPersistence creates the Persistence implementation
+// selected by the PersistenceBackend config setting.
+func NewPersistence(ctx context.Context, cfg *Config) (Persistence, error) {
+	switch cfg.PersistenceBackend {
+	case PersistenceBackendMemory:
+		return NewMemoryPersistence(cfg.MemorySnapshotPath)
+	case PersistenceBackendSQLite:
+		return NewSQLitePersistence(ctx, cfg.SQLitePath)
+	case PersistenceBackendPostgres:
+		return NewPGPersistence(ctx, cfg)
+	default:
+		return nil, fmt.Errorf("unsupported persistence backend %q", cfg.PersistenceBackend)
+	}
+}
@@ -1,28 +1,39 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"slices"
 	"strconv"
+	"time"
 )
 
 type TestPersistence struct {
-	Contacts        map[string]Contact
-	ContactRequests map[string][]ContactRequest
-	LoggedRequests  []LoggedRequest
-	LoggedResponses []LoggedResponse
-	APIKeys         map[string]APIKey
-	Healthy         bool
+	Contacts           map[string]Contact
+	ContactRequests    map[string][]ContactRequest
+	LoggedRequests     []LoggedRequest
+	LoggedResponses    []LoggedResponse
+	APIKeys            map[string]APIKey
+	WebhookDeliveries  []WebhookDelivery
+	RedeemedChallenges map[string]time.Time
+	ResumeVersions     []ResumeVersion
+	ResumeShares       []ResumeShare
+	ResumeDownloads    []ResumeDownload
+	Healthy            bool
 }
 
-func (t *TestPersistence) HealthCheck() error {
+func (t *TestPersistence) HealthCheck(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if t.Healthy {
 		return nil
 	}
 	return errors.New("something went wrong")
 }
 
-func (t *TestPersistence) GetContact(email string) (*Contact, error) {
+func (t *TestPersistence) GetContact(ctx context.Context, email string) (*Contact, error) {
 	var contact *Contact
 	found := false
 	for _, c := range t.Contacts {
@@ -38,7 +49,7 @@ func (t *TestPersistence) GetContact(email string) (*Contact, error) {
 	return contact, nil
 }
 
-func (t *TestPersistence) CreateContact(contact Contact) (string, error) {
+func (t *TestPersistence) CreateContact(ctx context.Context, contact Contact) (string, error) {
 	ids := []int{}
 
 	for id := range t.Contacts {
@@ -60,7 +71,7 @@ func (t *TestPersistence) CreateContact(contact Contact) (string, error) {
 	return id, nil
 }
 
-func (t *TestPersistence) ListContacts() ([]Contact, error) {
+func (t *TestPersistence) ListContacts(ctx context.Context) ([]Contact, error) {
 	var contacts []Contact
 	for _, contact := range t.Contacts {
 		contacts = append(contacts, contact)
@@ -68,7 +79,7 @@ func (t *TestPersistence) ListContacts() ([]Contact, error) {
 	return contacts, nil
 }
 
-func (t *TestPersistence) GetRequestStats() (*RequestStats, error) {
+func (t *TestPersistence) GetRequestStats(ctx context.Context) (*RequestStats, error) {
 	var stats RequestStats
 	stats.TotalRequests = 100
 	stats.UniqueIPCount = 50
@@ -79,7 +90,7 @@ func (t *TestPersistence) GetRequestStats() (*RequestStats, error) {
 	return &stats, nil
 }
 
-func (t *TestPersistence) CreateContactRequest(entry ContactRequest) (string, error) {
+func (t *TestPersistence) CreateContactRequest(ctx context.Context, entry ContactRequest) (string, error) {
 	_, exists := t.ContactRequests[entry.ContactId]
 	if exists {
 		t.ContactRequests[entry.ContactId] = append(t.ContactRequests[entry.ContactId], entry)
@@ -89,7 +100,7 @@ func (t *TestPersistence) CreateContactRequest(entry ContactRequest) (string, er
 	return entry.Id, nil
 }
 
-func (t *TestPersistence) ListContactRequests() ([]ContactRequest, error) {
+func (t *TestPersistence) ListContactRequests(ctx context.Context) ([]ContactRequest, error) {
 	var requests []ContactRequest
 	for _, reqs := range t.ContactRequests {
 		requests = append(requests, reqs...)
@@ -97,20 +108,276 @@ func (t *TestPersistence) ListContactRequests() ([]ContactRequest, error) {
 	return requests, nil
 }
 
-func (t *TestPersistence) LogRequest(entry LoggedRequest) (string, error) {
-	t.LoggedRequests = append(t.LoggedRequests, entry)
-	return entry.ID, nil
+func (t *TestPersistence) RedeemChallenge(ctx context.Context, id string, expiresAt time.Time) error {
+	if t.RedeemedChallenges == nil {
+		t.RedeemedChallenges = make(map[string]time.Time)
+	}
+	if _, ok := t.RedeemedChallenges[id]; ok {
+		return ChallengeUsedError{Id: id}
+	}
+	t.RedeemedChallenges[id] = expiresAt
+	return nil
+}
+
+func (t *TestPersistence) CreateResumeVersion(ctx context.Context, version ResumeVersion) (*ResumeVersion, error) {
+	for i := range t.ResumeVersions {
+		if t.ResumeVersions[i].Variant == version.Variant && t.ResumeVersions[i].Locale == version.Locale && t.ResumeVersions[i].Format == version.Format {
+			t.ResumeVersions[i].Active = false
+		}
+	}
+	version.Id = fmt.Sprintf("version-%d", len(t.ResumeVersions)+1)
+	version.Size = len(version.Content)
+	version.Active = true
+	version.CreatedAt = time.Now()
+	version.ActivatedAt = version.CreatedAt
+	t.ResumeVersions = append(t.ResumeVersions, version)
+
+	created := version.withoutContent()
+	return &created, nil
+}
+
+func (t *TestPersistence) ListResumeVersions(ctx context.Context) ([]ResumeVersion, error) {
+	versions := []ResumeVersion{}
+	for _, version := range slices.Backward(t.ResumeVersions) {
+		versions = append(versions, version.withoutContent())
+	}
+	return versions, nil
+}
+
+func (t *TestPersistence) GetResumeVersion(ctx context.Context, id string) (*ResumeVersion, error) {
+	for _, version := range t.ResumeVersions {
+		if version.Id == id {
+			return &version, nil
+		}
+	}
+	return nil, ResumeVersionNotFoundError{Id: id}
+}
+
+func (t *TestPersistence) ActivateResumeVersion(ctx context.Context, id string) (*ResumeVersion, error) {
+	index := slices.IndexFunc(t.ResumeVersions, func(version ResumeVersion) bool { return version.Id == id })
+	if index < 0 {
+		return nil, ResumeVersionNotFoundError{Id: id}
+	}
+	for i := range t.ResumeVersions {
+		if t.ResumeVersions[i].Variant == t.ResumeVersions[index].Variant && t.ResumeVersions[i].Locale == t.ResumeVersions[index].Locale && t.ResumeVersions[i].Format == t.ResumeVersions[index].Format {
+			t.ResumeVersions[i].Active = false
+		}
+	}
+	t.ResumeVersions[index].Active = true
+	t.ResumeVersions[index].ActivatedAt = time.Now()
+
+	activated := t.ResumeVersions[index].withoutContent()
+	return &activated, nil
+}
+
+func (t *TestPersistence) ListActiveResumeVersions(ctx context.Context) ([]ResumeVersion, error) {
+	var versions []ResumeVersion
+	for _, version := range t.ResumeVersions {
+		if version.Active {
+			versions = append(versions, version)
+		}
+	}
+	return versions, nil
+}
+
+func (t *TestPersistence) CreateResumeShare(ctx context.Context, share ResumeShare) (*ResumeShare, error) {
+	share.Id = fmt.Sprintf("share-%d", len(t.ResumeShares)+1)
+	share.CreatedAt = time.Now()
+	t.ResumeShares = append(t.ResumeShares, share)
+	return &share, nil
+}
+
+func (t *TestPersistence) ListResumeShares(ctx context.Context) ([]ResumeShare, error) {
+	shares := []ResumeShare{}
+	for _, share := range slices.Backward(t.ResumeShares) {
+		shares = append(shares, share)
+	}
+	return shares, nil
+}
+
+func (t *TestPersistence) GetResumeShare(ctx context.Context, id string) (*ResumeShare, error) {
+	index := slices.IndexFunc(t.ResumeShares, func(share ResumeShare) bool { return share.Id == id })
+	if index < 0 {
+		return nil, ResumeShareNotFoundError{Id: id}
+	}
+	share := t.ResumeShares[index]
+	return &share, nil
+}
+
+func (t *TestPersistence) OpenResumeShare(ctx context.Context, id string) (*ResumeShare, error) {
+	index := slices.IndexFunc(t.ResumeShares, func(share ResumeShare) bool { return share.Id == id })
+	if index < 0 {
+		return nil, ResumeShareNotFoundError{Id: id}
+	}
+	share := &t.ResumeShares[index]
+	if share.SingleUse && share.Opens > 0 {
+		return nil, ResumeShareUsedError{Id: id}
+	}
+	now := time.Now()
+	share.Opens++
+	if share.FirstOpenedAt == nil {
+		share.FirstOpenedAt = &now
+	}
+	share.LastOpenedAt = &now
+
+	opened := *share
+	return &opened, nil
+}
+
+func (t *TestPersistence) RecordResumeDownload(ctx context.Context, download ResumeDownload) error {
+	t.ResumeDownloads = append(t.ResumeDownloads, download)
+	return nil
+}
+
+func (t *TestPersistence) GetResumeStats(ctx context.Context, query ResumeStatsQuery) (*ResumeStats, error) {
+	stats := ResumeStats{Since: query.Since, Until: query.Until, Daily: []ResumeDailyDownloads{}, TopReferrers: []ReferrerCount{}}
+	for _, download := range t.ResumeDownloads {
+		stats.TotalDownloads++
+		stats.Daily = addDailyDownloads(stats.Daily, download.DownloadedAt.UTC().Format(time.DateOnly), download.Format, 1)
+	}
+	return &stats, nil
 }
 
-func (t *TestPersistence) LogResponse(entry LoggedResponse) error {
-	t.LoggedResponses = append(t.LoggedResponses, entry)
+func (t *TestPersistence) LogRequests(ctx context.Context, requests []LoggedRequest, responses []LoggedResponse) error {
+	t.LoggedRequests = append(t.LoggedRequests, requests...)
+	t.LoggedResponses = append(t.LoggedResponses, responses...)
 	return nil
 }
 
-func (t *TestPersistence) GetAPIKey(key string) (*APIKey, error) {
+func (t *TestPersistence) GetAPIKey(ctx context.Context, key string) (*APIKey, error) {
 	apiKey, exists := t.APIKeys[key]
 	if !exists {
 		return nil, APIKeyNotFoundError{Key: key}
 	}
 	return &apiKey, nil
 }
+
+func (t *TestPersistence) Close() error {
+	return nil
+}
+
+func (t *TestPersistence) QueryContacts(ctx context.Context, query ListQuery) ([]Contact, *ListCursor, error) {
+	contacts, _ := t.ListContacts(ctx)
+	contacts = filterListQuery(contacts, query, func(contact Contact) (ListCursor, string, string) {
+		return contactCursor(contact), contact.Email, contact.Id
+	})
+	contacts, next := nextPage(contacts, query.Limit, contactCursor)
+	return contacts, next, nil
+}
+
+func (t *TestPersistence) QueryContactRequests(ctx context.Context, query ListQuery) ([]ContactRequest, *ListCursor, error) {
+	requests, _ := t.ListContactRequests(ctx)
+	if query.Status != "" {
+		requests = slices.DeleteFunc(requests, func(request ContactRequest) bool {
+			return request.Status != query.Status
+		})
+	}
+	requests = filterListQuery(requests, query, func(request ContactRequest) (ListCursor, string, string) {
+		return contactRequestCursor(request), request.Email, request.ContactId
+	})
+	requests, next := nextPage(requests, query.Limit, contactRequestCursor)
+	return requests, next, nil
+}
+
+func (t *TestPersistence) UpdateContactRequest(ctx context.Context, id string, update ContactRequestUpdate) (*ContactRequest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for contactId, requests := range t.ContactRequests {
+		for i, request := range requests {
+			if request.Id != id {
+				continue
+			}
+			if update.Status != nil {
+				if !request.Status.CanTransitionTo(*update.Status) {
+					return nil, InvalidStatusTransitionError{From: request.Status, To: *update.Status}
+				}
+				request.Status = *update.Status
+			}
+			if update.Notes != nil {
+				request.Notes = *update.Notes
+			}
+			t.ContactRequests[contactId][i] = request
+			return &request, nil
+		}
+	}
+	return nil, ContactRequestNotFoundError{Id: id}
+}
+
+func (t *TestPersistence) GetContactById(ctx context.Context, id string) (*Contact, error) {
+	contact, exists := t.Contacts[id]
+	if !exists {
+		return nil, ContactNotFoundError{Id: id}
+	}
+	return &contact, nil
+}
+
+func (t *TestPersistence) UpdateContact(ctx context.Context, id string, update ContactUpdate) (*Contact, error) {
+	contact, exists := t.Contacts[id]
+	if !exists {
+		return nil, ContactNotFoundError{Id: id}
+	}
+	if update.Email != nil {
+		for existingId, existing := range t.Contacts {
+			if existingId != id && existing.Email == *update.Email {
+				return nil, ContactConflictError{Email: *update.Email}
+			}
+		}
+		contact.Email = *update.Email
+	}
+	if update.Name != nil {
+		contact.Name = *update.Name
+	}
+	t.Contacts[id] = contact
+	return &contact, nil
+}
+
+func (t *TestPersistence) DeleteContact(ctx context.Context, id string) error {
+	if _, exists := t.Contacts[id]; !exists {
+		return ContactNotFoundError{Id: id}
+	}
+	delete(t.Contacts, id)
+	delete(t.ContactRequests, id)
+	return nil
+}
+
+func (t *TestPersistence) CreateWebhookDeliveries(ctx context.Context, deliveries []WebhookDelivery) error {
+	for _, delivery := range deliveries {
+		delivery.Id = strconv.Itoa(len(t.WebhookDeliveries) + 1)
+		delivery.Status = WebhookDeliveryPending
+		t.WebhookDeliveries = append(t.WebhookDeliveries, delivery)
+	}
+	return nil
+}
+
+func (t *TestPersistence) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	for i, delivery := range t.WebhookDeliveries {
+		if len(deliveries) == limit {
+			break
+		}
+		if delivery.Status != WebhookDeliveryPending || delivery.NextAttemptAt.After(time.Now()) {
+			continue
+		}
+		t.WebhookDeliveries[i].NextAttemptAt = time.Now().Add(lease)
+		deliveries = append(deliveries, t.WebhookDeliveries[i])
+	}
+	return deliveries, nil
+}
+
+func (t *TestPersistence) UpdateWebhookDelivery(ctx context.Context, delivery WebhookDelivery) error {
+	for i, existing := range t.WebhookDeliveries {
+		if existing.Id == delivery.Id {
+			t.WebhookDeliveries[i] = delivery
+		}
+	}
+	return nil
+}
+
+func (t *TestPersistence) QueryWebhookDeliveries(ctx context.Context, query ListQuery) ([]WebhookDelivery, *ListCursor, error) {
+	deliveries := filterListQuery(t.WebhookDeliveries, query, func(delivery WebhookDelivery) (ListCursor, string, string) {
+		return webhookDeliveryCursor(delivery), "", ""
+	})
+	deliveries, next := nextPage(deliveries, query.Limit, webhookDeliveryCursor)
+	return deliveries, next, nil
+}
@@ -1,5 +1,10 @@
 package main
 
+import (
+	"fmt"
+	"strings"
+)
+
 type APIKeyNotFoundError struct {
 	Key string
 }
@@ -10,8 +15,134 @@ func (e APIKeyNotFoundError) Error() string {
 
 type ContactNotFoundError struct {
 	Email string
+	Id    string
 }
 
 func (e ContactNotFoundError) Error() string {
+	if e.Id != "" {
+		return "contact not found with id " + e.Id
+	}
 	return "contact not found with email " + e.Email
 }
+
+type ResumeVersionNotFoundError struct {
+	Id string
+}
+
+func (e ResumeVersionNotFoundError) Error() string {
+	return "resume version not found with id " + e.Id
+}
+
+type ResumeShareNotFoundError struct {
+	Id string
+}
+
+func (e ResumeShareNotFoundError) Error() string {
+	return "resume share not found with id " + e.Id
+}
+
+// ResumeShareUsedError is returned when a single use
+// link to the resume has already been opened
+type ResumeShareUsedError struct {
+	Id string
+}
+
+func (e ResumeShareUsedError) Error() string {
+	return "resume share already used " + e.Id
+}
+
+type ContactConflictError struct {
+	Email string
+}
+
+func (e ContactConflictError) Error() string {
+	return "contact already exists with email " + e.Email
+}
+
+type ContactRequestNotFoundError struct {
+	Id string
+}
+
+func (e ContactRequestNotFoundError) Error() string {
+	return "contact request not found with id " + e.Id
+}
+
+type InvalidStatusTransitionError struct {
+	From ContactRequestStatus
+	To   ContactRequestStatus
+}
+
+func (e InvalidStatusTransitionError) Error() string {
+	return fmt.Sprintf("cannot transition contact request from %s to %s", e.From, e.To)
+}
+
+type InvalidCursorError struct {
+	Cursor string
+}
+
+func (e InvalidCursorError) Error() string {
+	return "invalid cursor " + e.Cursor
+}
+
+type InvalidChallengeError struct {
+	Reason string
+}
+
+func (e InvalidChallengeError) Error() string {
+	return "invalid challenge: " + e.Reason
+}
+
+type ChallengeUsedError struct {
+	Id string
+}
+
+func (e ChallengeUsedError) Error() string {
+	return "challenge already used " + e.Id
+}
+
+// ResumeFieldError describes a field of the JSON resume that
+// failed validation. Fields are given by their JSON path.
+type ResumeFieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type ResumeValidationError struct {
+	Fields []ResumeFieldError
+}
+
+func (e ResumeValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = strings.TrimSpace(field.Field + " " + field.Message)
+	}
+	return "invalid resume: " + strings.Join(messages, "; ")
+}
+
+type UnknownResumeSectionError struct {
+	Name string
+}
+
+func (e UnknownResumeSectionError) Error() string {
+	return "unknown resume section " + e.Name
+}
+
+type UnknownResumeVariantError struct {
+	Name string
+}
+
+func (e UnknownResumeVariantError) Error() string {
+	return "unknown resume variant " + e.Name
+}
+
+// InvalidResumeShareTokenError is returned for share tokens that were
+// not issued by this server or have expired.
+type InvalidResumeShareTokenError struct {
+	Reason string
+	// Expired is set if the token is valid but has expired
+	Expired bool
+}
+
+func (e InvalidResumeShareTokenError) Error() string {
+	return "invalid resume share token: " + e.Reason
+}
@@ -1,13 +1,22 @@
 package main
 
 import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
 	"encoding/base64"
-	"encoding/json"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"os"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
@@ -19,9 +28,9 @@ import (
 func HealthCheckHandler(c *gin.Context, db Persistence) RESTResponse {
 	// Perform a simple database health check
 	// If the database is unreachable, return a 500 error
-	if err := db.HealthCheck(); err != nil {
+	if err := db.HealthCheck(RequestContext(c)); err != nil {
 		log.Error(fmt.Sprintf("database health check failed: %v", err))
-		return InternalServerErrorResponse
+		return PersistenceErrorResponse(err)
 	}
 
 	response := RESTResponse{
@@ -42,78 +51,263 @@ func VersionHandler(c *gin.Context, config *Config) RESTResponse {
 	return response
 }
 
-// ResumeHandler serves the resume file located at the configured path.
-func ResumeHandler(c *gin.Context, config *Config) RESTResponse {
-	formatString := c.Query("format")
-	if len(formatString) == 0 {
-		formatString = "json"
+// ResumeHandler serves the resume held in the ResumeStore. The variant
+// query parameter falls back to the default variant, the language is
+// negotiated using the Accept-Language header, and the format is
+// negotiated using the Accept header, falling back to the format query
+// parameter. PDFs are only streamed as a file if download is set or the
+// client prefers them.
+func ResumeHandler(c *gin.Context, store *ResumeStore, renderer *ResumeRenderer) RESTResponse {
+	// the response depends on the Accept and Accept-Language
+	// headers as well as the query parameters
+	c.Header("Vary", "Accept, Accept-Language")
+
+	supported := make([]string, len(resumeFormats))
+	for i, format := range resumeFormats {
+		supported[i] = format.MediaType()
+	}
+	accept := c.GetHeader("Accept")
+	preferred := NegotiateMediaType(accept, supported...)
+	index := slices.Index(supported, preferred)
+
+	// the Accept header takes precedence, falling back to the
+	// format query parameter if the header does not name a
+	// media type or none of the named types are supported
+	var format ResumeFileFormat
+	if formatString := c.Query("format"); len(formatString) > 0 && (index < 0 || AcceptsAnyMediaType(accept)) {
+		// parse format into ResumeFileFormat
+		format = ResumeFileFormat(strings.ToLower(formatString))
+		// validate format
+		if !slices.Contains(resumeFormats, format) {
+			log.Error(fmt.Sprintf("invalid resume format requested: %s", format))
+			return BadRequestResponse
+		}
+	} else {
+		if index < 0 {
+			log.Error(fmt.Sprintf("no acceptable resume format for %q", accept))
+			return NotAcceptableResponse(supported)
+		}
+		format = resumeFormats[index]
 	}
-	// parse format into ResumeFileFormat
-	format := ResumeFileFormat(strings.ToLower(formatString))
-	// validate format
-	validModes := []string{"json", "pdf"}
-	if !slices.Contains(validModes, string(format)) {
-		log.Error(fmt.Sprintf("invalid resume format requested: %s", format))
+
+	// generated PDFs are cached for the whole resume, so
+	// only other formats can be restricted to parts of it
+	query := ParseResumeQuery(c.Query("fields"), c.Query("since"))
+	if format == ResumeFormatPDF && !query.IsZero() {
+		log.Error("fields and since are not supported for PDF resumes")
 		return BadRequestResponse
 	}
 
-	// determine file path based on format
-	var filePath string
-	switch format {
-	case ResumeFormatPDF:
-		filePath = config.ResumePathPDF
-	case ResumeFormatJSON:
-		filePath = config.ResumePathJSON
+	snapshot, err := store.Select(c.Query("variant"), c.GetHeader("Accept-Language"))
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to select resume: %v", err))
+		return NotFoundResponse
 	}
+	c.Header("Content-Language", snapshot.Locale)
+	c.Set(resumeDownloadKey, ResumeDownload{Format: format, Variant: snapshot.Variant, Locale: snapshot.Locale})
 
-	log.Info(fmt.Sprintf("serving resume file: %s", filePath))
-	// read file contents
-	contents, err := os.ReadFile(filePath)
+	download, _ := strconv.ParseBool(c.Query("download"))
+	streamed := format == ResumeFormatPDF && (download || preferred == mimePDF)
+	return serveResume(snapshot, format, query, streamed, renderer)
+}
+
+// serveResume returns the snapshot of the resume in the given format,
+// restricted by the query. PDFs are streamed as a file if streamed is
+// set, and are otherwise base64 encoded into the JSON payload.
+func serveResume(snapshot *ResumeSnapshot, format ResumeFileFormat, query ResumeQuery, streamed bool, renderer *ResumeRenderer) RESTResponse {
+	resume, err := query.Apply(snapshot.Resume)
 	if err != nil {
-		log.Error(fmt.Sprintf("failed to read resume file: %v", err))
-		return InternalServerErrorResponse
+		log.Error(fmt.Sprintf("invalid resume query: %v", err))
+		return BadRequestResponse
 	}
 
-	switch format {
-	case ResumeFormatPDF:
+	// the PDF is generated from the JSON resume
+	// unless a static PDF has been configured
+	if format == ResumeFormatPDF && snapshot.PDF != nil {
+		log.Info(fmt.Sprintf("serving resume file: %s", snapshot.PDF.Path))
+		if streamed {
+			return RESTResponse{
+				Code: 200,
+				File: &FileResponse{
+					Name:        filepath.Base(snapshot.PDF.Path),
+					ContentType: mimePDF,
+					Content:     snapshot.PDF.Content,
+					ETag:        snapshot.PDF.ETag("pdf"),
+					ModTime:     snapshot.PDF.ModTime,
+				},
+			}
+		}
+
 		// encode file contents to base64
-		encoded := base64.StdEncoding.EncodeToString(contents)
+		encoded := base64.StdEncoding.EncodeToString(snapshot.PDF.Content)
 		return RESTResponse{
 			Code: 200,
 			Payload: gin.H{
 				"data": encoded,
 			},
+			ETag:         snapshot.PDF.ETag("base64"),
+			LastModified: snapshot.PDF.ModTime,
 		}
+	}
 
-	case ResumeFormatJSON:
-		// unmarshal JSON contents
-		var data map[string]any
-		if err := json.Unmarshal(contents, &data); err != nil {
-			log.Error(fmt.Sprintf("failed to unmarshal JSON resume file: %v", err))
+	// all other formats are rendered from the validated resume
+	log.Info(fmt.Sprintf("serving resume file: %s", snapshot.JSON.Path))
+	switch format {
+	case ResumeFormatPDF:
+		contents, etag, err := renderer.RenderPDF(snapshot.Resume, snapshot.JSON.Hash)
+		if err != nil {
+			log.Error(fmt.Sprintf("failed to generate PDF resume: %v", err))
 			return InternalServerErrorResponse
 		}
 
+		if streamed {
+			return RESTResponse{
+				Code: 200,
+				File: &FileResponse{
+					Name:        "resume.pdf",
+					ContentType: mimePDF,
+					Content:     contents,
+					ETag:        etag,
+					ModTime:     snapshot.JSON.ModTime,
+				},
+			}
+		}
+
+		return RESTResponse{
+			Code: 200,
+			Payload: gin.H{
+				"data": base64.StdEncoding.EncodeToString(contents),
+			},
+			// the base64 payload is a different
+			// representation of the same PDF
+			ETag:         strings.TrimSuffix(etag, `"`) + `-base64"`,
+			LastModified: snapshot.JSON.ModTime,
+		}
+
+	case ResumeFormatJSON:
 		return RESTResponse{
 			Code: 200,
 			Payload: gin.H{
-				"data": data,
+				"data": resume,
 			},
+			ETag:         snapshot.JSON.ETag(string(format)),
+			LastModified: snapshot.JSON.ModTime,
+		}
+
+	case ResumeFormatHTML, ResumeFormatMarkdown, ResumeFormatText:
+		rendered, err := renderer.Render(format, resume)
+		if err != nil {
+			log.Error(fmt.Sprintf("failed to render resume as %s: %v", format, err))
+			return InternalServerErrorResponse
+		}
+
+		return RESTResponse{
+			Code:         200,
+			ContentType:  format.MediaType() + "; charset=utf-8",
+			Body:         rendered,
+			ETag:         snapshot.JSON.ETag(string(format)),
+			LastModified: snapshot.JSON.ModTime,
 		}
 	default:
 		return NotImplementedResponse
 	}
 }
 
+// ResumeSectionHandler serves a single section of the resume, e.g. work,
+// so that clients only need to fetch the parts of the resume they render.
+// The variant and language are selected in the same way as ResumeHandler.
+func ResumeSectionHandler(c *gin.Context, store *ResumeStore) RESTResponse {
+	c.Header("Vary", "Accept-Language")
+
+	snapshot, err := store.Select(c.Query("variant"), c.GetHeader("Accept-Language"))
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to select resume: %v", err))
+		return NotFoundResponse
+	}
+	c.Header("Content-Language", snapshot.Locale)
+
+	resume, err := ParseResumeQuery("", c.Query("since")).Apply(snapshot.Resume)
+	if err != nil {
+		log.Error(fmt.Sprintf("invalid resume query: %v", err))
+		return BadRequestResponse
+	}
+
+	section, err := resume.Section(c.Param("name"))
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to get resume section: %v", err))
+		return NotFoundResponse
+	}
+
+	return RESTResponse{
+		Code: 200,
+		Payload: gin.H{
+			"data": section,
+		},
+		ETag:         snapshot.JSON.ETag("section"),
+		LastModified: snapshot.JSON.ModTime,
+	}
+}
+
+// ResumeVariantsHandler lists the variants of the resume
+// and the languages each variant is available in.
+func ResumeVariantsHandler(c *gin.Context, store *ResumeStore) RESTResponse {
+	response := RESTResponse{
+		Code: 200,
+		Payload: gin.H{
+			"data": store.Variants(),
+		},
+	}
+	return response
+}
+
 type ContactRequestBody struct {
 	Name    string `json:"name" binding:"required"`
 	Email   string `json:"email" binding:"required,email"`
 	Message string `json:"message" binding:"required"`
+	// hidden honeypot field that must be left empty
+	Website string `json:"website"`
+	// form token issued by ContactTokenHandler
+	Token string `json:"token"`
+	// proof-of-work challenge issued by ChallengeHandler
+	// and the nonce that solves it
+	Challenge string `json:"challenge" binding:"required"`
+	Nonce     string `json:"nonce" binding:"required"`
+}
+
+// ChallengeHandler issues a proof-of-work challenge, which must
+// be solved before submitting the contact form.
+func ChallengeHandler(c *gin.Context, pow *ProofOfWork) RESTResponse {
+	challenge, err := pow.Issue(time.Now())
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to issue challenge: %v", err))
+		return InternalServerErrorResponse
+	}
+
+	response := RESTResponse{
+		Code: 200,
+		Payload: gin.H{
+			"data": challenge,
+		},
+	}
+	return response
+}
+
+// ContactTokenHandler issues a signed form token, which is submitted
+// with the contact form so that the time taken to fill it can be checked.
+func ContactTokenHandler(c *gin.Context, filter *SpamFilter) RESTResponse {
+	response := RESTResponse{
+		Code: 200,
+		Payload: gin.H{
+			"data": filter.IssueToken(time.Now()),
+		},
+	}
+	return response
 }
 
 // ContactHandler handles contact form submissions.
 // It creates a new contact if one does not exist
 // and logs the contact request message.
-func ContactHandler(c *gin.Context, db Persistence) RESTResponse {
+func ContactHandler(c *gin.Context, db Persistence, notifier Notifier, events EventPublisher, filter *SpamFilter, pow *ProofOfWork) RESTResponse {
 	var body ContactRequestBody
 	if err := c.ShouldBindJSON(&body); err != nil {
 		log.Error(fmt.Sprintf("invalid contact request payload: %v", err))
@@ -121,13 +315,41 @@ func ContactHandler(c *gin.Context, db Persistence) RESTResponse {
 	}
 	// Normalize email to lowercase
 	email := strings.ToLower(body.Email)
+	ctx := RequestContext(c)
+	// events are still published if the client
+	// disconnects once the request has been stored
+	eventCtx := context.WithoutCancel(ctx)
+
+	challengeId, expiresAt, err := pow.Verify(body.Challenge, body.Nonce, time.Now())
+	if err != nil {
+		log.Warn(fmt.Sprintf("rejecting contact request from %s: %v", email, err))
+		return ForbiddenResponse
+	}
+
+	// the challenge is redeemed before anything is stored,
+	// so that each solution can only be submitted once
+	if err := db.RedeemChallenge(ctx, challengeId, expiresAt); err != nil {
+		var errUsed ChallengeUsedError
+		if errors.As(err, &errUsed) {
+			log.Warn(fmt.Sprintf("rejecting contact request from %s: %v", email, err))
+			return ForbiddenResponse
+		}
+		log.Error(fmt.Sprintf("failed to redeem challenge: %v", err))
+		return PersistenceErrorResponse(err)
+	}
+
+	spam := filter.Check(body, c.ClientIP(), time.Now())
+	if spam.Flagged {
+		log.Warn(fmt.Sprintf("contact request from %s flagged as spam with score %d: %s",
+			email, spam.Score, strings.Join(spam.Reasons, ", ")))
+	}
 
-	contact, err := db.GetContact(email)
+	contact, err := db.GetContact(ctx, email)
 	if err != nil {
 		log.Error(fmt.Sprintf("failed to get contact: %v", err))
 		var errNotFound ContactNotFoundError
 		if !errors.As(err, &errNotFound) {
-			return InternalServerErrorResponse
+			return PersistenceErrorResponse(err)
 		}
 	}
 
@@ -142,10 +364,16 @@ func ContactHandler(c *gin.Context, db Persistence) RESTResponse {
 			Email: email,
 		}
 
-		contactId, err = db.CreateContact(newContact)
+		contactId, err = db.CreateContact(ctx, newContact)
 		if err != nil {
 			log.Error(fmt.Sprintf("failed to create contact: %v", err))
-			return InternalServerErrorResponse
+			return PersistenceErrorResponse(err)
+		}
+
+		newContact.Id = contactId
+		newContact.CreatedAt = time.Now()
+		if !spam.Flagged {
+			events.Publish(eventCtx, EventContactCreated, newContact)
 		}
 	} else {
 		log.Info(fmt.Sprintf("using existing contact for email: %s", body.Email))
@@ -154,17 +382,44 @@ func ContactHandler(c *gin.Context, db Persistence) RESTResponse {
 
 	request := ContactRequest{
 		ContactId: contactId,
+		Email:     email,
 		Message:   body.Message,
+		Status:    ContactRequestStatusNew,
+		SpamScore: spam.Score,
+	}
+	if spam.Flagged {
+		request.Status = ContactRequestStatusSpam
+		request.Notes = "flagged by spam filter: " + strings.Join(spam.Reasons, ", ")
 	}
 
 	// Log the contact request
-	id, err := db.CreateContactRequest(request)
+	id, err := db.CreateContactRequest(ctx, request)
 	if err != nil {
 		log.Error(fmt.Sprintf("failed to create contact request: %v", err))
-		return InternalServerErrorResponse
+		return PersistenceErrorResponse(err)
 	}
 	log.Info(fmt.Sprintf("created contact request with id: %s", id))
 
+	request.Id = id
+	request.CreatedAt = time.Now()
+
+	// flagged submissions receive the same response, so
+	// that bots cannot tell that they have been caught
+	if !spam.Flagged {
+		events.Publish(eventCtx, EventContactRequestCreated, request)
+
+		// notifications are sent in the background
+		// and never delay the response
+		notifier.NotifyContactRequest(ContactNotification{
+			Name:      body.Name,
+			Email:     email,
+			Message:   body.Message,
+			ContactId: contactId,
+			RequestId: id,
+			CreatedAt: request.CreatedAt,
+		})
+	}
+
 	response := RESTResponse{
 		Code: 201,
 		Payload: gin.H{
@@ -176,11 +431,64 @@ func ContactHandler(c *gin.Context, db Persistence) RESTResponse {
 
 // StatsHandler returns request statistics from the database.
 // This includes metrics such as total requests, requests per endpoint, etc.
-func StatsHandler(c *gin.Context, db Persistence) RESTResponse {
-	stats, err := db.GetRequestStats()
+// along with the number of requests the RequestLogger of this instance
+// has dropped.
+func StatsHandler(c *gin.Context, db Persistence, logger *RequestLogger) RESTResponse {
+	stats, err := db.GetRequestStats(RequestContext(c))
 	if err != nil {
 		log.Error(fmt.Sprintf("failed to get request stats: %v", err))
-		return InternalServerErrorResponse
+		return PersistenceErrorResponse(err)
+	}
+	stats.DroppedRequestLogs = logger.Dropped()
+
+	response := RESTResponse{
+		Code:    200,
+		Payload: gin.H{"data": stats},
+	}
+	return response
+}
+
+const (
+	// DefaultResumeStatsPeriod is the period covered by
+	// resume statistics if no since parameter is provided
+	DefaultResumeStatsPeriod = 30 * 24 * time.Hour
+	// TopResumeReferrers is the number of referrers
+	// included in resume statistics
+	TopResumeReferrers = 10
+)
+
+// ResumeStatsHandler returns statistics about downloads of the resume:
+// the number of downloads of each format per day, the number of unique
+// downloaders and the top referrers. The since and until query
+// parameters select the period, defaulting to the last 30 days.
+func ResumeStatsHandler(c *gin.Context, db Persistence) RESTResponse {
+	query := ResumeStatsQuery{Until: time.Now(), Referrers: TopResumeReferrers}
+	if until := c.Query("until"); until != "" {
+		ts, err := parseListTime(until)
+		if err != nil {
+			log.Error(fmt.Sprintf("invalid until %q", until))
+			return BadRequestResponse
+		}
+		query.Until = *ts
+	}
+	query.Since = query.Until.Add(-DefaultResumeStatsPeriod)
+	if since := c.Query("since"); since != "" {
+		ts, err := parseListTime(since)
+		if err != nil {
+			log.Error(fmt.Sprintf("invalid since %q", since))
+			return BadRequestResponse
+		}
+		query.Since = *ts
+	}
+	if !query.Since.Before(query.Until) {
+		log.Error(fmt.Sprintf("since %s is not before until %s", query.Since, query.Until))
+		return BadRequestResponse
+	}
+
+	stats, err := db.GetResumeStats(RequestContext(c), query)
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to get resume stats: %v", err))
+		return PersistenceErrorResponse(err)
 	}
 
 	response := RESTResponse{
@@ -190,32 +498,640 @@ func StatsHandler(c *gin.Context, db Persistence) RESTResponse {
 	return response
 }
 
-// ListContactsHandler returns a list of all contacts in the system.
+const (
+	// DefaultPageSize is the number of items returned
+	// by list endpoints if no limit is provided
+	DefaultPageSize = 50
+	// MaxPageSize is the maximum number of items
+	// that can be requested from list endpoints
+	MaxPageSize = 500
+)
+
+// parseListTime parses a since/until query parameter. Both RFC3339
+// timestamps and plain dates (interpreted as midnight UTC) are accepted.
+func parseListTime(value string) (*time.Time, error) {
+	ts, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		ts, err = time.Parse(time.DateOnly, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ts = ts.UTC()
+	return &ts, nil
+}
+
+// ParseListQuery parses the pagination, sorting and filtering query
+// parameters supported by list endpoints:
+//
+//   - limit: number of items to return (1-500, default 50)
+//   - cursor: next_cursor value returned with the previous page
+//   - sort: created_at (oldest first, default) or -created_at (newest first)
+//   - email: only return items for the given email
+//   - status: only return contact requests with the given triage status,
+//     rejected by endpoints that do not list contact requests
+//   - since/until: only return items created in [since, until)
+func ParseListQuery(c *gin.Context) (ListQuery, error) {
+	query := ListQuery{Limit: DefaultPageSize}
+	if c == nil || c.Request == nil {
+		return query, nil
+	}
+
+	if limitString := c.Query("limit"); limitString != "" {
+		limit, err := strconv.Atoi(limitString)
+		if err != nil || limit < 1 || limit > MaxPageSize {
+			return query, fmt.Errorf("invalid limit %q", limitString)
+		}
+		query.Limit = limit
+	}
+
+	if cursorString := c.Query("cursor"); cursorString != "" {
+		cursor, err := DecodeListCursor(cursorString)
+		if err != nil {
+			return query, err
+		}
+		query.Cursor = cursor
+	}
+
+	switch sort := c.Query("sort"); sort {
+	case "", "created_at":
+		query.Descending = false
+	case "-created_at":
+		query.Descending = true
+	default:
+		return query, fmt.Errorf("invalid sort %q", sort)
+	}
+
+	// emails are stored in lowercase
+	query.Email = strings.ToLower(c.Query("email"))
+
+	if status := ContactRequestStatus(c.Query("status")); status != "" {
+		if !status.Valid() {
+			return query, fmt.Errorf("invalid status %q", status)
+		}
+		query.Status = status
+	}
+
+	if since := c.Query("since"); since != "" {
+		ts, err := parseListTime(since)
+		if err != nil {
+			return query, fmt.Errorf("invalid since %q", since)
+		}
+		query.Since = ts
+	}
+
+	if until := c.Query("until"); until != "" {
+		ts, err := parseListTime(until)
+		if err != nil {
+			return query, fmt.Errorf("invalid until %q", until)
+		}
+		query.Until = ts
+	}
+	return query, nil
+}
+
+// listPayload builds the payload returned by list endpoints. next_cursor
+// is null once the last page has been reached.
+func listPayload(data any, next *ListCursor) gin.H {
+	payload := gin.H{
+		"data":        data,
+		"next_cursor": nil,
+	}
+	if next != nil {
+		payload["next_cursor"] = EncodeListCursor(next)
+	}
+	return payload
+}
+
+// ListContactsHandler returns a page of contacts in the system.
+// See ParseListQuery for supported query parameters.
 func ListContactsHandler(c *gin.Context, db Persistence) RESTResponse {
-	contacts, err := db.ListContacts()
+	query, err := ParseListQuery(c)
+	if err != nil {
+		log.Error(fmt.Sprintf("invalid list contacts query: %v", err))
+		return BadRequestResponse
+	}
+	// only contact requests have a triage status
+	if query.Status != "" {
+		log.Error("status filter is not supported for contacts")
+		return BadRequestResponse
+	}
+
+	contacts, next, err := db.QueryContacts(RequestContext(c), query)
 	if err != nil {
 		log.Error(fmt.Sprintf("failed to list contacts: %v", err))
-		return InternalServerErrorResponse
+		return PersistenceErrorResponse(err)
 	}
 
 	response := RESTResponse{
 		Code:    200,
-		Payload: gin.H{"data": contacts},
+		Payload: listPayload(contacts, next),
 	}
 	return response
 }
 
-// ListContactRequestsHandler returns a list of all contact requests in the system.
+// ListContactRequestsHandler returns a page of contact requests in the system.
+// See ParseListQuery for supported query parameters.
 func ListContactRequestsHandler(c *gin.Context, db Persistence) RESTResponse {
-	requests, err := db.ListContactRequests()
+	query, err := ParseListQuery(c)
+	if err != nil {
+		log.Error(fmt.Sprintf("invalid list contact requests query: %v", err))
+		return BadRequestResponse
+	}
+
+	requests, next, err := db.QueryContactRequests(RequestContext(c), query)
 	if err != nil {
 		log.Error(fmt.Sprintf("failed to list contact requests: %v", err))
+		return PersistenceErrorResponse(err)
+	}
+
+	response := RESTResponse{
+		Code:    200,
+		Payload: listPayload(requests, next),
+	}
+	return response
+}
+
+type UpdateContactRequestBody struct {
+	Status *ContactRequestStatus `json:"status" binding:"omitempty,oneof=new read replied archived spam"`
+	Notes  *string               `json:"notes"`
+}
+
+// UpdateContactRequestHandler moves a contact request to a new triage
+// status and/or replaces its internal notes. Transitions that are not
+// allowed from the current status return a 409.
+func UpdateContactRequestHandler(c *gin.Context, db Persistence) RESTResponse {
+	id := c.Param("id")
+
+	var body UpdateContactRequestBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.Error(fmt.Sprintf("invalid update contact request payload: %v", err))
+		return BadRequestResponse
+	}
+	if body.Status == nil && body.Notes == nil {
+		log.Error("update contact request payload contains no fields")
+		return BadRequestResponse
+	}
+
+	update := ContactRequestUpdate{Status: body.Status, Notes: body.Notes}
+	request, err := db.UpdateContactRequest(RequestContext(c), id, update)
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to update contact request %s: %v", id, err))
+		return PersistenceErrorResponse(err)
+	}
+	log.Info(fmt.Sprintf("updated contact request with id: %s", id))
+
+	response := RESTResponse{
+		Code:    200,
+		Payload: gin.H{"data": request},
+	}
+	return response
+}
+
+// GetContactHandler returns a single contact by ID.
+func GetContactHandler(c *gin.Context, db Persistence) RESTResponse {
+	id := c.Param("id")
+
+	contact, err := db.GetContactById(RequestContext(c), id)
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to get contact %s: %v", id, err))
+		return PersistenceErrorResponse(err)
+	}
+
+	response := RESTResponse{
+		Code:    200,
+		Payload: gin.H{"data": contact},
+	}
+	return response
+}
+
+type UpdateContactBody struct {
+	Name  *string `json:"name" binding:"omitempty,min=1"`
+	Email *string `json:"email" binding:"omitempty,email"`
+}
+
+// UpdateContactHandler updates the name and/or email of a contact.
+// Fields not present in the request body are left unchanged.
+func UpdateContactHandler(c *gin.Context, db Persistence) RESTResponse {
+	id := c.Param("id")
+
+	var body UpdateContactBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.Error(fmt.Sprintf("invalid update contact payload: %v", err))
+		return BadRequestResponse
+	}
+	if body.Name == nil && body.Email == nil {
+		log.Error("update contact payload contains no fields")
+		return BadRequestResponse
+	}
+
+	update := ContactUpdate{Name: body.Name}
+	if body.Email != nil {
+		// Normalize email to lowercase
+		email := strings.ToLower(*body.Email)
+		update.Email = &email
+	}
+
+	contact, err := db.UpdateContact(RequestContext(c), id, update)
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to update contact %s: %v", id, err))
+		return PersistenceErrorResponse(err)
+	}
+	log.Info(fmt.Sprintf("updated contact with id: %s", id))
+
+	response := RESTResponse{
+		Code:    200,
+		Payload: gin.H{"data": contact},
+	}
+	return response
+}
+
+// DeleteContactHandler deletes a contact along with all of its contact requests.
+func DeleteContactHandler(c *gin.Context, db Persistence) RESTResponse {
+	id := c.Param("id")
+
+	if err := db.DeleteContact(RequestContext(c), id); err != nil {
+		log.Error(fmt.Sprintf("failed to delete contact %s: %v", id, err))
+		return PersistenceErrorResponse(err)
+	}
+	log.Info(fmt.Sprintf("deleted contact with id: %s", id))
+
+	return RESTResponse{Code: 204}
+}
+
+// ListContactHistoryHandler returns a page of contact requests submitted
+// by a single contact. See ParseListQuery for supported query parameters.
+func ListContactHistoryHandler(c *gin.Context, db Persistence) RESTResponse {
+	id := c.Param("id")
+
+	query, err := ParseListQuery(c)
+	if err != nil {
+		log.Error(fmt.Sprintf("invalid list contact history query: %v", err))
+		return BadRequestResponse
+	}
+	query.ContactId = id
+
+	ctx := RequestContext(c)
+	// return a 404 rather than an empty
+	// list if the contact does not exist
+	if _, err := db.GetContactById(ctx, id); err != nil {
+		log.Error(fmt.Sprintf("failed to get contact %s: %v", id, err))
+		return PersistenceErrorResponse(err)
+	}
+
+	requests, next, err := db.QueryContactRequests(ctx, query)
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to list contact requests for %s: %v", id, err))
+		return PersistenceErrorResponse(err)
+	}
+
+	response := RESTResponse{
+		Code:    200,
+		Payload: listPayload(requests, next),
+	}
+	return response
+}
+
+// ListWebhooksHandler returns the configured webhook subscriptions.
+// Subscription secrets are never returned.
+func ListWebhooksHandler(c *gin.Context, events EventPublisher) RESTResponse {
+	subscriptions := []WebhookSubscription{}
+	for _, subscription := range events.Subscriptions() {
+		subscription.Secret = ""
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	response := RESTResponse{
+		Code:    200,
+		Payload: gin.H{"data": subscriptions},
+	}
+	return response
+}
+
+// ListWebhookDeliveriesHandler returns a page of the webhook delivery log.
+// See ParseListQuery for supported query parameters.
+func ListWebhookDeliveriesHandler(c *gin.Context, db Persistence) RESTResponse {
+	query, err := ParseListQuery(c)
+	if err != nil {
+		log.Error(fmt.Sprintf("invalid list webhook deliveries query: %v", err))
+		return BadRequestResponse
+	}
+	if query.Status != "" {
+		log.Error("status filter is not supported for webhook deliveries")
+		return BadRequestResponse
+	}
+
+	deliveries, next, err := db.QueryWebhookDeliveries(RequestContext(c), query)
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to list webhook deliveries: %v", err))
+		return PersistenceErrorResponse(err)
+	}
+
+	response := RESTResponse{
+		Code:    200,
+		Payload: listPayload(deliveries, next),
+	}
+	return response
+}
+
+// reloadResume reloads the resume after a version has been uploaded or
+// activated. The version is stored even if the reload fails, and is
+// served once the resume is next reloaded.
+func reloadResume(c *gin.Context, store *ResumeStore) {
+	if err := store.Reload(RequestContext(c)); err != nil {
+		log.Error(fmt.Sprintf("failed to reload resume: %v", err))
+	}
+}
+
+// UploadResumeVersionHandler stores a JSON or PDF resume uploaded by an
+// admin as a new version, and makes it the active version of its variant
+// and locale. The format is taken from the Content-Type header, and the
+// variant and locale from the query parameters, defaulting to the default
+// variant and locale. JSON resumes are validated, and PDFs can only be
+// uploaded for a variant and locale that has a JSON resume.
+func UploadResumeVersionHandler(c *gin.Context, db Persistence, store *ResumeStore, maxSize int64) RESTResponse {
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	var format ResumeFileFormat
+	for _, uploadable := range []ResumeFileFormat{ResumeFormatJSON, ResumeFormatPDF} {
+		if uploadable.MediaType() == mediaType {
+			format = uploadable
+		}
+	}
+	if format == "" {
+		log.Error(fmt.Sprintf("unsupported resume content type %q", mediaType))
+		return UnsupportedMediaTypeResponse
+	}
+
+	variant := cmp.Or(c.Query("variant"), store.DefaultVariant)
+	locale := cmp.Or(c.Query("locale"), store.DefaultLocale)
+	if !resumeVariantNamePattern.MatchString(variant) || !resumeLocalePattern.MatchString(locale) {
+		log.Error(fmt.Sprintf("invalid resume variant %q or locale %q", variant, locale))
+		return BadRequestResponse
+	}
+
+	content, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxSize))
+	if err != nil {
+		var errTooLarge *http.MaxBytesError
+		if errors.As(err, &errTooLarge) {
+			log.Error(fmt.Sprintf("uploaded resume exceeds %d bytes", maxSize))
+			return ContentTooLargeResponse
+		}
+		log.Error(fmt.Sprintf("failed to read uploaded resume: %v", err))
+		return BadRequestResponse
+	}
+
+	switch format {
+	case ResumeFormatJSON:
+		if _, err := ParseResume(content); err != nil {
+			log.Error(fmt.Sprintf("invalid uploaded resume: %v", err))
+			var errValidation ResumeValidationError
+			if errors.As(err, &errValidation) {
+				return RESTResponse{
+					Code: 400,
+					Payload: gin.H{
+						"error":  BadRequestPayload["error"],
+						"fields": errValidation.Fields,
+					},
+				}
+			}
+			return BadRequestResponse
+		}
+	case ResumeFormatPDF:
+		if !bytes.HasPrefix(content, []byte("%PDF-")) {
+			log.Error("uploaded resume is not a PDF")
+			return BadRequestResponse
+		}
+		if _, ok := store.Lookup(variant, locale); !ok {
+			log.Error(fmt.Sprintf("no JSON resume for variant %s and locale %s", variant, locale))
+			return BadRequestResponse
+		}
+	}
+
+	sum := sha256.Sum256(content)
+	version, err := db.CreateResumeVersion(RequestContext(c), ResumeVersion{
+		Variant: variant,
+		Locale:  locale,
+		Format:  format,
+		Content: content,
+		Hash:    hex.EncodeToString(sum[:]),
+		Author:  c.GetString(apiKeyOwnerKey),
+	})
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to create resume version: %v", err))
+		return PersistenceErrorResponse(err)
+	}
+	log.Info(fmt.Sprintf("created resume version with id: %s", version.Id))
+	reloadResume(c, store)
+
+	response := RESTResponse{
+		Code:    201,
+		Payload: gin.H{"data": version},
+	}
+	return response
+}
+
+// ListResumeVersionsHandler lists all versions of the resume uploaded
+// by admins, most recent first, without their content.
+func ListResumeVersionsHandler(c *gin.Context, db Persistence) RESTResponse {
+	versions, err := db.ListResumeVersions(RequestContext(c))
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to list resume versions: %v", err))
+		return PersistenceErrorResponse(err)
+	}
+
+	response := RESTResponse{
+		Code:    200,
+		Payload: gin.H{"data": versions},
+	}
+	return response
+}
+
+// DiffResumeVersionsHandler returns the changes between the JSON resume
+// version given by the base query parameter and the version with the
+// given ID.
+func DiffResumeVersionsHandler(c *gin.Context, db Persistence) RESTResponse {
+	id, baseId := c.Param("id"), c.Query("base")
+	if baseId == "" {
+		log.Error("missing base resume version")
+		return BadRequestResponse
+	}
+
+	var versions [2]*ResumeVersion
+	for i, versionId := range []string{baseId, id} {
+		version, err := db.GetResumeVersion(RequestContext(c), versionId)
+		if err != nil {
+			log.Error(fmt.Sprintf("failed to get resume version %s: %v", versionId, err))
+			return PersistenceErrorResponse(err)
+		}
+		if version.Format != ResumeFormatJSON {
+			log.Error(fmt.Sprintf("resume version %s is not a JSON resume", versionId))
+			return BadRequestResponse
+		}
+		versions[i] = version
+	}
+
+	changes, err := DiffResumes(versions[0].Content, versions[1].Content)
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to diff resume versions: %v", err))
 		return InternalServerErrorResponse
 	}
 
 	response := RESTResponse{
 		Code:    200,
-		Payload: gin.H{"data": requests},
+		Payload: gin.H{"data": changes},
 	}
 	return response
 }
+
+// ActivateResumeVersionHandler makes the version with the given ID the
+// active version of its variant and locale, e.g. to roll back a change.
+func ActivateResumeVersionHandler(c *gin.Context, db Persistence, store *ResumeStore) RESTResponse {
+	id := c.Param("id")
+
+	version, err := db.ActivateResumeVersion(RequestContext(c), id)
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to activate resume version %s: %v", id, err))
+		return PersistenceErrorResponse(err)
+	}
+	log.Info(fmt.Sprintf("activated resume version with id: %s", id))
+	reloadResume(c, store)
+
+	response := RESTResponse{
+		Code:    200,
+		Payload: gin.H{"data": version},
+	}
+	return response
+}
+
+type CreateResumeShareBody struct {
+	Variant   string           `json:"variant"`
+	Locale    string           `json:"locale"`
+	Format    ResumeFileFormat `json:"format"`
+	Recipient string           `json:"recipient" binding:"max=256"`
+	// ExpiresIn is the lifetime of the link in seconds
+	ExpiresIn int  `json:"expires_in" binding:"omitempty,min=60"`
+	SingleUse bool `json:"single_use"`
+}
+
+// CreateResumeShareHandler creates a signed link to a variant of the
+// resume in a given format, e.g. to send a PDF to a recruiter. The
+// variant defaults to the default variant, the locale to the first
+// locale of the variant and the format to PDF. Links expire after the
+// configured TTL unless a shorter or longer expiry, up to the configured
+// maximum, is requested, and single use links can only be opened once.
+func CreateResumeShareHandler(c *gin.Context, db Persistence, store *ResumeStore, sharer *ResumeSharer) RESTResponse {
+	var body CreateResumeShareBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		log.Error(fmt.Sprintf("invalid create resume share payload: %v", err))
+		return BadRequestResponse
+	}
+
+	format := cmp.Or(ResumeFileFormat(strings.ToLower(string(body.Format))), ResumeFormatPDF)
+	if !slices.Contains(resumeFormats, format) {
+		log.Error(fmt.Sprintf("invalid resume share format: %s", format))
+		return BadRequestResponse
+	}
+
+	ttl := sharer.TTL
+	if body.ExpiresIn > 0 {
+		ttl = time.Duration(body.ExpiresIn) * time.Second
+	}
+	if ttl > sharer.MaxTTL {
+		log.Error(fmt.Sprintf("resume share expiry %s exceeds %s", ttl, sharer.MaxTTL))
+		return BadRequestResponse
+	}
+
+	// links are bound to a locale, so that they keep serving
+	// the same language regardless of the recipient's browser
+	snapshot, err := store.Select(body.Variant, "")
+	if err == nil && body.Locale != "" {
+		var ok bool
+		if snapshot, ok = store.Lookup(snapshot.Variant, body.Locale); !ok {
+			err = fmt.Errorf("no resume for locale %s", body.Locale)
+		}
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("invalid resume share variant %q: %v", body.Variant, err))
+		return BadRequestResponse
+	}
+
+	share, err := db.CreateResumeShare(RequestContext(c), ResumeShare{
+		Variant:   snapshot.Variant,
+		Locale:    snapshot.Locale,
+		Format:    format,
+		Recipient: body.Recipient,
+		SingleUse: body.SingleUse,
+		CreatedBy: c.GetString(apiKeyOwnerKey),
+		// expiries are signed with second precision
+		ExpiresAt: time.Now().Add(ttl).Truncate(time.Second).UTC(),
+	})
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to create resume share: %v", err))
+		return PersistenceErrorResponse(err)
+	}
+	log.Info(fmt.Sprintf("created resume share with id: %s", share.Id))
+	share.Token = sharer.Token(*share)
+
+	response := RESTResponse{
+		Code:    201,
+		Payload: gin.H{"data": share},
+	}
+	return response
+}
+
+// ListResumeSharesHandler lists all links to the resume, most recent
+// first, along with how often they were opened and their tokens.
+func ListResumeSharesHandler(c *gin.Context, db Persistence, sharer *ResumeSharer) RESTResponse {
+	shares, err := db.ListResumeShares(RequestContext(c))
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to list resume shares: %v", err))
+		return PersistenceErrorResponse(err)
+	}
+	for i := range shares {
+		shares[i].Token = sharer.Token(shares[i])
+	}
+
+	response := RESTResponse{
+		Code:    200,
+		Payload: gin.H{"data": shares},
+	}
+	return response
+}
+
+// SharedResumeHandler serves the resume bound to a signed link. Invalid
+// tokens return a 404, and expired links and single use links that were
+// already opened return a 410. Responses are never cached, so that every
+// open is recorded by the ResumeShareMiddleware.
+func SharedResumeHandler(c *gin.Context, db Persistence, store *ResumeStore, sharer *ResumeSharer, renderer *ResumeRenderer) RESTResponse {
+	c.Header("Cache-Control", "private, no-store")
+
+	id, err := sharer.Verify(c.Param("token"), time.Now())
+	if err != nil {
+		log.Error(fmt.Sprintf("rejected resume share token: %v", err))
+		var errToken InvalidResumeShareTokenError
+		if errors.As(err, &errToken) && errToken.Expired {
+			return GoneResponse
+		}
+		return NotFoundResponse
+	}
+
+	share, err := db.GetResumeShare(RequestContext(c), id)
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to retrieve resume share %s: %v", id, err))
+		return PersistenceErrorResponse(err)
+	}
+	if share.SingleUse && share.Opens > 0 {
+		log.Error(fmt.Sprintf("resume share %s has already been used", id))
+		return GoneResponse
+	}
+
+	snapshot, ok := store.Lookup(share.Variant, share.Locale)
+	if !ok {
+		log.Error(fmt.Sprintf("no resume for variant %s and locale %s", share.Variant, share.Locale))
+		return NotFoundResponse
+	}
+	c.Header("Content-Language", snapshot.Locale)
+	c.Set(resumeShareKey, share.Id)
+	c.Set(resumeDownloadKey, ResumeDownload{Format: share.Format, Variant: snapshot.Variant, Locale: snapshot.Locale})
+	return serveResume(snapshot, share.Format, ResumeQuery{}, true, renderer)
+}
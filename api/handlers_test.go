@@ -2,9 +2,18 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"net/http"
 	"net/http/httptest"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -32,6 +41,44 @@ func TestHealthCheckHandler(t *testing.T) {
 			t.Errorf("Expected status code 500, got %d", response.Code)
 		}
 	})
+
+	t.Run("Timed Out Database", func(t *testing.T) {
+		persistence := &TestPersistence{
+			Healthy: true,
+		}
+
+		timeout, cancel := context.WithTimeout(context.Background(), 0)
+		defer cancel()
+
+		writer := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(writer)
+		ctx.Request = httptest.NewRequest(
+			"GET", "/api/health", nil).WithContext(timeout)
+
+		response := HealthCheckHandler(ctx, persistence)
+		if response.Code != 504 {
+			t.Errorf("Expected status code 504, got %d", response.Code)
+		}
+	})
+
+	t.Run("Cancelled Request", func(t *testing.T) {
+		persistence := &TestPersistence{
+			Healthy: true,
+		}
+
+		cancelled, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		writer := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(writer)
+		ctx.Request = httptest.NewRequest(
+			"GET", "/api/health", nil).WithContext(cancelled)
+
+		response := HealthCheckHandler(ctx, persistence)
+		if response.Code != 503 {
+			t.Errorf("Expected status code 503, got %d", response.Code)
+		}
+	})
 }
 
 func TestVersionHandler(t *testing.T) {
@@ -72,7 +119,7 @@ func TestResumeHandler(t *testing.T) {
 		ctx.Request = httptest.NewRequest(
 			"GET", "/api/resume?format=pdf", nil)
 
-		response := ResumeHandler(ctx, config)
+		response := ResumeHandler(ctx, newTestResumeStore(t, config), newTestResumeRenderer(t))
 		if response.Code != 200 {
 			t.Errorf("Expected status code 200, got %d", response.Code)
 		}
@@ -99,7 +146,7 @@ func TestResumeHandler(t *testing.T) {
 		ctx.Request = httptest.NewRequest(
 			"GET", "/api/resume?format=json", nil)
 
-		response := ResumeHandler(ctx, config)
+		response := ResumeHandler(ctx, newTestResumeStore(t, config), newTestResumeRenderer(t))
 		if response.Code != 200 {
 			t.Errorf("Expected status code 200, got %d", response.Code)
 		}
@@ -114,8 +161,8 @@ func TestResumeHandler(t *testing.T) {
 			t.Fatalf("Expected 'data' key in payload")
 		}
 
-		if _, ok := data.(map[string]any); !ok {
-			t.Errorf("Expected data to be a JSON object")
+		if _, ok := data.(*Resume); !ok {
+			t.Errorf("Expected data to be a resume")
 		}
 	})
 
@@ -126,7 +173,7 @@ func TestResumeHandler(t *testing.T) {
 		ctx.Request = httptest.NewRequest(
 			"GET", "/api/resume?format=xml", nil)
 
-		response := ResumeHandler(ctx, config)
+		response := ResumeHandler(ctx, newTestResumeStore(t, config), newTestResumeRenderer(t))
 		if response.Code != 400 {
 			t.Errorf("Expected status code 400, got %d", response.Code)
 		}
@@ -139,7 +186,7 @@ func TestResumeHandler(t *testing.T) {
 		ctx.Request = httptest.NewRequest(
 			"GET", "/api/resume", nil)
 
-		response := ResumeHandler(ctx, config)
+		response := ResumeHandler(ctx, newTestResumeStore(t, config), newTestResumeRenderer(t))
 		if response.Code != 200 {
 			t.Errorf("Expected status code 200, got %d", response.Code)
 		}
@@ -154,175 +201,1490 @@ func TestResumeHandler(t *testing.T) {
 			t.Fatalf("Expected 'data' key in payload")
 		}
 
-		if _, ok := data.(map[string]any); !ok {
-			t.Errorf("Expected data to be a JSON object")
+		if _, ok := data.(*Resume); !ok {
+			t.Errorf("Expected data to be a resume")
 		}
 	})
 }
 
-func TestStatsHandler(t *testing.T) {
-	persistence := &TestPersistence{}
+func TestResumeHandlerNegotiation(t *testing.T) {
+	config := &Config{
+		ResumePathPDF:  "etc/resume.pdf",
+		ResumePathJSON: "etc/resume.json",
+	}
 
-	response := StatsHandler(nil, persistence)
-	if response.Code != 200 {
-		t.Errorf("Expected status code 200, got %d", response.Code)
+	handle := func(path string, accept string) (*gin.Context, RESTResponse) {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest("GET", path, nil)
+		ctx.Request.Header.Set("Accept", accept)
+		return ctx, ResumeHandler(ctx, newTestResumeStore(t, config), newTestResumeRenderer(t))
 	}
+
+	t.Run("Quality Values", func(t *testing.T) {
+		_, response := handle("/api/resume", "application/json;q=0.5, application/pdf;q=0.9")
+		if response.File == nil || response.File.ContentType != "application/pdf" {
+			t.Errorf("Expected PDF file response, got %+v", response)
+		}
+
+		ctx, response := handle("/api/resume", "application/pdf;q=0.1, application/json")
+		if response.Code != 200 || response.File != nil {
+			t.Errorf("Expected JSON response, got %+v", response)
+		}
+
+		if ctx.Writer.Header().Get("Vary") != "Accept, Accept-Language" {
+			t.Errorf("Expected Vary header, got %v", ctx.Writer.Header())
+		}
+	})
+
+	t.Run("Accept Header Takes Precedence", func(t *testing.T) {
+		_, response := handle("/api/resume?format=json", "application/pdf")
+		if response.File == nil || response.File.ContentType != "application/pdf" {
+			t.Errorf("Expected PDF file response, got %+v", response)
+		}
+	})
+
+	t.Run("Query Parameter Fallback", func(t *testing.T) {
+		// headers without a media type or without a supported
+		// media type fall back to the query parameter
+		for _, accept := range []string{"", "*/*", "image/png"} {
+			_, response := handle("/api/resume?format=markdown", accept)
+			if response.Code != 200 || response.ContentType != "text/markdown; charset=utf-8" {
+				t.Errorf("Expected markdown resume for Accept %q, got %d %s", accept, response.Code, response.ContentType)
+			}
+		}
+
+		if _, response := handle("/api/resume?format=docx", "*/*"); response.Code != 400 {
+			t.Errorf("Expected status code 400, got %d", response.Code)
+		}
+	})
+
+	t.Run("Text Formats", func(t *testing.T) {
+		tests := map[string]string{
+			"text/html,application/xhtml+xml,*/*;q=0.8": "text/html; charset=utf-8",
+			"text/markdown": "text/markdown; charset=utf-8",
+			"text/plain":    "text/plain; charset=utf-8",
+		}
+		for accept, expected := range tests {
+			_, response := handle("/api/resume", accept)
+			if response.Code != 200 || response.ContentType != expected || len(response.Body) == 0 {
+				t.Errorf("Expected %s resume for Accept %s, got %d %s", expected, accept, response.Code, response.ContentType)
+			}
+		}
+
+		_, response := handle("/api/resume?format=markdown", "")
+		if response.ContentType != "text/markdown; charset=utf-8" || !bytes.HasPrefix(response.Body, []byte("# Pascal Sauerborn")) {
+			t.Errorf("Expected markdown resume, got %s %s", response.ContentType, response.Body)
+		}
+	})
+
+	t.Run("Not Acceptable", func(t *testing.T) {
+		_, response := handle("/api/resume", "image/png, application/json;q=0")
+		if response.Code != 406 {
+			t.Fatalf("Expected status code 406, got %d", response.Code)
+		}
+
+		supported, _ := response.Payload.(gin.H)["supported"].([]string)
+		if len(supported) != len(resumeFormats) || supported[0] != "application/json" {
+			t.Errorf("Expected supported media types, got %+v", response.Payload)
+		}
+	})
 }
 
-func TestListContactsHandler(t *testing.T) {
+func TestResumeHandlerDownload(t *testing.T) {
+	config := &Config{
+		ResumePathPDF:  "etc/resume.pdf",
+		ResumePathJSON: "etc/resume.json",
+	}
+	contents, err := os.ReadFile(config.ResumePathPDF)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
 
-	t.Run("No Contacts", func(t *testing.T) {
-		persistence := &TestPersistence{
-			Contacts: make(map[string]Contact),
+	send := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		writer := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(writer)
+		ctx.Request = httptest.NewRequest("GET", path, nil)
+		for key, value := range headers {
+			ctx.Request.Header.Set(key, value)
 		}
 
-		response := ListContactsHandler(nil, persistence)
-		if response.Code != 200 {
-			t.Errorf("Expected status code 200, got %d", response.Code)
+		ResumeHandler(ctx, newTestResumeStore(t, config), newTestResumeRenderer(t)).Send(ctx)
+		// gin writes headers once all handlers have run
+		ctx.Writer.WriteHeaderNow()
+		return writer
+	}
+
+	t.Run("Download Query Parameter", func(t *testing.T) {
+		writer := send("/api/resume?format=pdf&download=true", nil)
+		if writer.Code != 200 {
+			t.Fatalf("Expected status code 200, got %d", writer.Code)
 		}
 
-		payload, ok := response.Payload.(gin.H)
-		if !ok {
-			t.Fatalf("Expected payload to be of type gin.H")
+		if !bytes.Equal(writer.Body.Bytes(), contents) {
+			t.Errorf("Expected PDF contents, got %d bytes", writer.Body.Len())
 		}
 
-		contacts, exists := payload["data"].([]Contact)
-		if !exists {
-			t.Fatalf("Expected 'data' key in payload")
+		headers := writer.Header()
+		if headers.Get("Content-Type") != "application/pdf" || headers.Get("Content-Length") != strconv.Itoa(len(contents)) {
+			t.Errorf("Expected PDF content type and length, got %v", headers)
 		}
 
-		if len(contacts) != 0 {
-			t.Errorf("Expected 0 contacts, got %d", len(contacts))
+		if headers.Get("Content-Disposition") != `attachment; filename=resume.pdf` || headers.Get("ETag") == "" {
+			t.Errorf("Expected attachment with ETag, got %v", headers)
 		}
 	})
 
-	t.Run("With Contacts", func(t *testing.T) {
-		persistence := &TestPersistence{
-			Contacts: map[string]Contact{
-				"1": {Id: "1", Name: "John Doe", Email: "john@example.com"},
-				"2": {Id: "2", Name: "Jane Doe", Email: "jane@example.com"},
-				"3": {Id: "3", Name: "Jim Beam", Email: "jim@example.com"},
-			},
+	t.Run("Accept Header", func(t *testing.T) {
+		writer := send("/api/resume", map[string]string{"Accept": "application/pdf"})
+		if writer.Code != 200 || writer.Header().Get("Content-Type") != "application/pdf" {
+			t.Errorf("Expected PDF to be streamed, got %d %v", writer.Code, writer.Header())
 		}
 
-		response := ListContactsHandler(nil, persistence)
-		if response.Code != 200 {
-			t.Errorf("Expected status code 200, got %d", response.Code)
+		// the Accept header takes precedence over the format
+		writer = send("/api/resume?format=json", map[string]string{"Accept": "application/pdf"})
+		if writer.Header().Get("Content-Type") != "application/pdf" {
+			t.Errorf("Expected PDF to be streamed, got %v", writer.Header())
+		}
+		writer = send("/api/resume?format=pdf&download=true", map[string]string{"Accept": "application/json"})
+		if !strings.HasPrefix(writer.Header().Get("Content-Type"), "application/json") {
+			t.Errorf("Expected JSON resume, got %v", writer.Header())
 		}
+	})
 
-		payload, ok := response.Payload.(gin.H)
-		if !ok {
-			t.Fatalf("Expected payload to be of type gin.H")
+	t.Run("Range Request", func(t *testing.T) {
+		writer := send("/api/resume?format=pdf&download=true", map[string]string{"Range": "bytes=0-9"})
+		if writer.Code != 206 {
+			t.Fatalf("Expected status code 206, got %d", writer.Code)
 		}
 
-		contacts, exists := payload["data"].([]Contact)
-		if !exists {
-			t.Fatalf("Expected 'data' key in payload")
+		if !bytes.Equal(writer.Body.Bytes(), contents[:10]) {
+			t.Errorf("Expected first 10 bytes, got %q", writer.Body.Bytes())
 		}
 
-		if len(contacts) != 3 {
-			t.Errorf("Expected 3 contacts, got %d", len(contacts))
+		expected := fmt.Sprintf("bytes 0-9/%d", len(contents))
+		if writer.Header().Get("Content-Range") != expected {
+			t.Errorf("Expected Content-Range %s, got %s", expected, writer.Header().Get("Content-Range"))
+		}
+	})
+
+	t.Run("If-None-Match", func(t *testing.T) {
+		etag := send("/api/resume?format=pdf&download=true", nil).Header().Get("ETag")
+
+		writer := send("/api/resume?format=pdf&download=true", map[string]string{"If-None-Match": etag})
+		if writer.Code != 304 || writer.Body.Len() != 0 {
+			t.Errorf("Expected status code 304 without body, got %d", writer.Code)
+		}
+	})
+
+	t.Run("Generated PDF", func(t *testing.T) {
+		store := newTestResumeStore(t, &Config{ResumePathJSON: "etc/resume.json"})
+		renderer := newTestResumeRenderer(t)
+		send := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+			writer := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(writer)
+			ctx.Request = httptest.NewRequest("GET", path, nil)
+			for key, value := range headers {
+				ctx.Request.Header.Set(key, value)
+			}
+
+			ResumeHandler(ctx, store, renderer).Send(ctx)
+			ctx.Writer.WriteHeaderNow()
+			return writer
+		}
+
+		writer := send("/api/resume?format=pdf&download=true", nil)
+		if writer.Code != 200 || !bytes.HasPrefix(writer.Body.Bytes(), []byte("%PDF-")) {
+			t.Fatalf("Expected generated PDF, got %d", writer.Code)
+		}
+		if writer.Header().Get("Content-Disposition") != `attachment; filename=resume.pdf` {
+			t.Errorf("Expected attachment, got %v", writer.Header())
+		}
+
+		etag := writer.Header().Get("ETag")
+		if writer := send("/api/resume?format=pdf&download=true", map[string]string{"If-None-Match": etag}); writer.Code != 304 {
+			t.Errorf("Expected status code 304, got %d", writer.Code)
+		}
+
+		// the base64 encoded PDF matches the streamed PDF
+		var body struct {
+			Data string `json:"data"`
+		}
+		writer = send("/api/resume?format=pdf", nil)
+		if err := json.Unmarshal(writer.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Expected JSON response, got %v", err)
+		}
+		if body.Data != base64.StdEncoding.EncodeToString(send("/api/resume?format=pdf&download=true", nil).Body.Bytes()) {
+			t.Errorf("Expected base64 encoded generated PDF")
 		}
 	})
+
 }
 
-func TestListContactRequestsHandler(t *testing.T) {
-	persistence := &TestPersistence{
-		ContactRequests: map[string][]ContactRequest{
-			"1": {
-				{Id: "req1", ContactId: "1", Message: "Hello"},
-				{Id: "req2", ContactId: "1", Message: "Need help"},
-			},
-			"2": {
-				{Id: "req3", ContactId: "2", Message: "Inquiry"},
-			},
-		},
+func TestResumeHandlerConditional(t *testing.T) {
+	path := copyResume(t)
+	store := newTestResumeStore(t, &Config{ResumePathJSON: path})
+	renderer := newTestResumeRenderer(t)
+
+	send := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		writer := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(writer)
+		ctx.Request = httptest.NewRequest("GET", path, nil)
+		for key, value := range headers {
+			ctx.Request.Header.Set(key, value)
+		}
+
+		ResumeHandler(ctx, store, renderer).Send(ctx)
+		ctx.Writer.WriteHeaderNow()
+		return writer
 	}
 
-	response := ListContactRequestsHandler(nil, persistence)
-	if response.Code != 200 {
-		t.Errorf("Expected status code 200, got %d", response.Code)
+	first := send("/api/resume", nil)
+	etag, lastModified := first.Header().Get("ETag"), first.Header().Get("Last-Modified")
+	if first.Code != 200 || etag == "" || lastModified == "" {
+		t.Fatalf("Expected ETag and Last-Modified, got %d %v", first.Code, first.Header())
 	}
 
-	payload, ok := response.Payload.(gin.H)
-	if !ok {
-		t.Fatalf("Expected payload to be of type gin.H")
+	t.Run("If-None-Match", func(t *testing.T) {
+		writer := send("/api/resume", map[string]string{"If-None-Match": `"other", ` + etag})
+		if writer.Code != 304 || writer.Body.Len() != 0 {
+			t.Errorf("Expected status code 304 without body, got %d", writer.Code)
+		}
+
+		// each format has its own ETag
+		writer = send("/api/resume?format=markdown", map[string]string{"If-None-Match": etag})
+		if writer.Code != 200 || writer.Header().Get("ETag") == etag {
+			t.Errorf("Expected markdown resume with a different ETag, got %d", writer.Code)
+		}
+	})
+
+	t.Run("If-Modified-Since", func(t *testing.T) {
+		if writer := send("/api/resume", map[string]string{"If-Modified-Since": lastModified}); writer.Code != 304 {
+			t.Errorf("Expected status code 304, got %d", writer.Code)
+		}
+
+		// If-None-Match takes precedence
+		headers := map[string]string{"If-Modified-Since": lastModified, "If-None-Match": `"other"`}
+		if writer := send("/api/resume", headers); writer.Code != 200 {
+			t.Errorf("Expected status code 200, got %d", writer.Code)
+		}
+	})
+
+	t.Run("Changed Resume", func(t *testing.T) {
+		if err := os.WriteFile(path, []byte(`{"basics": {"name": "Jane Doe"}}`), 0o644); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := store.Reload(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		writer := send("/api/resume", map[string]string{"If-None-Match": etag})
+		if writer.Code != 200 || writer.Header().Get("ETag") == etag {
+			t.Errorf("Expected changed resume with a new ETag, got %d", writer.Code)
+		}
+	})
+}
+
+func TestResumeHandlerQuery(t *testing.T) {
+	store := newTestResumeStore(t, &Config{ResumePathJSON: "etc/resume.json"})
+
+	handle := func(path string) RESTResponse {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest("GET", path, nil)
+		return ResumeHandler(ctx, store, newTestResumeRenderer(t))
 	}
 
-	requests, exists := payload["data"].([]ContactRequest)
-	if !exists {
-		t.Fatalf("Expected 'data' key in payload")
+	t.Run("Fields", func(t *testing.T) {
+		response := handle("/api/resume?fields=work,skills")
+		resume, ok := response.Payload.(gin.H)["data"].(*Resume)
+		if response.Code != 200 || !ok {
+			t.Fatalf("Expected resume, got %d %+v", response.Code, response.Payload)
+		}
+		if len(resume.Work) == 0 || len(resume.Skills) == 0 || resume.Education != nil || resume.Basics.Name != "" {
+			t.Errorf("Expected only work and skills, got %+v", resume)
+		}
+	})
+
+	t.Run("Since", func(t *testing.T) {
+		response := handle("/api/resume?since=2022-06&fields=work")
+		resume := response.Payload.(gin.H)["data"].(*Resume)
+		for _, work := range resume.Work {
+			if work.EndDate != "" && work.EndDate < "2022-06" {
+				t.Errorf("Expected work ending after 2022-06, got %+v", work)
+			}
+		}
+		if len(resume.Work) == 0 || len(resume.Work) == len(store.Snapshot().Resume.Work) {
+			t.Errorf("Expected work to be filtered, got %d entries", len(resume.Work))
+		}
+	})
+
+	t.Run("Text Formats", func(t *testing.T) {
+		response := handle("/api/resume?format=markdown&fields=skills")
+		if response.Code != 200 || bytes.Contains(response.Body, []byte("## Experience")) {
+			t.Errorf("Expected markdown resume without experience, got %s", response.Body)
+		}
+	})
+
+	t.Run("Invalid Queries", func(t *testing.T) {
+		for _, path := range []string{
+			"/api/resume?fields=unknown",
+			"/api/resume?since=yesterday",
+			"/api/resume?format=pdf&fields=work",
+		} {
+			if response := handle(path); response.Code != 400 {
+				t.Errorf("Expected status code 400 for %s, got %d", path, response.Code)
+			}
+		}
+	})
+}
+
+func TestResumeSectionHandler(t *testing.T) {
+	store := newTestResumeStore(t, &Config{ResumePathJSON: "etc/resume.json"})
+
+	handle := func(name string, path string) RESTResponse {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest("GET", path, nil)
+		ctx.Params = gin.Params{{Key: "name", Value: name}}
+		return ResumeSectionHandler(ctx, store)
 	}
 
-	if len(requests) != 3 {
-		t.Errorf("Expected 3 contact requests, got %d", len(requests))
+	response := handle("education", "/api/resume/sections/education")
+	if education, ok := response.Payload.(gin.H)["data"].([]ResumeEducation); response.Code != 200 || !ok || len(education) == 0 {
+		t.Errorf("Expected education section, got %d %+v", response.Code, response.Payload)
+	}
+	if response.ETag == "" || response.LastModified.IsZero() {
+		t.Errorf("Expected ETag and Last-Modified, got %+v", response)
+	}
+
+	response = handle("work", "/api/resume/sections/work?since=2030")
+	if work := response.Payload.(gin.H)["data"].([]ResumeWork); len(work) != 1 || work[0].EndDate != "" {
+		t.Errorf("Expected only ongoing work, got %+v", work)
+	}
+
+	if response := handle("unknown", "/api/resume/sections/unknown"); response.Code != 404 {
+		t.Errorf("Expected status code 404, got %d", response.Code)
+	}
+	if response := handle("work", "/api/resume/sections/work?since=soon"); response.Code != 400 {
+		t.Errorf("Expected status code 400, got %d", response.Code)
 	}
 }
 
-func TestContactHandler(t *testing.T) {
-	t.Run("Create New Contact and Request", func(t *testing.T) {
-		persistence := &TestPersistence{
-			Contacts:        make(map[string]Contact),
-			ContactRequests: make(map[string][]ContactRequest),
-		}
+func TestResumeHandlerVariants(t *testing.T) {
+	dir := writeResumeVariants(t, "default.json", "default.de.json")
+	store := newTestResumeStore(t, &Config{ResumeDir: dir})
 
-		body := ContactRequestBody{
-			Name:    "Alice",
-			Email:   "alice@example.com",
-			Message: "Foo bar",
-		}
+	handle := func(path string, acceptLanguage string) (RESTResponse, http.Header) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest("GET", path, nil)
+		ctx.Request.Header.Set("Accept-Language", acceptLanguage)
+		return ResumeHandler(ctx, store, newTestResumeRenderer(t)), recorder.Header()
+	}
 
-		encoded, _ := json.Marshal(body)
+	response, header := handle("/api/resume?format=json", "de-DE, en;q=0.5")
+	if resume := response.Payload.(gin.H)["data"].(*Resume); resume.Basics.Name != "default.de.json" {
+		t.Errorf("Expected German resume, got %s", resume.Basics.Name)
+	}
+	if header.Get("Content-Language") != "de" || header.Get("Vary") != "Accept, Accept-Language" {
+		t.Errorf("Expected Content-Language and Vary headers, got %v", header)
+	}
 
-		writer := httptest.NewRecorder()
-		ctx, _ := gin.CreateTestContext(writer)
-		// set request body
-		ctx.Request = httptest.NewRequest(
-			"POST", "/api/contact", bytes.NewBuffer(encoded))
+	if response, _ := handle("/api/resume?format=json&variant=academic", ""); response.Code != 404 {
+		t.Errorf("Expected status code 404, got %d", response.Code)
+	}
 
-		if _, exists := persistence.Contacts["1"]; exists {
-			t.Errorf("Expected contact with ID '1' to not exist")
-		}
+	response = ResumeVariantsHandler(nil, store)
+	if variants := response.Payload.(gin.H)["data"].([]ResumeVariant); len(variants) != 1 || len(variants[0].Locales) != 2 {
+		t.Errorf("Expected a single variant in two locales, got %+v", variants)
+	}
+}
+
+func TestResumeVersionHandlers(t *testing.T) {
+	persistence := &TestPersistence{}
+	store, err := NewResumeStore(&Config{ResumePathJSON: copyResume(t)}, persistence)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer store.Close()
+
+	upload := func(path string, contentType string, body []byte) RESTResponse {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest("POST", path, bytes.NewReader(body))
+		ctx.Request.Header.Set("Content-Type", contentType)
+		ctx.Set(apiKeyOwnerKey, "admin")
+		return UploadResumeVersionHandler(ctx, persistence, store, 1024)
+	}
+	handle := func(method string, path string, id string, handler func(*gin.Context) RESTResponse) RESTResponse {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(method, path, nil)
+		ctx.Params = gin.Params{{Key: "id", Value: id}}
+		return handler(ctx)
+	}
+	diff := func(c *gin.Context) RESTResponse { return DiffResumeVersionsHandler(c, persistence) }
+	activate := func(c *gin.Context) RESTResponse { return ActivateResumeVersionHandler(c, persistence, store) }
 
-		response := ContactHandler(ctx, persistence)
+	t.Run("Upload", func(t *testing.T) {
+		response := upload("/api/resume/versions", "application/json", []byte(`{"basics": {"name": "Jane Doe"}}`))
 		if response.Code != 201 {
-			t.Errorf("Expected status code 201, got %d", response.Code)
+			t.Fatalf("Expected status code 201, got %d", response.Code)
+		}
+		if version := response.Payload.(gin.H)["data"].(*ResumeVersion); version.Author != "admin" || version.Variant != DefaultResumeVariant || version.Locale != DefaultResumeLocale {
+			t.Errorf("Expected version of the default variant by admin, got %+v", version)
+		}
+		if name := store.Snapshot().Resume.Basics.Name; name != "Jane Doe" {
+			t.Errorf("Expected uploaded resume to be served, got %s", name)
 		}
 
-		if _, exists := persistence.Contacts["1"]; !exists {
-			t.Errorf("Expected contact with ID '1' to exist")
+		upload("/api/resume/versions", "application/json; charset=utf-8", []byte(`{"basics": {"name": "Jane Smith"}}`))
+		if response := upload("/api/resume/versions", "application/pdf", []byte("%PDF-1.4")); response.Code != 201 || store.Snapshot().PDF == nil {
+			t.Errorf("Expected uploaded PDF to be served, got %d", response.Code)
 		}
 	})
 
-	t.Run("Use Existing Contact and Create Request", func(t *testing.T) {
-		persistence := &TestPersistence{
-			Contacts: map[string]Contact{
-				"1": {Id: "1", Name: "Alice", Email: "alice@example.com"},
-			},
-			ContactRequests: make(map[string][]ContactRequest),
+	t.Run("Invalid Upload", func(t *testing.T) {
+		response := upload("/api/resume/versions", "application/json", []byte(`{"basics": {"name": "Jane Doe", "email": "jane"}}`))
+		if fields, _ := response.Payload.(gin.H)["fields"].([]ResumeFieldError); response.Code != 400 || len(fields) != 1 || fields[0].Field != "basics.email" {
+			t.Errorf("Expected 400 with invalid fields, got %d %+v", response.Code, response.Payload)
 		}
 
-		body := ContactRequestBody{
-			Name:    "Alice",
-			Email:   "alice@example.com",
-			Message: "Hello again",
+		tests := []struct {
+			name        string
+			path        string
+			contentType string
+			body        []byte
+			expected    int
+		}{
+			{"Too Large", "/api/resume/versions", "application/json", bytes.Repeat([]byte(" "), 2048), 413},
+			{"Unsupported Content Type", "/api/resume/versions", "text/plain", []byte("Jane Doe"), 415},
+			{"Invalid Variant", "/api/resume/versions?variant=Jane%20Doe", "application/json", []byte(`{"basics": {"name": "Jane Doe"}}`), 400},
+			{"Invalid PDF", "/api/resume/versions", "application/pdf", []byte("Jane Doe"), 400},
+			{"PDF Without JSON", "/api/resume/versions?locale=fr", "application/pdf", []byte("%PDF-1.4"), 400},
+		}
+		for _, tc := range tests {
+			if response := upload(tc.path, tc.contentType, tc.body); response.Code != tc.expected {
+				t.Errorf("Expected status code %d for %s, got %d", tc.expected, tc.name, response.Code)
+			}
 		}
+	})
 
-		encoded, _ := json.Marshal(body)
+	t.Run("List", func(t *testing.T) {
+		response := ListResumeVersionsHandler(nil, persistence)
+		if versions := response.Payload.(gin.H)["data"].([]ResumeVersion); len(versions) != 3 || versions[0].Format != ResumeFormatPDF || versions[0].Content != nil {
+			t.Errorf("Expected 3 versions most recent first without content, got %+v", versions)
+		}
+	})
 
-		writer := httptest.NewRecorder()
-		ctx, _ := gin.CreateTestContext(writer)
-		// set request body
-		ctx.Request = httptest.NewRequest(
-			"POST", "/api/contact", bytes.NewBuffer(encoded))
+	t.Run("Diff", func(t *testing.T) {
+		response := handle("GET", "/api/resume/versions/version-2/diff?base=version-1", "version-2", diff)
+		expected := []ResumeChange{{Path: "basics.name", Type: ResumeChangeChanged, From: "Jane Doe", To: "Jane Smith"}}
+		if changes, _ := response.Payload.(gin.H)["data"].([]ResumeChange); response.Code != 200 || !reflect.DeepEqual(changes, expected) {
+			t.Errorf("Expected changes %+v, got %d %+v", expected, response.Code, response.Payload)
+		}
 
-		if _, exists := persistence.Contacts["1"]; !exists {
-			t.Errorf("Expected contact with ID '1' to exist")
+		if response := handle("GET", "/api/resume/versions/version-2/diff", "version-2", diff); response.Code != 400 {
+			t.Errorf("Expected status code 400 without base, got %d", response.Code)
+		}
+		if response := handle("GET", "/api/resume/versions/version-3/diff?base=version-1", "version-3", diff); response.Code != 400 {
+			t.Errorf("Expected status code 400 for PDF, got %d", response.Code)
+		}
+		if response := handle("GET", "/api/resume/versions/missing/diff?base=version-1", "missing", diff); response.Code != 404 {
+			t.Errorf("Expected status code 404, got %d", response.Code)
 		}
+	})
 
-		response := ContactHandler(ctx, persistence)
-		if response.Code != 201 {
-			t.Errorf("Expected status code 201, got %d", response.Code)
+	t.Run("Rollback", func(t *testing.T) {
+		response := handle("POST", "/api/resume/versions/version-1/activate", "version-1", activate)
+		if response.Code != 200 {
+			t.Fatalf("Expected status code 200, got %d", response.Code)
+		}
+		if name := store.Snapshot().Resume.Basics.Name; name != "Jane Doe" {
+			t.Errorf("Expected rolled back resume to be served, got %s", name)
+		}
+
+		if response := handle("POST", "/api/resume/versions/missing/activate", "missing", activate); response.Code != 404 {
+			t.Errorf("Expected status code 404, got %d", response.Code)
 		}
 	})
 }
+
+func TestResumeShareHandlers(t *testing.T) {
+	dir := writeResumeVariants(t, "default.json", "default.de.json", "short.json")
+	store := newTestResumeStore(t, &Config{ResumeDir: dir})
+	persistence := &TestPersistence{}
+	sharer := newTestResumeSharer()
+
+	create := func(body string) RESTResponse {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest("POST", "/api/resume/shares", strings.NewReader(body))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+		ctx.Set(apiKeyOwnerKey, "admin")
+		return CreateResumeShareHandler(ctx, persistence, store, sharer)
+	}
+	// opens are recorded by the middleware once the response is sent
+	var response RESTResponse
+	router := gin.New()
+	router.GET("/api/resume/shared/:token", ResumeShareMiddleware(persistence), func(c *gin.Context) {
+		response = SharedResumeHandler(c, persistence, store, sharer, newTestResumeRenderer(t))
+		response.Send(c)
+	})
+	send := func(request *http.Request) (RESTResponse, *httptest.ResponseRecorder) {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return response, recorder
+	}
+	open := func(token string) (RESTResponse, http.Header) {
+		response, recorder := send(httptest.NewRequest("GET", "/api/resume/shared/"+token, nil))
+		return response, recorder.Header()
+	}
+	opens := func(id string) int {
+		share, err := persistence.GetResumeShare(context.Background(), id)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return share.Opens
+	}
+
+	var share *ResumeShare
+	t.Run("Create", func(t *testing.T) {
+		response := create(`{"locale": "de", "format": "json", "recipient": "recruiter@example.com", "single_use": true}`)
+		if response.Code != 201 {
+			t.Fatalf("Expected status code 201, got %d", response.Code)
+		}
+		share = response.Payload.(gin.H)["data"].(*ResumeShare)
+		if share.Variant != DefaultResumeVariant || share.Locale != "de" || share.CreatedBy != "admin" || share.Token == "" {
+			t.Errorf("Expected signed share of the German resume by admin, got %+v", share)
+		}
+		if expiry := time.Until(share.ExpiresAt); expiry > sharer.TTL || expiry < sharer.TTL-time.Minute {
+			t.Errorf("Expected share to expire after the default TTL, got %s", expiry)
+		}
+
+		if response := create(`{"variant": "short", "expires_in": 600}`); response.Code != 201 {
+			t.Errorf("Expected status code 201, got %d", response.Code)
+		} else if created := response.Payload.(gin.H)["data"].(*ResumeShare); created.Format != ResumeFormatPDF || created.Locale != DefaultResumeLocale {
+			t.Errorf("Expected PDF share of the default locale, got %+v", created)
+		}
+	})
+
+	t.Run("Invalid Create", func(t *testing.T) {
+		tests := []struct {
+			name string
+			body string
+		}{
+			{"Unknown Variant", `{"variant": "academic"}`},
+			{"Unknown Locale", `{"locale": "fr"}`},
+			{"Unknown Format", `{"format": "docx"}`},
+			{"Short Expiry", `{"expires_in": 1}`},
+			{"Long Expiry", `{"expires_in": 172800}`},
+		}
+		for _, tc := range tests {
+			if response := create(tc.body); response.Code != 400 {
+				t.Errorf("Expected status code 400 for %s, got %d", tc.name, response.Code)
+			}
+		}
+	})
+
+	t.Run("Create Rendered Formats", func(t *testing.T) {
+		db := newTestSQLitePersistence(t)
+
+		for _, format := range []ResumeFileFormat{ResumeFormatHTML, ResumeFormatMarkdown, ResumeFormatText} {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest("POST", "/api/resume/shares", strings.NewReader(`{"format": "`+string(format)+`"}`))
+			ctx.Request.Header.Set("Content-Type", "application/json")
+			ctx.Set(apiKeyOwnerKey, "admin")
+
+			response := CreateResumeShareHandler(ctx, db, store, sharer)
+			if response.Code != 201 {
+				t.Errorf("Expected status code 201 for %s, got %d", format, response.Code)
+			}
+		}
+
+		shares, err := db.ListResumeShares(context.Background())
+		if err != nil || len(shares) != 3 {
+			t.Fatalf("Expected 3 shares, got %+v %v", shares, err)
+		}
+		for _, share := range shares {
+			if share.Format == ResumeFormatJSON || share.Format == ResumeFormatPDF {
+				t.Errorf("Expected share of a rendered format, got %+v", share)
+			}
+		}
+	})
+
+	t.Run("Open", func(t *testing.T) {
+		response, header := open(share.Token)
+		if response.Code != 200 {
+			t.Fatalf("Expected status code 200, got %d", response.Code)
+		}
+		if resume := response.Payload.(gin.H)["data"].(*Resume); resume.Basics.Name != "default.de.json" {
+			t.Errorf("Expected shared German resume, got %s", resume.Basics.Name)
+		}
+		if header.Get("Cache-Control") != "private, no-store" || header.Get("Content-Language") != "de" {
+			t.Errorf("Expected uncached German resume, got %v", header)
+		}
+		if count := opens(share.Id); count != 1 {
+			t.Errorf("Expected share to be opened once, got %d", count)
+		}
+
+		// single use links can only be opened once
+		if response, _ := open(share.Token); response.Code != 410 {
+			t.Errorf("Expected status code 410, got %d", response.Code)
+		}
+	})
+
+	t.Run("Invalid Open", func(t *testing.T) {
+		if response, _ := open("invalid"); response.Code != 404 {
+			t.Errorf("Expected status code 404, got %d", response.Code)
+		}
+
+		expired := sharer.Token(ResumeShare{Id: "share-2", ExpiresAt: time.Now().Add(-time.Minute)})
+		if response, _ := open(expired); response.Code != 410 {
+			t.Errorf("Expected status code 410, got %d", response.Code)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		response := ListResumeSharesHandler(nil, persistence, sharer)
+		shares := response.Payload.(gin.H)["data"].([]ResumeShare)
+		if len(shares) != 2 || shares[1].Opens != 1 || shares[1].FirstOpenedAt == nil || shares[1].Token != share.Token {
+			t.Errorf("Expected shares most recent first with opens and tokens, got %+v", shares)
+		}
+	})
+
+	t.Run("Removed Variant Does Not Consume Link", func(t *testing.T) {
+		removed, _ := persistence.CreateResumeShare(context.Background(), ResumeShare{
+			Variant: "academic", Locale: DefaultResumeLocale, Format: ResumeFormatJSON, SingleUse: true, ExpiresAt: time.Now().Add(time.Hour),
+		})
+		if response, _ := open(sharer.Token(*removed)); response.Code != 404 {
+			t.Errorf("Expected status code 404, got %d", response.Code)
+		}
+		if count := opens(removed.Id); count != 0 {
+			t.Errorf("Expected link not to be opened, got %d opens", count)
+		}
+	})
+
+	t.Run("Partial Responses Are Not Opens", func(t *testing.T) {
+		pdf, _ := persistence.CreateResumeShare(context.Background(), ResumeShare{
+			Variant: DefaultResumeVariant, Locale: DefaultResumeLocale, Format: ResumeFormatPDF, SingleUse: true, ExpiresAt: time.Now().Add(time.Hour),
+		})
+		request := httptest.NewRequest("GET", "/api/resume/shared/"+sharer.Token(*pdf), nil)
+		request.Header.Set("Range", "bytes=0-9")
+		if _, recorder := send(request); recorder.Code != 206 {
+			t.Fatalf("Expected status code 206, got %d", recorder.Code)
+		}
+		if count := opens(pdf.Id); count != 0 {
+			t.Errorf("Expected range request not to open the link, got %d opens", count)
+		}
+
+		if _, recorder := send(httptest.NewRequest("GET", "/api/resume/shared/"+sharer.Token(*pdf), nil)); recorder.Code != 200 {
+			t.Fatalf("Expected status code 200, got %d", recorder.Code)
+		}
+		if count := opens(pdf.Id); count != 1 {
+			t.Errorf("Expected share to be opened once, got %d", count)
+		}
+	})
+}
+
+func TestResumeStatsHandler(t *testing.T) {
+	persistence := &TestPersistence{}
+
+	handle := func(path string) RESTResponse {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest("GET", path, nil)
+		return ResumeStatsHandler(ctx, persistence)
+	}
+
+	response := handle("/api/stats/resume")
+	if response.Code != 200 {
+		t.Fatalf("Expected status code 200, got %d", response.Code)
+	}
+	stats := response.Payload.(gin.H)["data"].(*ResumeStats)
+	if period := stats.Until.Sub(stats.Since); period != DefaultResumeStatsPeriod {
+		t.Errorf("Expected default period, got %s", period)
+	}
+
+	response = handle("/api/stats/resume?since=2026-01-01&until=2026-02-01T00:00:00Z")
+	stats = response.Payload.(gin.H)["data"].(*ResumeStats)
+	if !stats.Since.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) || !stats.Until.Equal(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected requested period, got %s to %s", stats.Since, stats.Until)
+	}
+
+	for _, path := range []string{"/api/stats/resume?since=yesterday", "/api/stats/resume?until=never", "/api/stats/resume?since=2026-02-01&until=2026-01-01"} {
+		if response := handle(path); response.Code != 400 {
+			t.Errorf("Expected status code 400 for %s, got %d", path, response.Code)
+		}
+	}
+}
+
+func TestStatsHandler(t *testing.T) {
+	persistence := &TestPersistence{}
+
+	logger := &RequestLogger{}
+	logger.dropped.Add(2)
+
+	response := StatsHandler(nil, persistence, logger)
+	if response.Code != 200 {
+		t.Errorf("Expected status code 200, got %d", response.Code)
+	}
+
+	stats := response.Payload.(gin.H)["data"].(*RequestStats)
+	if stats.DroppedRequestLogs != 2 {
+		t.Errorf("Expected 2 dropped request logs, got %d", stats.DroppedRequestLogs)
+	}
+}
+
+func TestListContactsHandler(t *testing.T) {
+
+	t.Run("No Contacts", func(t *testing.T) {
+		persistence := &TestPersistence{
+			Contacts: make(map[string]Contact),
+		}
+
+		response := ListContactsHandler(nil, persistence)
+		if response.Code != 200 {
+			t.Errorf("Expected status code 200, got %d", response.Code)
+		}
+
+		payload, ok := response.Payload.(gin.H)
+		if !ok {
+			t.Fatalf("Expected payload to be of type gin.H")
+		}
+
+		contacts, exists := payload["data"].([]Contact)
+		if !exists {
+			t.Fatalf("Expected 'data' key in payload")
+		}
+
+		if len(contacts) != 0 {
+			t.Errorf("Expected 0 contacts, got %d", len(contacts))
+		}
+	})
+
+	t.Run("With Contacts", func(t *testing.T) {
+		persistence := &TestPersistence{
+			Contacts: map[string]Contact{
+				"1": {Id: "1", Name: "John Doe", Email: "john@example.com"},
+				"2": {Id: "2", Name: "Jane Doe", Email: "jane@example.com"},
+				"3": {Id: "3", Name: "Jim Beam", Email: "jim@example.com"},
+			},
+		}
+
+		response := ListContactsHandler(nil, persistence)
+		if response.Code != 200 {
+			t.Errorf("Expected status code 200, got %d", response.Code)
+		}
+
+		payload, ok := response.Payload.(gin.H)
+		if !ok {
+			t.Fatalf("Expected payload to be of type gin.H")
+		}
+
+		contacts, exists := payload["data"].([]Contact)
+		if !exists {
+			t.Fatalf("Expected 'data' key in payload")
+		}
+
+		if len(contacts) != 3 {
+			t.Errorf("Expected 3 contacts, got %d", len(contacts))
+		}
+	})
+}
+
+func TestListContactsHandlerPagination(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	persistence := &TestPersistence{
+		Contacts: map[string]Contact{
+			"1": {Id: "1", Email: "a@example.com", CreatedAt: base},
+			"2": {Id: "2", Email: "b@example.com", CreatedAt: base.Add(time.Hour)},
+			"3": {Id: "3", Email: "c@example.com", CreatedAt: base.Add(2 * time.Hour)},
+			"4": {Id: "4", Email: "d@example.com", CreatedAt: base.Add(2 * time.Hour)},
+			"5": {Id: "5", Email: "e@example.com", CreatedAt: base.Add(3 * time.Hour)},
+		},
+	}
+
+	list := func(t *testing.T, query string) ([]Contact, any) {
+		t.Helper()
+
+		writer := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(writer)
+		ctx.Request = httptest.NewRequest(
+			"GET", "/api/admin/contacts?"+query, nil)
+
+		response := ListContactsHandler(ctx, persistence)
+		if response.Code != 200 {
+			t.Fatalf("Expected status code 200, got %d", response.Code)
+		}
+
+		payload := response.Payload.(gin.H)
+		return payload["data"].([]Contact), payload["next_cursor"]
+	}
+
+	t.Run("Walk Pages", func(t *testing.T) {
+		var ids []string
+		query := "limit=2"
+		for range 5 {
+			contacts, next := list(t, query)
+			for _, contact := range contacts {
+				ids = append(ids, contact.Id)
+			}
+			if next == nil {
+				break
+			}
+			query = "limit=2&cursor=" + next.(string)
+		}
+
+		if strings.Join(ids, ",") != "1,2,3,4,5" {
+			t.Errorf("Expected contacts 1,2,3,4,5, got %s", strings.Join(ids, ","))
+		}
+	})
+
+	t.Run("Descending", func(t *testing.T) {
+		contacts, next := list(t, "sort=-created_at&limit=3")
+		if len(contacts) != 3 || contacts[0].Id != "5" || contacts[1].Id != "4" {
+			t.Errorf("Expected newest contacts first, got %+v", contacts)
+		}
+
+		if next == nil {
+			t.Errorf("Expected next cursor")
+		}
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		contacts, next := list(t, "email=C@example.com")
+		if len(contacts) != 1 || contacts[0].Id != "3" {
+			t.Errorf("Expected contact 3, got %+v", contacts)
+		}
+
+		if next != nil {
+			t.Errorf("Expected no next cursor, got %v", next)
+		}
+
+		contacts, _ = list(t, "since=2025-01-01T01:00:00Z&until=2025-01-01T03:00:00Z")
+		if len(contacts) != 3 {
+			t.Errorf("Expected 3 contacts, got %d", len(contacts))
+		}
+	})
+
+	t.Run("Invalid Query", func(t *testing.T) {
+		for _, query := range []string{"limit=0", "limit=abc", "sort=name", "cursor=invalid", "since=yesterday", "status=spam"} {
+			writer := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(writer)
+			ctx.Request = httptest.NewRequest(
+				"GET", "/api/admin/contacts?"+query, nil)
+
+			response := ListContactsHandler(ctx, persistence)
+			if response.Code != 400 {
+				t.Errorf("Expected status code 400 for %s, got %d", query, response.Code)
+			}
+		}
+	})
+}
+
+func TestListContactRequestsHandler(t *testing.T) {
+	persistence := &TestPersistence{
+		ContactRequests: map[string][]ContactRequest{
+			"1": {
+				{Id: "req1", ContactId: "1", Message: "Hello"},
+				{Id: "req2", ContactId: "1", Message: "Need help"},
+			},
+			"2": {
+				{Id: "req3", ContactId: "2", Message: "Inquiry"},
+			},
+		},
+	}
+
+	response := ListContactRequestsHandler(nil, persistence)
+	if response.Code != 200 {
+		t.Errorf("Expected status code 200, got %d", response.Code)
+	}
+
+	payload, ok := response.Payload.(gin.H)
+	if !ok {
+		t.Fatalf("Expected payload to be of type gin.H")
+	}
+
+	requests, exists := payload["data"].([]ContactRequest)
+	if !exists {
+		t.Fatalf("Expected 'data' key in payload")
+	}
+
+	if len(requests) != 3 {
+		t.Errorf("Expected 3 contact requests, got %d", len(requests))
+	}
+}
+
+func TestListContactRequestsHandlerStatusFilter(t *testing.T) {
+	persistence := &TestPersistence{
+		ContactRequests: map[string][]ContactRequest{
+			"1": {
+				{Id: "req1", ContactId: "1", Message: "Hello", Status: ContactRequestStatusNew},
+				{Id: "req2", ContactId: "1", Message: "Need help", Status: ContactRequestStatusRead},
+			},
+			"2": {
+				{Id: "req3", ContactId: "2", Message: "Buy now", Status: ContactRequestStatusSpam},
+			},
+		},
+	}
+
+	list := func(query string) RESTResponse {
+		writer := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(writer)
+		ctx.Request = httptest.NewRequest(
+			"GET", "/api/admin/contacts/requests?"+query, nil)
+		return ListContactRequestsHandler(ctx, persistence)
+	}
+
+	response := list("status=spam")
+	if response.Code != 200 {
+		t.Fatalf("Expected status code 200, got %d", response.Code)
+	}
+
+	requests := response.Payload.(gin.H)["data"].([]ContactRequest)
+	if len(requests) != 1 || requests[0].Id != "req3" {
+		t.Errorf("Expected request req3, got %+v", requests)
+	}
+
+	if response := list("status=unknown"); response.Code != 400 {
+		t.Errorf("Expected status code 400, got %d", response.Code)
+	}
+}
+
+func TestUpdateContactRequestHandler(t *testing.T) {
+	update := func(persistence *TestPersistence, id string, body string) RESTResponse {
+		writer := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(writer)
+		ctx.Request = httptest.NewRequest(
+			"PATCH", "/api/admin/contacts/requests/"+id, bytes.NewBufferString(body))
+		ctx.Params = gin.Params{{Key: "id", Value: id}}
+		return UpdateContactRequestHandler(ctx, persistence)
+	}
+
+	newPersistence := func() *TestPersistence {
+		return &TestPersistence{
+			ContactRequests: map[string][]ContactRequest{
+				"1": {
+					{Id: "req1", ContactId: "1", Message: "Hello", Status: ContactRequestStatusNew},
+					{Id: "req2", ContactId: "1", Message: "Thanks", Status: ContactRequestStatusReplied},
+				},
+			},
+		}
+	}
+
+	t.Run("Update Status and Notes", func(t *testing.T) {
+		persistence := newPersistence()
+
+		response := update(persistence, "req1", `{"status": "read", "notes": "follow up next week"}`)
+		if response.Code != 200 {
+			t.Fatalf("Expected status code 200, got %d", response.Code)
+		}
+
+		request := persistence.ContactRequests["1"][0]
+		if request.Status != ContactRequestStatusRead || request.Notes != "follow up next week" {
+			t.Errorf("Expected read request with notes, got %+v", request)
+		}
+	})
+
+	t.Run("Update Notes Only", func(t *testing.T) {
+		persistence := newPersistence()
+
+		response := update(persistence, "req2", `{"notes": "sent quote"}`)
+		if response.Code != 200 {
+			t.Fatalf("Expected status code 200, got %d", response.Code)
+		}
+
+		request := persistence.ContactRequests["1"][1]
+		if request.Status != ContactRequestStatusReplied || request.Notes != "sent quote" {
+			t.Errorf("Expected only notes to be updated, got %+v", request)
+		}
+	})
+
+	t.Run("Invalid Transition", func(t *testing.T) {
+		response := update(newPersistence(), "req2", `{"status": "new"}`)
+		if response.Code != 409 {
+			t.Errorf("Expected status code 409, got %d", response.Code)
+		}
+	})
+
+	t.Run("Invalid Payload", func(t *testing.T) {
+		for _, body := range []string{`{}`, `{"status": "deleted"}`, `not json`} {
+			response := update(newPersistence(), "req1", body)
+			if response.Code != 400 {
+				t.Errorf("Expected status code 400 for %s, got %d", body, response.Code)
+			}
+		}
+	})
+
+	t.Run("Missing Request", func(t *testing.T) {
+		response := update(newPersistence(), "req3", `{"status": "read"}`)
+		if response.Code != 404 {
+			t.Errorf("Expected status code 404, got %d", response.Code)
+		}
+	})
+}
+
+func TestContactHandler(t *testing.T) {
+	t.Run("Create New Contact and Request", func(t *testing.T) {
+		persistence := &TestPersistence{
+			Contacts:        make(map[string]Contact),
+			ContactRequests: make(map[string][]ContactRequest),
+		}
+
+		pow := newTestProofOfWork()
+		challenge, nonce := newSolvedChallenge(t, pow)
+		body := ContactRequestBody{
+			Name:      "Alice",
+			Email:     "alice@example.com",
+			Message:   "Foo bar",
+			Challenge: challenge,
+			Nonce:     nonce,
+		}
+
+		encoded, _ := json.Marshal(body)
+
+		writer := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(writer)
+		// set request body
+		ctx.Request = httptest.NewRequest(
+			"POST", "/api/contact", bytes.NewBuffer(encoded))
+
+		if _, exists := persistence.Contacts["1"]; exists {
+			t.Errorf("Expected contact with ID '1' to not exist")
+		}
+
+		notifier := &TestNotifier{}
+		events := &TestPublisher{}
+		response := ContactHandler(ctx, persistence, notifier, events, newTestSpamFilter(), pow)
+		if response.Code != 201 {
+			t.Errorf("Expected status code 201, got %d", response.Code)
+		}
+
+		if _, exists := persistence.Contacts["1"]; !exists {
+			t.Errorf("Expected contact with ID '1' to exist")
+		}
+
+		if len(notifier.Notifications) != 1 || notifier.Notifications[0].ContactId != "1" {
+			t.Errorf("Expected 1 notification for contact 1, got %+v", notifier.Notifications)
+		}
+
+		if len(events.Events) != 2 || events.Events[0].Type != EventContactCreated || events.Events[1].Type != EventContactRequestCreated {
+			t.Errorf("Expected contact and contact request created events, got %+v", events.Events)
+		}
+	})
+
+	t.Run("Use Existing Contact and Create Request", func(t *testing.T) {
+		persistence := &TestPersistence{
+			Contacts: map[string]Contact{
+				"1": {Id: "1", Name: "Alice", Email: "alice@example.com"},
+			},
+			ContactRequests: make(map[string][]ContactRequest),
+		}
+
+		pow := newTestProofOfWork()
+		challenge, nonce := newSolvedChallenge(t, pow)
+		body := ContactRequestBody{
+			Name:      "Alice",
+			Email:     "alice@example.com",
+			Message:   "Hello again",
+			Challenge: challenge,
+			Nonce:     nonce,
+		}
+
+		encoded, _ := json.Marshal(body)
+
+		writer := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(writer)
+		// set request body
+		ctx.Request = httptest.NewRequest(
+			"POST", "/api/contact", bytes.NewBuffer(encoded))
+
+		if _, exists := persistence.Contacts["1"]; !exists {
+			t.Errorf("Expected contact with ID '1' to exist")
+		}
+
+		events := &TestPublisher{}
+		response := ContactHandler(ctx, persistence, NoopNotifier{}, events, newTestSpamFilter(), pow)
+		if response.Code != 201 {
+			t.Errorf("Expected status code 201, got %d", response.Code)
+		}
+
+		if len(events.Events) != 1 || events.Events[0].Type != EventContactRequestCreated {
+			t.Errorf("Expected only a contact request created event, got %+v", events.Events)
+		}
+	})
+
+	t.Run("Client Disconnected", func(t *testing.T) {
+		persistence := &TestPersistence{
+			Contacts:        make(map[string]Contact),
+			ContactRequests: make(map[string][]ContactRequest),
+		}
+
+		pow := newTestProofOfWork()
+		challenge, nonce := newSolvedChallenge(t, pow)
+		encoded, _ := json.Marshal(ContactRequestBody{
+			Name:      "Alice",
+			Email:     "alice@example.com",
+			Message:   "Foo bar",
+			Challenge: challenge,
+			Nonce:     nonce,
+		})
+
+		cancelled, cancel := context.WithCancel(context.Background())
+		cancel()
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequestWithContext(cancelled,
+			"POST", "/api/contact", bytes.NewBuffer(encoded))
+
+		events := &TestPublisher{}
+		ContactHandler(ctx, persistence, NoopNotifier{}, events, newTestSpamFilter(), pow)
+		if len(events.Events) != 2 {
+			t.Errorf("Expected events to be published after the client disconnected, got %+v", events.Events)
+		}
+	})
+}
+
+func TestContactHandlerSpam(t *testing.T) {
+	persistence := &TestPersistence{
+		Contacts:        make(map[string]Contact),
+		ContactRequests: make(map[string][]ContactRequest),
+	}
+
+	pow := newTestProofOfWork()
+	challenge, nonce := newSolvedChallenge(t, pow)
+	body := ContactRequestBody{
+		Name:      "Bot",
+		Email:     "bot@example.com",
+		Message:   "Buy now",
+		Website:   "https://example.com",
+		Challenge: challenge,
+		Nonce:     nonce,
+	}
+
+	encoded, _ := json.Marshal(body)
+
+	writer := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(writer)
+	ctx.Request = httptest.NewRequest(
+		"POST", "/api/contact", bytes.NewBuffer(encoded))
+
+	notifier := &TestNotifier{}
+	events := &TestPublisher{}
+	response := ContactHandler(ctx, persistence, notifier, events, newTestSpamFilter(), pow)
+	if response.Code != 201 {
+		t.Errorf("Expected status code 201, got %d", response.Code)
+	}
+
+	requests := persistence.ContactRequests["1"]
+	if len(requests) != 1 {
+		t.Fatalf("Expected flagged request to be stored, got %+v", persistence.ContactRequests)
+	}
+
+	if requests[0].Status != ContactRequestStatusSpam || requests[0].SpamScore < 100 {
+		t.Errorf("Expected request marked as spam with score, got %+v", requests[0])
+	}
+
+	if len(notifier.Notifications) != 0 || len(events.Events) != 0 {
+		t.Errorf("Expected no notifications or events, got %+v and %+v", notifier.Notifications, events.Events)
+	}
+}
+
+func TestContactHandlerChallenge(t *testing.T) {
+	pow := newTestProofOfWork()
+	persistence := &TestPersistence{
+		Contacts:        make(map[string]Contact),
+		ContactRequests: make(map[string][]ContactRequest),
+	}
+
+	submit := func(challenge string, nonce string) RESTResponse {
+		body := ContactRequestBody{
+			Name:      "Alice",
+			Email:     "alice@example.com",
+			Message:   "Foo bar",
+			Challenge: challenge,
+			Nonce:     nonce,
+		}
+		encoded, _ := json.Marshal(body)
+
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(
+			"POST", "/api/contact", bytes.NewBuffer(encoded))
+		return ContactHandler(ctx, persistence, NoopNotifier{}, NoopPublisher{}, newTestSpamFilter(), pow)
+	}
+
+	t.Run("Missing Challenge", func(t *testing.T) {
+		if response := submit("", ""); response.Code != 400 {
+			t.Errorf("Expected status code 400, got %d", response.Code)
+		}
+	})
+
+	t.Run("Unsolved Challenge", func(t *testing.T) {
+		challenge, nonce := newSolvedChallenge(t, pow)
+		// changing the challenge invalidates its signature
+		if response := submit(challenge+"0", nonce); response.Code != 403 {
+			t.Errorf("Expected status code 403, got %d", response.Code)
+		}
+
+		if len(persistence.Contacts) != 0 {
+			t.Errorf("Expected nothing to be stored, got %+v", persistence.Contacts)
+		}
+	})
+
+	t.Run("Challenge Is Single Use", func(t *testing.T) {
+		challenge, nonce := newSolvedChallenge(t, pow)
+		if response := submit(challenge, nonce); response.Code != 201 {
+			t.Fatalf("Expected status code 201, got %d", response.Code)
+		}
+
+		if response := submit(challenge, nonce); response.Code != 403 {
+			t.Errorf("Expected status code 403 for reused challenge, got %d", response.Code)
+		}
+
+		if len(persistence.ContactRequests["1"]) != 1 {
+			t.Errorf("Expected 1 contact request, got %+v", persistence.ContactRequests)
+		}
+	})
+}
+
+func TestChallengeHandler(t *testing.T) {
+	pow := newTestProofOfWork()
+
+	response := ChallengeHandler(nil, pow)
+	if response.Code != 200 {
+		t.Errorf("Expected status code 200, got %d", response.Code)
+	}
+
+	challenge, ok := response.Payload.(gin.H)["data"].(Challenge)
+	if !ok {
+		t.Fatalf("Expected challenge in payload, got %+v", response.Payload)
+	}
+
+	if challenge.Difficulty != pow.Difficulty || challenge.Algorithm != "sha256" {
+		t.Errorf("Expected sha256 challenge with difficulty %d, got %+v", pow.Difficulty, challenge)
+	}
+
+	if _, _, err := pow.Verify(challenge.Challenge, solveChallenge(challenge), time.Now()); err != nil {
+		t.Errorf("Expected issued challenge to be solvable, got %v", err)
+	}
+}
+
+func TestContactTokenHandler(t *testing.T) {
+	filter := newTestSpamFilter()
+
+	response := ContactTokenHandler(nil, filter)
+	if response.Code != 200 {
+		t.Errorf("Expected status code 200, got %d", response.Code)
+	}
+
+	token, ok := response.Payload.(gin.H)["data"].(string)
+	if !ok {
+		t.Fatalf("Expected token in payload, got %+v", response.Payload)
+	}
+
+	// a token used immediately is flagged as submitted too fast
+	score, _ := filter.checkToken(token, time.Now())
+	if score != spamScoreTooFast {
+		t.Errorf("Expected score %d for immediate submission, got %d", spamScoreTooFast, score)
+	}
+
+	if score, _ := filter.checkToken(token, time.Now().Add(time.Minute)); score != 0 {
+		t.Errorf("Expected valid token after a minute, got score %d", score)
+	}
+}
+
+func TestGetContactHandler(t *testing.T) {
+	persistence := &TestPersistence{
+		Contacts: map[string]Contact{
+			"1": {Id: "1", Name: "Alice", Email: "alice@example.com"},
+		},
+	}
+
+	for _, tc := range []struct {
+		id       string
+		expected int
+	}{{"1", 200}, {"2", 404}} {
+		writer := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(writer)
+		ctx.Request = httptest.NewRequest("GET", "/api/admin/contacts/"+tc.id, nil)
+		ctx.Params = gin.Params{{Key: "id", Value: tc.id}}
+
+		response := GetContactHandler(ctx, persistence)
+		if response.Code != tc.expected {
+			t.Errorf("Expected status code %d for contact %s, got %d", tc.expected, tc.id, response.Code)
+		}
+	}
+}
+
+func TestUpdateContactHandler(t *testing.T) {
+	update := func(persistence *TestPersistence, id string, body string) RESTResponse {
+		writer := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(writer)
+		ctx.Request = httptest.NewRequest(
+			"PATCH", "/api/admin/contacts/"+id, bytes.NewBufferString(body))
+		ctx.Params = gin.Params{{Key: "id", Value: id}}
+		return UpdateContactHandler(ctx, persistence)
+	}
+
+	newPersistence := func() *TestPersistence {
+		return &TestPersistence{
+			Contacts: map[string]Contact{
+				"1": {Id: "1", Name: "Alice", Email: "alice@example.com"},
+				"2": {Id: "2", Name: "Bob", Email: "bob@example.com"},
+			},
+		}
+	}
+
+	t.Run("Update Name", func(t *testing.T) {
+		persistence := newPersistence()
+
+		response := update(persistence, "1", `{"name": "Alice Smith"}`)
+		if response.Code != 200 {
+			t.Fatalf("Expected status code 200, got %d", response.Code)
+		}
+
+		contact := persistence.Contacts["1"]
+		if contact.Name != "Alice Smith" || contact.Email != "alice@example.com" {
+			t.Errorf("Expected only name to be updated, got %+v", contact)
+		}
+	})
+
+	t.Run("Update Email", func(t *testing.T) {
+		persistence := newPersistence()
+
+		response := update(persistence, "1", `{"email": "Alice.Smith@example.com"}`)
+		if response.Code != 200 {
+			t.Fatalf("Expected status code 200, got %d", response.Code)
+		}
+
+		if email := persistence.Contacts["1"].Email; email != "alice.smith@example.com" {
+			t.Errorf("Expected normalized email, got %s", email)
+		}
+	})
+
+	t.Run("Conflicting Email", func(t *testing.T) {
+		response := update(newPersistence(), "1", `{"email": "bob@example.com"}`)
+		if response.Code != 409 {
+			t.Errorf("Expected status code 409, got %d", response.Code)
+		}
+	})
+
+	t.Run("Invalid Payload", func(t *testing.T) {
+		for _, body := range []string{`{}`, `{"email": "invalid"}`, `{"name": ""}`, `not json`} {
+			response := update(newPersistence(), "1", body)
+			if response.Code != 400 {
+				t.Errorf("Expected status code 400 for %s, got %d", body, response.Code)
+			}
+		}
+	})
+
+	t.Run("Missing Contact", func(t *testing.T) {
+		response := update(newPersistence(), "3", `{"name": "Carol"}`)
+		if response.Code != 404 {
+			t.Errorf("Expected status code 404, got %d", response.Code)
+		}
+	})
+}
+
+func TestDeleteContactHandler(t *testing.T) {
+	persistence := &TestPersistence{
+		Contacts: map[string]Contact{
+			"1": {Id: "1", Name: "Alice", Email: "alice@example.com"},
+		},
+		ContactRequests: map[string][]ContactRequest{
+			"1": {{Id: "req1", ContactId: "1", Message: "Hello"}},
+		},
+	}
+
+	for _, expected := range []int{204, 404} {
+		writer := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(writer)
+		ctx.Request = httptest.NewRequest("DELETE", "/api/admin/contacts/1", nil)
+		ctx.Params = gin.Params{{Key: "id", Value: "1"}}
+
+		response := DeleteContactHandler(ctx, persistence)
+		if response.Code != expected {
+			t.Errorf("Expected status code %d, got %d", expected, response.Code)
+		}
+	}
+
+	if len(persistence.ContactRequests["1"]) != 0 {
+		t.Errorf("Expected contact requests to be deleted")
+	}
+}
+
+func TestListContactHistoryHandler(t *testing.T) {
+	persistence := &TestPersistence{
+		Contacts: map[string]Contact{
+			"1": {Id: "1", Name: "Alice", Email: "alice@example.com"},
+			"2": {Id: "2", Name: "Bob", Email: "bob@example.com"},
+		},
+		ContactRequests: map[string][]ContactRequest{
+			"1": {
+				{Id: "req1", ContactId: "1", Message: "Hello"},
+				{Id: "req2", ContactId: "1", Message: "Need help"},
+			},
+			"2": {
+				{Id: "req3", ContactId: "2", Message: "Inquiry"},
+			},
+		},
+	}
+
+	writer := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(writer)
+	ctx.Request = httptest.NewRequest("GET", "/api/admin/contacts/1/requests", nil)
+	ctx.Params = gin.Params{{Key: "id", Value: "1"}}
+
+	response := ListContactHistoryHandler(ctx, persistence)
+	if response.Code != 200 {
+		t.Fatalf("Expected status code 200, got %d", response.Code)
+	}
+
+	requests := response.Payload.(gin.H)["data"].([]ContactRequest)
+	if len(requests) != 2 {
+		t.Errorf("Expected 2 contact requests, got %d", len(requests))
+	}
+
+	ctx.Params = gin.Params{{Key: "id", Value: "3"}}
+	if response := ListContactHistoryHandler(ctx, persistence); response.Code != 404 {
+		t.Errorf("Expected status code 404, got %d", response.Code)
+	}
+}
+
+func TestListWebhooksHandler(t *testing.T) {
+	response := ListWebhooksHandler(nil, &TestPublisher{})
+	if response.Code != 200 {
+		t.Errorf("Expected status code 200, got %d", response.Code)
+	}
+
+	subscriptions, ok := response.Payload.(gin.H)["data"].([]WebhookSubscription)
+	if !ok || len(subscriptions) != 1 {
+		t.Fatalf("Expected 1 subscription, got %+v", response.Payload)
+	}
+
+	if subscriptions[0].Secret != "" {
+		t.Errorf("Expected secret to be omitted, got %s", subscriptions[0].Secret)
+	}
+}
+
+func TestListWebhookDeliveriesHandler(t *testing.T) {
+	persistence := &TestPersistence{
+		WebhookDeliveries: []WebhookDelivery{
+			{Id: "1", Subscription: "crm", EventType: EventContactCreated, Status: WebhookDeliveryDelivered, CreatedAt: time.Now()},
+			{Id: "2", Subscription: "crm", EventType: EventContactCreated, Status: WebhookDeliveryFailed, CreatedAt: time.Now()},
+		},
+	}
+
+	writer := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(writer)
+	ctx.Request = httptest.NewRequest("GET", "/admin/webhooks/deliveries?limit=1", nil)
+
+	response := ListWebhookDeliveriesHandler(ctx, persistence)
+	if response.Code != 200 {
+		t.Errorf("Expected status code 200, got %d", response.Code)
+	}
+
+	payload := response.Payload.(gin.H)
+	deliveries, ok := payload["data"].([]WebhookDelivery)
+	if !ok || len(deliveries) != 1 {
+		t.Errorf("Expected 1 delivery, got %+v", payload["data"])
+	}
+
+	if payload["next_cursor"] == nil {
+		t.Errorf("Expected next cursor, got nil")
+	}
+}
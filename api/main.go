@@ -1,18 +1,43 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 )
 
-// NewRouter creates a new Gin router with all routes and middleware configured
-// based on the provided configuration.
-func NewRouter(config *Config) *gin.Engine {
+// RouterDeps holds the services shared by the handlers and middlewares.
+type RouterDeps struct {
+	DB          Persistence
+	Logger      *RequestLogger
+	Notifier    Notifier
+	Events      EventPublisher
+	SpamFilter  *SpamFilter
+	ProofOfWork *ProofOfWork
+	Limiter     *RateLimiter
+	Resumes     *ResumeStore
+	Renderer    *ResumeRenderer
+	Sharer      *ResumeSharer
+}
+
+// NewRouter creates a Gin router with all routes and middleware configured.
+func NewRouter(config *Config, deps RouterDeps) *gin.Engine {
 	r := gin.Default()
 	r.Use(cors.Default())
+	// gin trusts X-Forwarded-For from any proxy by default,
+	// which lets clients choose the IP they are limited by
+	if err := r.SetTrustedProxies(config.TrustedProxies); err != nil {
+		log.Fatal(fmt.Sprintf("invalid trusted proxies: %v", err))
+	}
 
 	// GET /api/v1/public/version is used by k8s cluster
 	// liveness and readiness probes. do not log to db.
@@ -26,30 +51,22 @@ func NewRouter(config *Config) *gin.Engine {
 	// do not require authentication but are logged
 	// for tracing purposes
 	public := r.Group(fmt.Sprintf("/api/%s/public", config.APIVersion))
-	public.Use(RouteLoggingMiddleware(config, loggingExemptions))
+	public.Use(RouteLoggingMiddleware(deps.Logger, loggingExemptions))
+	// rejected requests are still logged
+	public.Use(RateLimitMiddleware(deps.Limiter, "public"))
 
 	// router group for private routes that require
 	// authentication
 	admin := r.Group(fmt.Sprintf("/api/%s/admin", config.APIVersion))
-	admin.Use(AdminAuthMiddleware(config))
-
-	pgDsn := PostgresDSNFromConfig(config)
+	// limits are applied before authentication
+	// to slow down attempts to guess API keys
+	admin.Use(RateLimitMiddleware(deps.Limiter, "admin"))
+	admin.Use(AdminAuthMiddleware(deps.DB, deps.Events))
 
 	// health check endpoint
 	public.GET("/health", func(c *gin.Context) {
-		// Create a new database connection
-		db, err := NewPGPersistence(pgDsn)
-		if err != nil {
-			log.Error(fmt.Sprintf("failed to connect to database: %v", err))
-			c.JSON(500, gin.H{
-				"error": "Internal server error",
-			})
-			return
-		}
-		defer db.Conn.Close()
-
 		log.Info("processing health check request")
-		response := HealthCheckHandler(c, db)
+		response := HealthCheckHandler(c, deps.DB)
 		response.Send(c)
 	})
 
@@ -61,84 +78,189 @@ func NewRouter(config *Config) *gin.Engine {
 	})
 
 	// GET /resume endpoint to return resume PDF
-	public.GET("/resume", func(c *gin.Context) {
+	// successful responses are recorded as downloads
+	public.GET("/resume", ResumeDownloadMiddleware(deps.Logger), func(c *gin.Context) {
 		log.Info("processing resume request")
-		// NOTE: /resume returns a file as attachment
-		// not a JSON RESTResponse
-		response := ResumeHandler(c, config)
+		// NOTE: /resume returns the PDF as an attachment
+		// instead of a JSON RESTResponse when downloaded
+		response := ResumeHandler(c, deps.Resumes, deps.Renderer)
+		response.Send(c)
+	})
+
+	// GET /resume/sections/:name endpoint to return
+	// a single section of the resume, e.g. work
+	public.GET("/resume/sections/:name", func(c *gin.Context) {
+		log.Info("processing resume section request")
+		response := ResumeSectionHandler(c, deps.Resumes)
+		response.Send(c)
+	})
+
+	// GET /resume/variants endpoint to list the
+	// variants and languages of the resume
+	public.GET("/resume/variants", func(c *gin.Context) {
+		log.Info("processing resume variants request")
+		response := ResumeVariantsHandler(c, deps.Resumes)
+		response.Send(c)
+	})
+
+	// GET /resume/shared/:token endpoint to serve
+	// the resume bound to a signed share link
+	public.GET("/resume/shared/:token", ResumeDownloadMiddleware(deps.Logger), ResumeShareMiddleware(deps.DB), func(c *gin.Context) {
+		log.Info("processing shared resume request")
+		response := SharedResumeHandler(c, deps.DB, deps.Resumes, deps.Sharer, deps.Renderer)
+		response.Send(c)
+	})
+
+	// GET /contacts/token endpoint to issue a form token
+	// that is submitted along with the contact form
+	public.GET("/contacts/token", func(c *gin.Context) {
+		log.Info("processing contact token request")
+		response := ContactTokenHandler(c, deps.SpamFilter)
+		response.Send(c)
+	})
+
+	// GET /challenge endpoint to issue a proof-of-work
+	// challenge that is solved before submitting the contact form
+	public.GET("/challenge", func(c *gin.Context) {
+		log.Info("processing challenge request")
+		response := ChallengeHandler(c, deps.ProofOfWork)
 		response.Send(c)
 	})
 
 	// POST /contacts endpoint to submit a new contact request
 	public.POST("/contacts", func(c *gin.Context) {
-		// Create a new database connection
-		db, err := NewPGPersistence(pgDsn)
-		if err != nil {
-			log.Error(fmt.Sprintf("failed to connect to database: %v", err))
-			c.JSON(500, gin.H{
-				"error": "Internal server error",
-			})
-			return
-		}
-		defer db.Conn.Close()
-
 		log.Info("processing contact request")
 
-		response := ContactHandler(c, db)
+		response := ContactHandler(c, deps.DB, deps.Notifier, deps.Events, deps.SpamFilter, deps.ProofOfWork)
 		response.Send(c)
 	})
 
 	// GET /stats endpoint to return site statistics
 	admin.GET("/stats", func(c *gin.Context) {
-		// Create a new database connection
-		db, err := NewPGPersistence(pgDsn)
-		if err != nil {
-			log.Error(fmt.Sprintf("failed to connect to database: %v", err))
-			c.JSON(500, gin.H{
-				"error": "Internal server error",
-			})
-			return
-		}
-		defer db.Conn.Close()
-
 		log.Info("processing stats request")
-		response := StatsHandler(c, db)
+		response := StatsHandler(c, deps.DB, deps.Logger)
+		response.Send(c)
+	})
+
+	// GET /stats/resume endpoint to return
+	// resume download statistics
+	admin.GET("/stats/resume", func(c *gin.Context) {
+		log.Info("processing resume stats request")
+		response := ResumeStatsHandler(c, deps.DB)
 		response.Send(c)
 	})
 
 	// GET /contacts endpoint to list all contacts
 	admin.GET("/contacts", func(c *gin.Context) {
-		// Create a new database connection
-		db, err := NewPGPersistence(pgDsn)
-		if err != nil {
-			log.Error(fmt.Sprintf("failed to connect to database: %v", err))
-			c.JSON(500, gin.H{
-				"error": "Internal server error",
-			})
-			return
-		}
-		defer db.Conn.Close()
-
 		log.Info("processing contacts request")
-		response := ListContactsHandler(c, db)
+		response := ListContactsHandler(c, deps.DB)
 		response.Send(c)
 	})
 
 	// GET /contacts/requests endpoint to list all contact requests
 	admin.GET("/contacts/requests", func(c *gin.Context) {
-		// Create a new database connection
-		db, err := NewPGPersistence(pgDsn)
-		if err != nil {
-			log.Error(fmt.Sprintf("failed to connect to database: %v", err))
-			c.JSON(500, gin.H{
-				"error": "Internal server error",
-			})
-			return
-		}
-		defer db.Conn.Close()
-
 		log.Info("processing contact requests")
-		response := ListContactRequestsHandler(c, db)
+		response := ListContactRequestsHandler(c, deps.DB)
+		response.Send(c)
+	})
+
+	// PATCH /contacts/requests/:id endpoint to update the
+	// triage status and/or notes of a contact request
+	admin.PATCH("/contacts/requests/:id", func(c *gin.Context) {
+		log.Info("processing update contact request status")
+		response := UpdateContactRequestHandler(c, deps.DB)
+		response.Send(c)
+	})
+
+	// GET /contacts/:id endpoint to get a single contact
+	admin.GET("/contacts/:id", func(c *gin.Context) {
+		log.Info("processing get contact request")
+		response := GetContactHandler(c, deps.DB)
+		response.Send(c)
+	})
+
+	// PATCH /contacts/:id endpoint to update a contact
+	admin.PATCH("/contacts/:id", func(c *gin.Context) {
+		log.Info("processing update contact request")
+		response := UpdateContactHandler(c, deps.DB)
+		response.Send(c)
+	})
+
+	// DELETE /contacts/:id endpoint to delete a contact
+	// and all of its contact requests
+	admin.DELETE("/contacts/:id", func(c *gin.Context) {
+		log.Info("processing delete contact request")
+		response := DeleteContactHandler(c, deps.DB)
+		response.Send(c)
+	})
+
+	// GET /contacts/:id/requests endpoint to list all
+	// contact requests submitted by a single contact
+	admin.GET("/contacts/:id/requests", func(c *gin.Context) {
+		log.Info("processing contact history request")
+		response := ListContactHistoryHandler(c, deps.DB)
+		response.Send(c)
+	})
+
+	// POST /resume/versions endpoint to upload a new
+	// version of the resume and make it active
+	admin.POST("/resume/versions", func(c *gin.Context) {
+		log.Info("processing upload resume version request")
+		response := UploadResumeVersionHandler(c, deps.DB, deps.Resumes, config.ResumeMaxUploadSize)
+		response.Send(c)
+	})
+
+	// GET /resume/versions endpoint to list resume versions
+	admin.GET("/resume/versions", func(c *gin.Context) {
+		log.Info("processing list resume versions request")
+		response := ListResumeVersionsHandler(c, deps.DB)
+		response.Send(c)
+	})
+
+	// GET /resume/versions/:id/diff endpoint to compare
+	// a JSON resume version with another version
+	admin.GET("/resume/versions/:id/diff", func(c *gin.Context) {
+		log.Info("processing diff resume versions request")
+		response := DiffResumeVersionsHandler(c, deps.DB)
+		response.Send(c)
+	})
+
+	// POST /resume/versions/:id/activate endpoint to make a
+	// version active, e.g. to roll back to a previous version
+	admin.POST("/resume/versions/:id/activate", func(c *gin.Context) {
+		log.Info("processing activate resume version request")
+		response := ActivateResumeVersionHandler(c, deps.DB, deps.Resumes)
+		response.Send(c)
+	})
+
+	// POST /resume/shares endpoint to create a
+	// signed, expiring link to the resume
+	admin.POST("/resume/shares", func(c *gin.Context) {
+		log.Info("processing create resume share request")
+		response := CreateResumeShareHandler(c, deps.DB, deps.Resumes, deps.Sharer)
+		response.Send(c)
+	})
+
+	// GET /resume/shares endpoint to list resume
+	// share links and how often they were opened
+	admin.GET("/resume/shares", func(c *gin.Context) {
+		log.Info("processing list resume shares request")
+		response := ListResumeSharesHandler(c, deps.DB, deps.Sharer)
+		response.Send(c)
+	})
+
+	// GET /webhooks endpoint to list webhook subscriptions
+	admin.GET("/webhooks", func(c *gin.Context) {
+		log.Info("processing list webhooks request")
+		response := ListWebhooksHandler(c, deps.Events)
+		response.Send(c)
+	})
+
+	// GET /webhooks/deliveries endpoint to list
+	// the webhook delivery log
+	admin.GET("/webhooks/deliveries", func(c *gin.Context) {
+		log.Info("processing list webhook deliveries request")
+		response := ListWebhookDeliveriesHandler(c, deps.DB)
 		response.Send(c)
 	})
 
@@ -150,9 +272,149 @@ func main() {
 	// set log level based on config settings
 	log.SetLevel(ParseLogLevel(config.LogLevel))
 
-	router := NewRouter(config)
+	// cancel the root context on SIGINT/SIGTERM
+	// so that the server can shut down gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// create a single persistence instance that is
+	// shared for the lifetime of the process
+	db, err := NewPersistence(ctx, config)
+	if err != nil {
+		log.Fatal(fmt.Sprintf("failed to initialize persistence: %v", err))
+	}
+	log.Info(fmt.Sprintf("using %s persistence backend", config.PersistenceBackend))
+
+	// run migrate subcommand instead of serving
+	// if requested, e.g. ./api migrate up
+	if len(os.Args) > 1 {
+		if os.Args[1] != "migrate" {
+			log.Fatal(fmt.Sprintf("unknown command %q", os.Args[1]))
+		}
+		err := RunMigrateCommand(ctx, db, os.Args[2:])
+		db.Close()
+		if err != nil {
+			log.Fatal(fmt.Sprintf("failed to run migrations: %v", err))
+		}
+		return
+	}
+
+	// replicas starting at the same time are serialized by the
+	// advisory lock held by the migrator. sqlite is only used for
+	// single-node deployments, so it is always migrated on startup
+	autoMigrate := config.AutoMigrate && config.PersistenceBackend == PersistenceBackendPostgres
+	if autoMigrate || config.PersistenceBackend == PersistenceBackendSQLite {
+		migrator, err := NewMigrator(db)
+		if err != nil {
+			log.Fatal(fmt.Sprintf("failed to create migrator: %v", err))
+		}
+		count, err := migrator.Up(ctx)
+		migrator.Close()
+		if err != nil {
+			log.Fatal(fmt.Sprintf("failed to apply migrations: %v", err))
+		}
+		log.Info(fmt.Sprintf("applied %d pending migration(s)", count))
+	}
+
+	logger := NewRequestLogger(config, db)
+
+	notifier, err := NewNotifier(config)
+	if err != nil {
+		log.Fatal(fmt.Sprintf("failed to initialize notifier: %v", err))
+	}
+
+	events, err := NewEventPublisher(config, db)
+	if err != nil {
+		log.Fatal(fmt.Sprintf("failed to initialize webhooks: %v", err))
+	}
+
+	filter, err := NewSpamFilter(config)
+	if err != nil {
+		log.Fatal(fmt.Sprintf("failed to initialize spam filter: %v", err))
+	}
+
+	pow, err := NewProofOfWork(config)
+	if err != nil {
+		log.Fatal(fmt.Sprintf("failed to initialize proof-of-work challenges: %v", err))
+	}
+
+	limiter, err := NewRateLimiter(config, db)
+	if err != nil {
+		log.Fatal(fmt.Sprintf("failed to initialize rate limiter: %v", err))
+	}
+
+	// fail fast rather than serving errors for an invalid resume
+	resumes, err := NewResumeStore(config, db)
+	if err != nil {
+		log.Fatal(fmt.Sprintf("failed to load resume: %v", err))
+	}
+
+	renderer, err := LoadResumeTemplates(config.ResumeTemplateDir)
+	if err != nil {
+		log.Fatal(fmt.Sprintf("failed to load resume templates: %v", err))
+	}
+	if renderer.Theme, err = NewResumePDFTheme(config); err != nil {
+		log.Fatal(fmt.Sprintf("invalid resume PDF theme: %v", err))
+	}
+
+	sharer, err := NewResumeSharer(config)
+	if err != nil {
+		log.Fatal(fmt.Sprintf("failed to initialize resume share links: %v", err))
+	}
+
+	router := NewRouter(config, RouterDeps{
+		DB:          db,
+		Logger:      logger,
+		Notifier:    notifier,
+		Events:      events,
+		SpamFilter:  filter,
+		ProofOfWork: pow,
+		Limiter:     limiter,
+		Resumes:     resumes,
+		Renderer:    renderer,
+		Sharer:      sharer,
+	})
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", config.Port),
+		Handler: router,
+	}
+
 	// start server and listen on configured port
-	if err := router.Run(fmt.Sprintf(":%d", config.Port)); err != nil {
-		log.Fatal(fmt.Sprintf("failed to start server: %v", err))
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(fmt.Sprintf("failed to start server: %v", err))
+		}
+	}()
+
+	<-ctx.Done()
+	log.Info("shutting down server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Error(fmt.Sprintf("failed to shut down server: %v", err))
+	}
+	// write requests logged by in-flight requests
+	if err := logger.Close(shutdownCtx); err != nil {
+		log.Error(fmt.Sprintf("failed to write pending request logs: %v", err))
+	}
+	// send notifications queued by in-flight requests
+	// before shutting down
+	if err := notifier.Close(shutdownCtx); err != nil {
+		log.Error(fmt.Sprintf("failed to send pending notifications: %v", err))
+	}
+	// undelivered webhook events remain queued and
+	// are delivered once the API is restarted
+	if err := events.Close(shutdownCtx); err != nil {
+		log.Error(fmt.Sprintf("failed to stop webhook dispatcher: %v", err))
+	}
+	if err := resumes.Close(); err != nil {
+		log.Error(fmt.Sprintf("failed to stop watching resume files: %v", err))
+	}
+	// close persistence only once all in-flight
+	// requests have completed
+	if err := db.Close(); err != nil {
+		log.Error(fmt.Sprintf("failed to close persistence: %v", err))
 	}
 }
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestRouterDeps returns the dependencies of a
+// router backed by the given persistence.
+func newTestRouterDeps(t *testing.T, config *Config, persistence Persistence) RouterDeps {
+	t.Helper()
+
+	return RouterDeps{
+		DB:          persistence,
+		Logger:      newTestRequestLogger(t, persistence),
+		Notifier:    NoopNotifier{},
+		Events:      NoopPublisher{},
+		SpamFilter:  newTestSpamFilter(),
+		ProofOfWork: newTestProofOfWork(),
+		Limiter:     &RateLimiter{},
+		Resumes:     newTestResumeStore(t, config),
+		Renderer:    newTestResumeRenderer(t),
+		Sharer:      newTestResumeSharer(),
+	}
+}
+
+func TestNewRouter(t *testing.T) {
+	config := &Config{
+		APIVersion:     "v1",
+		ResumePathPDF:  "etc/resume.pdf",
+		ResumePathJSON: "etc/resume.json",
+	}
+	persistence := &TestPersistence{
+		Healthy: true,
+		Contacts: map[string]Contact{
+			"1": {Id: "1", Name: "Alice", Email: "alice@example.com"},
+		},
+		ContactRequests: make(map[string][]ContactRequest),
+		APIKeys: map[string]APIKey{
+			"key": {Key: "key", Owner: "admin", ExpiresAt: time.Now().Add(time.Hour)},
+		},
+	}
+
+	deps := newTestRouterDeps(t, config, persistence)
+	router := NewRouter(config, deps)
+
+	cases := []struct {
+		method   string
+		path     string
+		expected int
+	}{
+		{"GET", "/api/v1/public/version", 200},
+		{"GET", "/api/v1/public/health", 200},
+		{"GET", "/api/v1/public/resume/sections/work", 200},
+		{"GET", "/api/v1/public/resume/sections/unknown", 404},
+		{"GET", "/api/v1/public/resume/variants", 200},
+		{"GET", "/api/v1/public/resume?variant=unknown", 404},
+		{"GET", "/api/v1/public/resume/shared/invalid", 404},
+		{"GET", "/api/v1/public/contacts/token", 200},
+		{"GET", "/api/v1/public/challenge", 200},
+		{"POST", "/api/v1/public/contacts", 400},
+		{"GET", "/api/v1/admin/contacts", 200},
+		{"GET", "/api/v1/admin/contacts/requests", 200},
+		{"GET", "/api/v1/admin/contacts/1", 200},
+		{"GET", "/api/v1/admin/contacts/1/requests", 200},
+		{"GET", "/api/v1/admin/contacts/2", 404},
+		{"DELETE", "/api/v1/admin/contacts/1", 204},
+		{"GET", "/api/v1/admin/resume/versions", 200},
+		{"POST", "/api/v1/admin/resume/versions", 415},
+		{"GET", "/api/v1/admin/resume/versions/missing/diff", 400},
+		{"POST", "/api/v1/admin/resume/versions/missing/activate", 404},
+		{"GET", "/api/v1/admin/resume/shares", 200},
+		{"GET", "/api/v1/admin/stats/resume", 200},
+		{"POST", "/api/v1/admin/resume/shares", 400},
+	}
+
+	for _, tc := range cases {
+		writer := httptest.NewRecorder()
+		request := httptest.NewRequest(tc.method, tc.path, nil)
+		request.Header.Set("X-API-Key", "key")
+
+		router.ServeHTTP(writer, request)
+		if writer.Code != tc.expected {
+			t.Errorf("Expected status code %d for %s %s, got %d", tc.expected, tc.method, tc.path, writer.Code)
+		}
+	}
+
+	// only the successful resume request is recorded as a download.
+	// downloads are written in the background once the logger is closed
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v1/public/resume?format=json", nil))
+	if err := deps.Logger.Close(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(persistence.ResumeDownloads) != 1 || persistence.ResumeDownloads[0].Format != ResumeFormatJSON {
+		t.Errorf("Expected JSON resume download, got %+v", persistence.ResumeDownloads)
+	}
+}
+
+func TestNewRouterTrustedProxies(t *testing.T) {
+	send := func(router http.Handler, forwardedFor string) int {
+		writer := httptest.NewRecorder()
+		request := httptest.NewRequest("GET", "/api/v1/public/version", nil)
+		request.RemoteAddr = "10.0.0.1:1234"
+		request.Header.Set("X-Forwarded-For", forwardedFor)
+		router.ServeHTTP(writer, request)
+		return writer.Code
+	}
+	newRouter := func(trustedProxies []string) http.Handler {
+		config := &Config{
+			APIVersion:     "v1",
+			ResumePathJSON: "etc/resume.json",
+			TrustedProxies: trustedProxies,
+		}
+		deps := newTestRouterDeps(t, config, &TestPersistence{})
+		deps.Limiter = &RateLimiter{
+			Store: NewMemoryRateLimitStore(),
+			Rules: []RateLimitRule{{Group: "public", Requests: 1, Period: time.Minute}},
+		}
+		return NewRouter(config, deps)
+	}
+
+	t.Run("Spoofed Header Is Ignored", func(t *testing.T) {
+		router := newRouter(nil)
+		if code := send(router, "1.1.1.1"); code != 200 {
+			t.Fatalf("Expected status code 200, got %d", code)
+		}
+		// a new forwarded address does not reset the bucket
+		if code := send(router, "2.2.2.2"); code != 429 {
+			t.Errorf("Expected status code 429, got %d", code)
+		}
+	})
+
+	t.Run("Trusted Proxy", func(t *testing.T) {
+		router := newRouter([]string{"10.0.0.0/8"})
+		if code := send(router, "1.1.1.1"); code != 200 {
+			t.Fatalf("Expected status code 200, got %d", code)
+		}
+		if code := send(router, "2.2.2.2"); code != 200 {
+			t.Errorf("Expected status code 200 for another client, got %d", code)
+		}
+		if code := send(router, "1.1.1.1"); code != 429 {
+			t.Errorf("Expected status code 429, got %d", code)
+		}
+	})
+}
@@ -0,0 +1,902 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// MemorySnapshot is the on-disk representation of the in-memory
+// persistence backend. API keys can be seeded by adding them to
+// the snapshot file before startup.
+type MemorySnapshot struct {
+	Contacts          []Contact         `json:"contacts"`
+	ContactRequests   []ContactRequest  `json:"contact_requests"`
+	LoggedRequests    []LoggedRequest   `json:"logged_requests"`
+	LoggedResponses   []LoggedResponse  `json:"logged_responses"`
+	APIKeys           []APIKey          `json:"api_keys"`
+	WebhookDeliveries []WebhookDelivery `json:"webhook_deliveries"`
+	ResumeVersions    []ResumeVersion   `json:"resume_versions"`
+	ResumeShares      []ResumeShare     `json:"resume_shares"`
+	ResumeDownloads   []ResumeDownload  `json:"resume_downloads"`
+}
+
+// MemoryPersistence is a concurrency-safe Persistence implementation
+// that keeps all data in memory. Data is optionally restored from and
+// written to a JSON snapshot file.
+type MemoryPersistence struct {
+	mu                sync.RWMutex
+	contacts          map[string]Contact
+	contactRequests   []ContactRequest
+	loggedRequests    []LoggedRequest
+	loggedResponses   []LoggedResponse
+	apiKeys           map[string]APIKey
+	webhookDeliveries []WebhookDelivery
+	resumeVersions    []ResumeVersion
+	resumeShares      []ResumeShare
+	resumeDownloads   []ResumeDownload
+	// redeemed challenges are not included in snapshots,
+	// as they expire shortly after being issued
+	redeemedChallenges map[string]time.Time
+	snapshotPath       string
+}
+
+// newMemoryID generates a new ID in the same format
+// as the IDs generated by the postgres backend.
+func newMemoryID() string {
+	return strings.ReplaceAll(uuid.New().String(), "-", "")
+}
+
+// sortContacts sorts contacts in order of creation so
+// that listings are stable across calls.
+func sortContacts(contacts []Contact) {
+	slices.SortFunc(contacts, func(a, b Contact) int {
+		return compareListCursors(contactCursor(a), contactCursor(b))
+	})
+}
+
+// compareListCursors orders cursors by creation
+// time, using the ID to break ties.
+func compareListCursors(a, b ListCursor) int {
+	if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Id, b.Id)
+}
+
+// filterListQuery applies the filters, cursor and ordering of the given
+// query to items held in memory, returning at most one item more than
+// the page size to match the behaviour of buildListQuery.
+func filterListQuery[T any](items []T, query ListQuery, key func(T) (ListCursor, string, string)) []T {
+	var filtered []T
+	for _, item := range items {
+		cursor, email, contactId := key(item)
+		if query.Email != "" && email != query.Email {
+			continue
+		}
+		if query.ContactId != "" && contactId != query.ContactId {
+			continue
+		}
+		if query.Since != nil && cursor.CreatedAt.Before(*query.Since) {
+			continue
+		}
+		if query.Until != nil && !cursor.CreatedAt.Before(*query.Until) {
+			continue
+		}
+		if query.Cursor != nil {
+			c := compareListCursors(cursor, *query.Cursor)
+			if (!query.Descending && c <= 0) || (query.Descending && c >= 0) {
+				continue
+			}
+		}
+		filtered = append(filtered, item)
+	}
+
+	slices.SortFunc(filtered, func(a, b T) int {
+		cursorA, _, _ := key(a)
+		cursorB, _, _ := key(b)
+		if query.Descending {
+			return compareListCursors(cursorB, cursorA)
+		}
+		return compareListCursors(cursorA, cursorB)
+	})
+
+	if len(filtered) > query.Limit+1 {
+		filtered = filtered[:query.Limit+1]
+	}
+	return filtered
+}
+
+func (db *MemoryPersistence) HealthCheck(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (db *MemoryPersistence) GetContact(ctx context.Context, email string) (*Contact, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	for _, contact := range db.contacts {
+		if contact.Email == email {
+			return &contact, nil
+		}
+	}
+	return nil, ContactNotFoundError{Email: email}
+}
+
+// GetContactById retrieves a single contact by ID
+func (db *MemoryPersistence) GetContactById(ctx context.Context, id string) (*Contact, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	contact, exists := db.contacts[id]
+	if !exists {
+		return nil, ContactNotFoundError{Id: id}
+	}
+	return &contact, nil
+}
+
+// UpdateContact updates the name and/or email of a contact
+// and returns the updated contact
+func (db *MemoryPersistence) UpdateContact(ctx context.Context, id string, update ContactUpdate) (*Contact, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	contact, exists := db.contacts[id]
+	if !exists {
+		return nil, ContactNotFoundError{Id: id}
+	}
+
+	if update.Email != nil {
+		for _, existing := range db.contacts {
+			if existing.Id != id && existing.Email == *update.Email {
+				return nil, ContactConflictError{Email: *update.Email}
+			}
+		}
+		contact.Email = *update.Email
+	}
+	if update.Name != nil {
+		contact.Name = *update.Name
+	}
+
+	db.contacts[id] = contact
+	return &contact, nil
+}
+
+// DeleteContact deletes a contact along with all of its contact requests
+func (db *MemoryPersistence) DeleteContact(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, exists := db.contacts[id]; !exists {
+		return ContactNotFoundError{Id: id}
+	}
+	delete(db.contacts, id)
+
+	db.contactRequests = slices.DeleteFunc(db.contactRequests, func(request ContactRequest) bool {
+		return request.ContactId == id
+	})
+	return nil
+}
+
+// CreateContact stores a new contact in memory
+func (db *MemoryPersistence) CreateContact(ctx context.Context, contact Contact) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	// emails are unique in the postgres
+	// schema, so enforce the same here
+	for _, existing := range db.contacts {
+		if existing.Email == contact.Email {
+			return "", errors.New("contact already exists with email " + contact.Email)
+		}
+	}
+
+	contact.Id = newMemoryID()
+	contact.CreatedAt = time.Now()
+	db.contacts[contact.Id] = contact
+	return contact.Id, nil
+}
+
+// ListContacts retrieves all contacts in order of creation
+func (db *MemoryPersistence) ListContacts(ctx context.Context) ([]Contact, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var contacts []Contact
+	for _, contact := range db.contacts {
+		contacts = append(contacts, contact)
+	}
+	sortContacts(contacts)
+	return contacts, nil
+}
+
+// QueryContacts retrieves a page of contacts matching the given query
+func (db *MemoryPersistence) QueryContacts(ctx context.Context, query ListQuery) ([]Contact, *ListCursor, error) {
+	contacts, err := db.ListContacts(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	contacts = filterListQuery(contacts, query, func(contact Contact) (ListCursor, string, string) {
+		return contactCursor(contact), contact.Email, contact.Id
+	})
+	contacts, next := nextPage(contacts, query.Limit, contactCursor)
+	return contacts, next, nil
+}
+
+// CreateContactRequest stores a new contact request in memory
+func (db *MemoryPersistence) CreateContactRequest(ctx context.Context, entry ContactRequest) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, exists := db.contacts[entry.ContactId]; !exists {
+		return "", errors.New("contact does not exist with id " + entry.ContactId)
+	}
+
+	entry.Id = newMemoryID()
+	entry.CreatedAt = time.Now()
+	if entry.Status == "" {
+		entry.Status = ContactRequestStatusNew
+	}
+	db.contactRequests = append(db.contactRequests, entry)
+	return entry.Id, nil
+}
+
+// ListContactRequests retrieves all contact requests along
+// with the email of the contact that submitted them
+func (db *MemoryPersistence) ListContactRequests(ctx context.Context) ([]ContactRequest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var requests []ContactRequest
+	for _, request := range db.contactRequests {
+		contact, exists := db.contacts[request.ContactId]
+		if !exists {
+			continue
+		}
+		request.Email = contact.Email
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
+
+// QueryContactRequests retrieves a page of contact requests matching the given query
+func (db *MemoryPersistence) QueryContactRequests(ctx context.Context, query ListQuery) ([]ContactRequest, *ListCursor, error) {
+	requests, err := db.ListContactRequests(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if query.Status != "" {
+		requests = slices.DeleteFunc(requests, func(request ContactRequest) bool {
+			return request.Status != query.Status
+		})
+	}
+	requests = filterListQuery(requests, query, func(request ContactRequest) (ListCursor, string, string) {
+		return contactRequestCursor(request), request.Email, request.ContactId
+	})
+	requests, next := nextPage(requests, query.Limit, contactRequestCursor)
+	return requests, next, nil
+}
+
+// UpdateContactRequest updates the status and/or notes
+// of a contact request and returns the updated request
+func (db *MemoryPersistence) UpdateContactRequest(ctx context.Context, id string, update ContactRequestUpdate) (*ContactRequest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	index := slices.IndexFunc(db.contactRequests, func(request ContactRequest) bool {
+		return request.Id == id
+	})
+	if index < 0 {
+		return nil, ContactRequestNotFoundError{Id: id}
+	}
+
+	request := db.contactRequests[index]
+	if update.Status != nil {
+		if !request.Status.CanTransitionTo(*update.Status) {
+			return nil, InvalidStatusTransitionError{From: request.Status, To: *update.Status}
+		}
+		request.Status = *update.Status
+	}
+	if update.Notes != nil {
+		request.Notes = *update.Notes
+	}
+	db.contactRequests[index] = request
+
+	if contact, exists := db.contacts[request.ContactId]; exists {
+		request.Email = contact.Email
+	}
+	return &request, nil
+}
+
+// CreateWebhookDeliveries queues the given deliveries in memory
+func (db *MemoryPersistence) CreateWebhookDeliveries(ctx context.Context, deliveries []WebhookDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now()
+	for _, delivery := range deliveries {
+		delivery.Id = newMemoryID()
+		delivery.Status = WebhookDeliveryPending
+		delivery.NextAttemptAt = now
+		delivery.CreatedAt = now
+		db.webhookDeliveries = append(db.webhookDeliveries, delivery)
+	}
+	return nil
+}
+
+// ClaimWebhookDeliveries claims up to limit pending deliveries that are
+// due, and pushes back their next attempt by the lease so that they are
+// not claimed again while being delivered
+func (db *MemoryPersistence) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var due []int
+	now := time.Now()
+	for i, delivery := range db.webhookDeliveries {
+		if delivery.Status == WebhookDeliveryPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, i)
+		}
+	}
+	slices.SortStableFunc(due, func(a, b int) int {
+		return db.webhookDeliveries[a].NextAttemptAt.Compare(db.webhookDeliveries[b].NextAttemptAt)
+	})
+
+	var deliveries []WebhookDelivery
+	for _, i := range due[:min(limit, len(due))] {
+		db.webhookDeliveries[i].NextAttemptAt = now.Add(lease)
+		deliveries = append(deliveries, db.webhookDeliveries[i])
+	}
+	return deliveries, nil
+}
+
+// UpdateWebhookDelivery records the outcome of a delivery attempt
+func (db *MemoryPersistence) UpdateWebhookDelivery(ctx context.Context, delivery WebhookDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i, existing := range db.webhookDeliveries {
+		if existing.Id == delivery.Id {
+			db.webhookDeliveries[i] = delivery
+			return nil
+		}
+	}
+	return nil
+}
+
+// QueryWebhookDeliveries retrieves a page of webhook deliveries matching the given query
+func (db *MemoryPersistence) QueryWebhookDeliveries(ctx context.Context, query ListQuery) ([]WebhookDelivery, *ListCursor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	db.mu.RLock()
+	deliveries := append([]WebhookDelivery{}, db.webhookDeliveries...)
+	db.mu.RUnlock()
+
+	// deliveries have no email or contact, so those
+	// filters are ignored as in the SQL backends
+	query.Email, query.ContactId = "", ""
+	deliveries = filterListQuery(deliveries, query, func(delivery WebhookDelivery) (ListCursor, string, string) {
+		return webhookDeliveryCursor(delivery), "", ""
+	})
+	deliveries, next := nextPage(deliveries, query.Limit, webhookDeliveryCursor)
+	return deliveries, next, nil
+}
+
+// RedeemChallenge marks a solved challenge as used, returning a
+// ChallengeUsedError if it has already been redeemed. Expired
+// challenges are removed, as they can no longer be verified
+func (db *MemoryPersistence) RedeemChallenge(ctx context.Context, id string, expiresAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now()
+	for redeemed, expiry := range db.redeemedChallenges {
+		if expiry.Before(now) {
+			delete(db.redeemedChallenges, redeemed)
+		}
+	}
+
+	if _, ok := db.redeemedChallenges[id]; ok {
+		return ChallengeUsedError{Id: id}
+	}
+	db.redeemedChallenges[id] = expiresAt
+	return nil
+}
+
+// CreateResumeVersion stores a new version of a resume and makes it
+// the active version of its variant, locale and format
+func (db *MemoryPersistence) CreateResumeVersion(ctx context.Context, version ResumeVersion) (*ResumeVersion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	version.Id = newMemoryID()
+	version.Size = len(version.Content)
+	version.Active = true
+	version.CreatedAt = time.Now()
+	version.ActivatedAt = version.CreatedAt
+	db.deactivateResumeVersions(version)
+	db.resumeVersions = append(db.resumeVersions, version)
+
+	created := version.withoutContent()
+	return &created, nil
+}
+
+// deactivateResumeVersions deactivates all versions with the same
+// variant, locale and format as the given version. The caller must
+// hold the write lock
+func (db *MemoryPersistence) deactivateResumeVersions(version ResumeVersion) {
+	for i, existing := range db.resumeVersions {
+		if existing.Variant == version.Variant && existing.Locale == version.Locale && existing.Format == version.Format {
+			db.resumeVersions[i].Active = false
+		}
+	}
+}
+
+// ListResumeVersions lists all versions of the resume without
+// their content, most recent first
+func (db *MemoryPersistence) ListResumeVersions(ctx context.Context) ([]ResumeVersion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	versions := make([]ResumeVersion, 0, len(db.resumeVersions))
+	for _, version := range slices.Backward(db.resumeVersions) {
+		versions = append(versions, version.withoutContent())
+	}
+	return versions, nil
+}
+
+// GetResumeVersion retrieves a single version of the resume
+// along with its content
+func (db *MemoryPersistence) GetResumeVersion(ctx context.Context, id string) (*ResumeVersion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	for _, version := range db.resumeVersions {
+		if version.Id == id {
+			return &version, nil
+		}
+	}
+	return nil, ResumeVersionNotFoundError{Id: id}
+}
+
+// ActivateResumeVersion makes the given version the active version
+// of its variant, locale and format, e.g. to roll back a change
+func (db *MemoryPersistence) ActivateResumeVersion(ctx context.Context, id string) (*ResumeVersion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	index := slices.IndexFunc(db.resumeVersions, func(version ResumeVersion) bool { return version.Id == id })
+	if index < 0 {
+		return nil, ResumeVersionNotFoundError{Id: id}
+	}
+	db.deactivateResumeVersions(db.resumeVersions[index])
+	db.resumeVersions[index].Active = true
+	db.resumeVersions[index].ActivatedAt = time.Now()
+
+	activated := db.resumeVersions[index].withoutContent()
+	return &activated, nil
+}
+
+// ListActiveResumeVersions lists the active version of each
+// variant, locale and format along with its content
+func (db *MemoryPersistence) ListActiveResumeVersions(ctx context.Context) ([]ResumeVersion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var versions []ResumeVersion
+	for _, version := range db.resumeVersions {
+		if version.Active {
+			versions = append(versions, version)
+		}
+	}
+	return versions, nil
+}
+
+// CreateResumeShare stores a new link to the resume
+func (db *MemoryPersistence) CreateResumeShare(ctx context.Context, share ResumeShare) (*ResumeShare, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	share.Id = newMemoryID()
+	share.CreatedAt = time.Now()
+	share.Opens = 0
+	share.FirstOpenedAt = nil
+	share.LastOpenedAt = nil
+	share.Token = ""
+	db.resumeShares = append(db.resumeShares, share)
+	return &share, nil
+}
+
+// ListResumeShares lists all links to the resume, most recent first
+func (db *MemoryPersistence) ListResumeShares(ctx context.Context) ([]ResumeShare, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	shares := make([]ResumeShare, 0, len(db.resumeShares))
+	for _, share := range slices.Backward(db.resumeShares) {
+		shares = append(shares, share)
+	}
+	return shares, nil
+}
+
+// GetResumeShare retrieves a link to the resume by id
+func (db *MemoryPersistence) GetResumeShare(ctx context.Context, id string) (*ResumeShare, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	index := slices.IndexFunc(db.resumeShares, func(share ResumeShare) bool { return share.Id == id })
+	if index < 0 {
+		return nil, ResumeShareNotFoundError{Id: id}
+	}
+	share := db.resumeShares[index]
+	return &share, nil
+}
+
+// OpenResumeShare records that a link to the resume was opened. Single
+// use links that have already been opened return a ResumeShareUsedError
+func (db *MemoryPersistence) OpenResumeShare(ctx context.Context, id string) (*ResumeShare, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	index := slices.IndexFunc(db.resumeShares, func(share ResumeShare) bool { return share.Id == id })
+	if index < 0 {
+		return nil, ResumeShareNotFoundError{Id: id}
+	}
+	share := &db.resumeShares[index]
+	if share.SingleUse && share.Opens > 0 {
+		return nil, ResumeShareUsedError{Id: id}
+	}
+
+	now := time.Now()
+	share.Opens++
+	if share.FirstOpenedAt == nil {
+		share.FirstOpenedAt = &now
+	}
+	share.LastOpenedAt = &now
+
+	opened := *share
+	return &opened, nil
+}
+
+// LogRequests logs a batch of requests and their responses in memory
+func (db *MemoryPersistence) LogRequests(ctx context.Context, requests []LoggedRequest, responses []LoggedResponse) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.loggedRequests = append(db.loggedRequests, requests...)
+	db.loggedResponses = append(db.loggedResponses, responses...)
+	return nil
+}
+
+// GetRequestStats computes aggregated request statistics
+func (db *MemoryPersistence) GetRequestStats(ctx context.Context) (*RequestStats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	stats := RequestStats{
+		TotalRequests: len(db.loggedRequests),
+		PathCounts:    make(map[string]int),
+		StatusCounts:  make(map[int]int),
+	}
+
+	ips := make(map[string]struct{})
+	for _, request := range db.loggedRequests {
+		ips[request.IPAddress] = struct{}{}
+		stats.PathCounts[request.Path]++
+	}
+	stats.UniqueIPCount = len(ips)
+
+	for _, response := range db.loggedResponses {
+		stats.StatusCounts[response.Status]++
+	}
+	return &stats, nil
+}
+
+// RecordResumeDownload records a successful fetch of the resume in memory
+func (db *MemoryPersistence) RecordResumeDownload(ctx context.Context, download ResumeDownload) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.resumeDownloads = append(db.resumeDownloads, download)
+	return nil
+}
+
+// GetResumeStats aggregates the resume downloads in the given period,
+// grouping downloads by UTC day
+func (db *MemoryPersistence) GetResumeStats(ctx context.Context, query ResumeStatsQuery) (*ResumeStats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	since, until := query.Since.UTC(), query.Until.UTC()
+	stats := ResumeStats{
+		Since:        since,
+		Until:        until,
+		Daily:        []ResumeDailyDownloads{},
+		TopReferrers: []ReferrerCount{},
+	}
+
+	var downloads []ResumeDownload
+	for _, download := range db.resumeDownloads {
+		if !download.DownloadedAt.Before(since) && download.DownloadedAt.Before(until) {
+			downloads = append(downloads, download)
+		}
+	}
+	slices.SortStableFunc(downloads, func(a, b ResumeDownload) int {
+		return a.DownloadedAt.Compare(b.DownloadedAt)
+	})
+
+	ips := make(map[string]struct{})
+	referrers := make(map[string]int)
+	for _, download := range downloads {
+		ips[download.IPAddress] = struct{}{}
+		if download.Referrer != "" {
+			referrers[download.Referrer]++
+		}
+		date := download.DownloadedAt.UTC().Format(time.DateOnly)
+		stats.Daily = addDailyDownloads(stats.Daily, date, download.Format, 1)
+	}
+	stats.TotalDownloads = len(downloads)
+	stats.UniqueDownloaders = len(ips)
+
+	for referrer, count := range referrers {
+		stats.TopReferrers = append(stats.TopReferrers, ReferrerCount{Referrer: referrer, Count: count})
+	}
+	slices.SortFunc(stats.TopReferrers, func(a, b ReferrerCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Referrer, b.Referrer))
+	})
+	if len(stats.TopReferrers) > query.Referrers {
+		stats.TopReferrers = stats.TopReferrers[:query.Referrers]
+	}
+	return &stats, nil
+}
+
+// GetAPIKey retrieves an API key from memory
+func (db *MemoryPersistence) GetAPIKey(ctx context.Context, key string) (*APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	apiKey, exists := db.apiKeys[key]
+	if !exists {
+		return nil, APIKeyNotFoundError{Key: key}
+	}
+	return &apiKey, nil
+}
+
+// Snapshot returns a copy of all data currently held in memory.
+func (db *MemoryPersistence) Snapshot() MemorySnapshot {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	snapshot := MemorySnapshot{
+		ContactRequests:   append([]ContactRequest{}, db.contactRequests...),
+		LoggedRequests:    append([]LoggedRequest{}, db.loggedRequests...),
+		LoggedResponses:   append([]LoggedResponse{}, db.loggedResponses...),
+		WebhookDeliveries: append([]WebhookDelivery{}, db.webhookDeliveries...),
+		ResumeVersions:    append([]ResumeVersion{}, db.resumeVersions...),
+		ResumeShares:      append([]ResumeShare{}, db.resumeShares...),
+		ResumeDownloads:   append([]ResumeDownload{}, db.resumeDownloads...),
+	}
+	for _, contact := range db.contacts {
+		snapshot.Contacts = append(snapshot.Contacts, contact)
+	}
+	sortContacts(snapshot.Contacts)
+
+	for _, apiKey := range db.apiKeys {
+		snapshot.APIKeys = append(snapshot.APIKeys, apiKey)
+	}
+	return snapshot
+}
+
+// Restore replaces all data held in memory with the given snapshot.
+func (db *MemoryPersistence) Restore(snapshot MemorySnapshot) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.contacts = make(map[string]Contact)
+	for _, contact := range snapshot.Contacts {
+		db.contacts[contact.Id] = contact
+	}
+	db.apiKeys = make(map[string]APIKey)
+	for _, apiKey := range snapshot.APIKeys {
+		db.apiKeys[apiKey.Key] = apiKey
+	}
+	db.contactRequests = append([]ContactRequest{}, snapshot.ContactRequests...)
+	// snapshots written before contact requests had
+	// a status are treated as not yet triaged
+	for i := range db.contactRequests {
+		if db.contactRequests[i].Status == "" {
+			db.contactRequests[i].Status = ContactRequestStatusNew
+		}
+	}
+	db.loggedRequests = append([]LoggedRequest{}, snapshot.LoggedRequests...)
+	db.loggedResponses = append([]LoggedResponse{}, snapshot.LoggedResponses...)
+	db.webhookDeliveries = append([]WebhookDelivery{}, snapshot.WebhookDeliveries...)
+	db.resumeVersions = append([]ResumeVersion{}, snapshot.ResumeVersions...)
+	db.resumeShares = append([]ResumeShare{}, snapshot.ResumeShares...)
+	db.resumeDownloads = append([]ResumeDownload{}, snapshot.ResumeDownloads...)
+}
+
+// Close writes a snapshot to disk if a snapshot path is configured.
+// The snapshot is written to a temporary file first and renamed so
+// that a crash during shutdown does not corrupt an existing snapshot.
+func (db *MemoryPersistence) Close() error {
+	if db.snapshotPath == "" {
+		return nil
+	}
+
+	encoded, err := json.MarshalIndent(db.Snapshot(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(db.snapshotPath), ".snapshot-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(encoded); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	log.Info("writing in-memory snapshot to " + db.snapshotPath)
+	return os.Rename(tmp.Name(), db.snapshotPath)
+}
+
+// NewMemoryPersistence creates a new MemoryPersistence. If a snapshot
+// path is given and the file exists, data is restored from the file.
+func NewMemoryPersistence(snapshotPath string) (*MemoryPersistence, error) {
+	db := &MemoryPersistence{
+		contacts:           make(map[string]Contact),
+		apiKeys:            make(map[string]APIKey),
+		redeemedChallenges: make(map[string]time.Time),
+		snapshotPath:       snapshotPath,
+	}
+	if snapshotPath == "" {
+		return db, nil
+	}
+
+	contents, err := os.ReadFile(snapshotPath)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Info("no in-memory snapshot found at " + snapshotPath)
+		return db, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot MemorySnapshot
+	if err := json.Unmarshal(contents, &snapshot); err != nil {
+		return nil, err
+	}
+	db.Restore(snapshot)
+
+	log.Info("restored in-memory snapshot from " + snapshotPath)
+	return db, nil
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryPersistence(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Create and Get Contact", func(t *testing.T) {
+		db, _ := NewMemoryPersistence("")
+
+		id, err := db.CreateContact(ctx, Contact{Name: "Alice", Email: "alice@example.com"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		contact, err := db.GetContact(ctx, "alice@example.com")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if contact.Id != id {
+			t.Errorf("Expected contact id %s, got %s", id, contact.Id)
+		}
+
+		if _, err := db.CreateContact(ctx, Contact{Name: "Alice", Email: "alice@example.com"}); err == nil {
+			t.Errorf("Expected error when creating duplicate contact")
+		}
+	})
+
+	t.Run("Contact Not Found", func(t *testing.T) {
+		db, _ := NewMemoryPersistence("")
+
+		_, err := db.GetContact(ctx, "missing@example.com")
+		if _, ok := err.(ContactNotFoundError); !ok {
+			t.Errorf("Expected ContactNotFoundError, got %v", err)
+		}
+	})
+
+	t.Run("List Contact Requests", func(t *testing.T) {
+		db, _ := NewMemoryPersistence("")
+
+		contactId, _ := db.CreateContact(ctx, Contact{Name: "Bob", Email: "bob@example.com"})
+		if _, err := db.CreateContactRequest(ctx, ContactRequest{ContactId: contactId, Message: "Hello"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if _, err := db.CreateContactRequest(ctx, ContactRequest{ContactId: "missing", Message: "Hello"}); err == nil {
+			t.Errorf("Expected error for unknown contact")
+		}
+
+		requests, err := db.ListContactRequests(ctx)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(requests) != 1 || requests[0].Email != "bob@example.com" {
+			t.Errorf("Expected 1 request for bob@example.com, got %+v", requests)
+		}
+	})
+
+	t.Run("Triage Contact Request", func(t *testing.T) {
+		db, _ := NewMemoryPersistence("")
+
+		contactId, _ := db.CreateContact(ctx, Contact{Name: "Bob", Email: "bob@example.com"})
+		id, _ := db.CreateContactRequest(ctx, ContactRequest{ContactId: contactId, Message: "Hello"})
+		_, _ = db.CreateContactRequest(ctx, ContactRequest{ContactId: contactId, Message: "Hello again"})
+
+		read, notes := ContactRequestStatusRead, "replied by phone"
+		request, err := db.UpdateContactRequest(ctx, id, ContactRequestUpdate{Status: &read, Notes: &notes})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if request.Status != read || request.Notes != notes || request.Email != "bob@example.com" {
+			t.Errorf("Expected read request with notes for bob@example.com, got %+v", request)
+		}
+
+		spam := ContactRequestStatusSpam
+		if _, err := db.UpdateContactRequest(ctx, id, ContactRequestUpdate{Status: &spam}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		replied := ContactRequestStatusReplied
+		if _, err := db.UpdateContactRequest(ctx, id, ContactRequestUpdate{Status: &replied}); err == nil {
+			t.Errorf("Expected InvalidStatusTransitionError, got nil")
+		} else if _, ok := err.(InvalidStatusTransitionError); !ok {
+			t.Errorf("Expected InvalidStatusTransitionError, got %v", err)
+		}
+
+		if _, err := db.UpdateContactRequest(ctx, "missing", ContactRequestUpdate{Notes: &notes}); err == nil {
+			t.Errorf("Expected ContactRequestNotFoundError, got nil")
+		} else if _, ok := err.(ContactRequestNotFoundError); !ok {
+			t.Errorf("Expected ContactRequestNotFoundError, got %v", err)
+		}
+
+		requests, _, err := db.QueryContactRequests(ctx, ListQuery{Limit: 10, Status: ContactRequestStatusNew})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(requests) != 1 || requests[0].Id == id {
+			t.Errorf("Expected 1 new request, got %+v", requests)
+		}
+
+		requests, _, _ = db.QueryContactRequests(ctx, ListQuery{Limit: 10, Status: spam})
+		if len(requests) != 1 || requests[0].Id != id || requests[0].Notes != notes {
+			t.Errorf("Expected request %s marked as spam, got %+v", id, requests)
+		}
+	})
+
+	t.Run("Redeem Challenge", func(t *testing.T) {
+		db, _ := NewMemoryPersistence("")
+
+		if err := db.RedeemChallenge(ctx, "challenge1", time.Now().Add(time.Minute)); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		var errUsed ChallengeUsedError
+		if err := db.RedeemChallenge(ctx, "challenge1", time.Now().Add(time.Minute)); !errors.As(err, &errUsed) {
+			t.Errorf("Expected ChallengeUsedError, got %v", err)
+		}
+
+		// expired challenges are removed when redeeming
+		if err := db.RedeemChallenge(ctx, "challenge2", time.Now().Add(-time.Minute)); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := db.RedeemChallenge(ctx, "challenge2", time.Now().Add(time.Minute)); err != nil {
+			t.Errorf("Expected expired challenge to be removed, got %v", err)
+		}
+	})
+
+	t.Run("Resume Versions", func(t *testing.T) {
+		db, _ := NewMemoryPersistence("")
+
+		first, err := db.CreateResumeVersion(ctx, ResumeVersion{Variant: "default", Locale: "en", Format: ResumeFormatJSON, Content: []byte(`{"basics": {}}`), Hash: "first", Author: "admin"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !first.Active || first.Size != 14 || first.Content != nil || first.CreatedAt.IsZero() {
+			t.Errorf("Expected active version without content, got %+v", first)
+		}
+
+		second, _ := db.CreateResumeVersion(ctx, ResumeVersion{Variant: "default", Locale: "en", Format: ResumeFormatJSON, Content: []byte(`{}`), Hash: "second", Author: "admin"})
+		db.CreateResumeVersion(ctx, ResumeVersion{Variant: "default", Locale: "de", Format: ResumeFormatJSON, Content: []byte(`{}`), Hash: "third", Author: "admin"})
+
+		versions, err := db.ListResumeVersions(ctx)
+		if err != nil || len(versions) != 3 || versions[1].Id != second.Id || versions[2].Active {
+			t.Errorf("Expected versions most recent first with the first inactive, got %+v %v", versions, err)
+		}
+
+		// rolling back makes the first version active again
+		if _, err := db.ActivateResumeVersion(ctx, first.Id); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		active, _ := db.ListActiveResumeVersions(ctx)
+		hashes := []string{}
+		for _, version := range active {
+			hashes = append(hashes, version.Hash)
+		}
+		slices.Sort(hashes)
+		if !slices.Equal(hashes, []string{"first", "third"}) {
+			t.Errorf("Expected first and third versions to be active, got %v", hashes)
+		}
+
+		version, err := db.GetResumeVersion(ctx, first.Id)
+		if err != nil || string(version.Content) != `{"basics": {}}` || version.ActivatedAt.Before(version.CreatedAt) {
+			t.Errorf("Expected version with content, got %+v %v", version, err)
+		}
+
+		var errNotFound ResumeVersionNotFoundError
+		if _, err := db.GetResumeVersion(ctx, "missing"); !errors.As(err, &errNotFound) {
+			t.Errorf("Expected ResumeVersionNotFoundError, got %v", err)
+		}
+		if _, err := db.ActivateResumeVersion(ctx, "missing"); !errors.As(err, &errNotFound) {
+			t.Errorf("Expected ResumeVersionNotFoundError, got %v", err)
+		}
+	})
+
+	t.Run("Resume Downloads", func(t *testing.T) {
+		db, _ := NewMemoryPersistence("")
+
+		day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+		downloads := []ResumeDownload{
+			{Format: ResumeFormatPDF, Referrer: "linkedin.com", IPAddress: "1.1.1.1", DownloadedAt: day.Add(time.Hour)},
+			{Format: ResumeFormatPDF, Referrer: "linkedin.com", IPAddress: "1.1.1.1", DownloadedAt: day.Add(2 * time.Hour)},
+			{Format: ResumeFormatJSON, Referrer: "github.com", IPAddress: "2.2.2.2", DownloadedAt: day.Add(3 * time.Hour)},
+			{Format: ResumeFormatPDF, IPAddress: "3.3.3.3", DownloadedAt: day.Add(25 * time.Hour)},
+			// outside of the queried period
+			{Format: ResumeFormatPDF, Referrer: "example.com", IPAddress: "4.4.4.4", DownloadedAt: day.Add(-time.Hour)},
+		}
+		for _, download := range downloads {
+			download.Variant, download.Locale = "default", "en"
+			if err := db.RecordResumeDownload(ctx, download); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		}
+
+		stats, err := db.GetResumeStats(ctx, ResumeStatsQuery{Since: day, Until: day.AddDate(0, 0, 7), Referrers: 1})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if stats.TotalDownloads != 4 || stats.UniqueDownloaders != 3 {
+			t.Errorf("Expected 4 downloads by 3 downloaders, got %+v", stats)
+		}
+		expected := []ResumeDailyDownloads{
+			{Date: "2026-03-01", Formats: map[ResumeFileFormat]int{ResumeFormatPDF: 2, ResumeFormatJSON: 1}},
+			{Date: "2026-03-02", Formats: map[ResumeFileFormat]int{ResumeFormatPDF: 1}},
+		}
+		if !reflect.DeepEqual(stats.Daily, expected) {
+			t.Errorf("Expected daily downloads %+v, got %+v", expected, stats.Daily)
+		}
+		if !slices.Equal(stats.TopReferrers, []ReferrerCount{{Referrer: "linkedin.com", Count: 2}}) {
+			t.Errorf("Expected linkedin.com as top referrer, got %+v", stats.TopReferrers)
+		}
+
+		empty, err := db.GetResumeStats(ctx, ResumeStatsQuery{Since: day.AddDate(1, 0, 0), Until: day.AddDate(1, 0, 1), Referrers: 10})
+		if err != nil || empty.TotalDownloads != 0 || len(empty.Daily) != 0 || empty.TopReferrers == nil {
+			t.Errorf("Expected empty stats, got %+v %v", empty, err)
+		}
+	})
+
+	t.Run("Resume Shares", func(t *testing.T) {
+		db, _ := NewMemoryPersistence("")
+
+		expiresAt := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+		share, err := db.CreateResumeShare(ctx, ResumeShare{Variant: "default", Locale: "en", Format: ResumeFormatPDF, Recipient: "recruiter", SingleUse: true, CreatedBy: "admin", ExpiresAt: expiresAt})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if share.Id == "" || share.Opens != 0 || share.FirstOpenedAt != nil || !share.ExpiresAt.Equal(expiresAt) {
+			t.Errorf("Expected unopened share, got %+v", share)
+		}
+		reusable, _ := db.CreateResumeShare(ctx, ResumeShare{Variant: "default", Locale: "en", Format: ResumeFormatJSON, CreatedBy: "admin", ExpiresAt: expiresAt})
+
+		opened, err := db.OpenResumeShare(ctx, share.Id)
+		if err != nil || opened.Opens != 1 || opened.FirstOpenedAt == nil || opened.LastOpenedAt == nil {
+			t.Errorf("Expected opened share, got %+v %v", opened, err)
+		}
+
+		// single use shares can only be opened once
+		var errUsed ResumeShareUsedError
+		if _, err := db.OpenResumeShare(ctx, share.Id); !errors.As(err, &errUsed) {
+			t.Errorf("Expected ResumeShareUsedError, got %v", err)
+		}
+		db.OpenResumeShare(ctx, reusable.Id)
+		if opened, err := db.OpenResumeShare(ctx, reusable.Id); err != nil || opened.Opens != 2 {
+			t.Errorf("Expected share opened twice, got %+v %v", opened, err)
+		}
+
+		var errNotFound ResumeShareNotFoundError
+		if _, err := db.OpenResumeShare(ctx, "missing"); !errors.As(err, &errNotFound) {
+			t.Errorf("Expected ResumeShareNotFoundError, got %v", err)
+		}
+
+		if got, err := db.GetResumeShare(ctx, share.Id); err != nil || got.Opens != 1 || got.Recipient != "recruiter" {
+			t.Errorf("Expected opened share, got %+v %v", got, err)
+		}
+		if _, err := db.GetResumeShare(ctx, "missing"); !errors.As(err, &errNotFound) {
+			t.Errorf("Expected ResumeShareNotFoundError, got %v", err)
+		}
+
+		shares, err := db.ListResumeShares(ctx)
+		if err != nil || len(shares) != 2 || shares[0].Id != reusable.Id || shares[1].Opens != 1 {
+			t.Errorf("Expected shares most recent first, got %+v %v", shares, err)
+		}
+	})
+
+	t.Run("Webhook Delivery Queue", func(t *testing.T) {
+		db, _ := NewMemoryPersistence("")
+
+		deliveries := []WebhookDelivery{
+			{Subscription: "crm", EventId: "event1", EventType: EventContactCreated, Payload: []byte(`{"id":"event1"}`)},
+			{Subscription: "chat", EventId: "event1", EventType: EventContactCreated, Payload: []byte(`{"id":"event1"}`)},
+		}
+		if err := db.CreateWebhookDeliveries(ctx, deliveries); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		claimed, err := db.ClaimWebhookDeliveries(ctx, 1, time.Minute)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(claimed) != 1 || string(claimed[0].Payload) != `{"id":"event1"}` {
+			t.Fatalf("Expected 1 claimed delivery, got %+v", claimed)
+		}
+
+		// claimed deliveries are leased and cannot be claimed again
+		remaining, _ := db.ClaimWebhookDeliveries(ctx, 10, time.Minute)
+		if len(remaining) != 1 || remaining[0].Id == claimed[0].Id {
+			t.Errorf("Expected the other delivery to be claimed, got %+v", remaining)
+		}
+
+		now := time.Now()
+		delivery := claimed[0]
+		delivery.Status = WebhookDeliveryDelivered
+		delivery.Attempts = 1
+		delivery.ResponseStatus = 204
+		delivery.LastAttemptAt = &now
+		if err := db.UpdateWebhookDelivery(ctx, delivery); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		page, next, err := db.QueryWebhookDeliveries(ctx, ListQuery{Limit: 10})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(page) != 2 || next != nil {
+			t.Fatalf("Expected 2 deliveries on a single page, got %d", len(page))
+		}
+
+		for _, d := range page {
+			if d.Id == delivery.Id && (d.Status != WebhookDeliveryDelivered || d.ResponseStatus != 204 || d.LastAttemptAt == nil) {
+				t.Errorf("Expected delivered delivery, got %+v", d)
+			}
+		}
+	})
+
+	t.Run("Request Stats", func(t *testing.T) {
+		db, _ := NewMemoryPersistence("")
+
+		var requests []LoggedRequest
+		var responses []LoggedResponse
+		for i, ip := range []string{"1.1.1.1", "1.1.1.1", "2.2.2.2"} {
+			id := fmt.Sprintf("request-%d", i)
+			requests = append(requests, LoggedRequest{ID: id, Method: "GET", Path: "/health", IPAddress: ip})
+			responses = append(responses, LoggedResponse{RequestId: id, Status: 200})
+		}
+		if err := db.LogRequests(ctx, requests, responses); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		stats, err := db.GetRequestStats(ctx)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if stats.TotalRequests != 3 {
+			t.Errorf("Expected 3 total requests, got %d", stats.TotalRequests)
+		}
+
+		if stats.UniqueIPCount != 2 {
+			t.Errorf("Expected 2 unique IPs, got %d", stats.UniqueIPCount)
+		}
+
+		if stats.StatusCounts[200] != 3 {
+			t.Errorf("Expected 3 responses with status 200, got %d", stats.StatusCounts[200])
+		}
+	})
+
+	t.Run("Concurrent Writes", func(t *testing.T) {
+		db, _ := NewMemoryPersistence("")
+
+		var wg sync.WaitGroup
+		for i := range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				email := fmt.Sprintf("user%d@example.com", i)
+				_, _ = db.CreateContact(ctx, Contact{Name: "User", Email: email})
+				_ = db.LogRequests(ctx, []LoggedRequest{{Method: "POST", Path: "/contacts"}}, nil)
+			}()
+		}
+		wg.Wait()
+
+		contacts, _ := db.ListContacts(ctx)
+		if len(contacts) != 50 {
+			t.Errorf("Expected 50 contacts, got %d", len(contacts))
+		}
+	})
+
+	t.Run("Snapshot Round Trip", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "snapshot.json")
+
+		db, err := NewMemoryPersistence(path)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		db.Restore(MemorySnapshot{
+			APIKeys: []APIKey{{Key: "key", Owner: "admin", ExpiresAt: time.Now().Add(time.Hour)}},
+		})
+		_, _ = db.CreateContact(ctx, Contact{Name: "Carol", Email: "carol@example.com"})
+
+		if err := db.Close(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		restored, err := NewMemoryPersistence(path)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if _, err := restored.GetContact(ctx, "carol@example.com"); err != nil {
+			t.Errorf("Expected restored contact, got %v", err)
+		}
+
+		if _, err := restored.GetAPIKey(ctx, "key"); err != nil {
+			t.Errorf("Expected restored API key, got %v", err)
+		}
+	})
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
 	"time"
@@ -10,22 +14,16 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// apiKeyOwnerKey is the key of the owner of the API key used to
+// authorize an admin request in the Gin context
+const apiKeyOwnerKey = "apiKeyOwner"
+
 // AdminAuthMiddleware is a Gin middleware that checks for a valid API key
-// in the "X-API-Key" header for protected admin routes.
-func AdminAuthMiddleware(cfg *Config) gin.HandlerFunc {
+// in the "X-API-Key" header for protected admin routes. An api_key.used
+// event is published for every authorized request, and the owner of the
+// key is stored in the context under apiKeyOwnerKey.
+func AdminAuthMiddleware(db Persistence, events EventPublisher) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		pgDsn := PostgresDSNFromConfig(cfg)
-		// Initialize database connection for logging
-		db, err := NewPGPersistence(pgDsn)
-		if err != nil {
-			log.Error(fmt.Sprintf("failed to connect to database for tracing: %v", err))
-			c.AbortWithStatusJSON(500, gin.H{
-				"error": "Internal server error",
-			})
-			return
-		}
-		defer db.Conn.Close()
-
 		// Validate API key from header
 		apiKey := c.GetHeader("X-API-Key")
 		if apiKey == "" {
@@ -36,9 +34,20 @@ func AdminAuthMiddleware(cfg *Config) gin.HandlerFunc {
 			return
 		}
 
-		// Check if the API key is valid
-		key, err := db.GetAPIKey(apiKey)
-		if err != nil || key == nil || key.ExpiresAt.Before(time.Now()) {
+		// Check if the API key is valid. database errors
+		// such as timeouts are surfaced rather than being
+		// reported as an authorization failure
+		key, err := db.GetAPIKey(RequestContext(c), apiKey)
+		if err != nil {
+			var errNotFound APIKeyNotFoundError
+			if !errors.As(err, &errNotFound) {
+				log.Error(fmt.Sprintf("failed to retrieve API key: %v", err))
+				response := PersistenceErrorResponse(err)
+				c.AbortWithStatusJSON(response.Code, response.Payload)
+				return
+			}
+		}
+		if key == nil || key.ExpiresAt.Before(time.Now()) {
 			log.Warn("unauthorized access attempt to admin route")
 			c.AbortWithStatusJSON(403, gin.H{
 				"error": "Forbidden",
@@ -47,79 +56,139 @@ func AdminAuthMiddleware(cfg *Config) gin.HandlerFunc {
 		}
 
 		log.Info(fmt.Sprintf("authorized admin access by %s", key.Owner))
+		// the key itself is never included in the event
+		events.Publish(context.WithoutCancel(RequestContext(c)), EventAPIKeyUsed, gin.H{
+			"owner":      key.Owner,
+			"method":     c.Request.Method,
+			"path":       c.Request.URL.Path,
+			"ip_address": c.ClientIP(),
+		})
+		c.Set(apiKeyOwnerKey, key.Owner)
 		c.Next()
 	}
 }
 
+// resumeDownloadKey is the key of the ResumeDownload
+// served by a resume handler in the Gin context
+const resumeDownloadKey = "resumeDownload"
+
+// maxUserAgentLength limits the size of recorded user agents
+const maxUserAgentLength = 512
+
+// ResumeDownloadMiddleware is a Gin middleware that records the resume
+// stored in the context under resumeDownloadKey as a download using the
+// RequestLogger, which writes it to the database in the background. Only
+// complete responses to GET requests are recorded, so that 304 responses
+// to conditional requests and partial responses to range requests are
+// not counted.
+func ResumeDownloadMiddleware(logger *RequestLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		value, exists := c.Get(resumeDownloadKey)
+		if !exists || c.Request.Method != http.MethodGet || c.Writer.Status() != http.StatusOK {
+			return
+		}
+
+		download := value.(ResumeDownload)
+		download.Referrer = referrerHost(c.Request.Referer())
+		download.UserAgent = c.Request.UserAgent()
+		if len(download.UserAgent) > maxUserAgentLength {
+			download.UserAgent = strings.ToValidUTF8(download.UserAgent[:maxUserAgentLength], "")
+		}
+		download.IPAddress = c.ClientIP()
+		download.DownloadedAt = time.Now()
+		logger.LogDownload(download)
+	}
+}
+
+// resumeShareKey is the id of the ResumeShare served
+// by the shared resume handler in the Gin context
+const resumeShareKey = "resumeShare"
+
+// ResumeShareMiddleware is a Gin middleware that records that the share
+// stored in the context under resumeShareKey was opened. As with
+// downloads, only complete responses to GET requests are recorded. The
+// open is written before the handler chain returns, since it consumes
+// single use links.
+func ResumeShareMiddleware(db Persistence) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		value, exists := c.Get(resumeShareKey)
+		if !exists || c.Request.Method != http.MethodGet || c.Writer.Status() != http.StatusOK {
+			return
+		}
+
+		id := value.(string)
+		ctx := context.WithoutCancel(RequestContext(c))
+		if _, err := db.OpenResumeShare(ctx, id); err != nil {
+			log.Warn(fmt.Sprintf("failed to record opening of resume share %s: %v", id, err))
+			return
+		}
+		log.Info(fmt.Sprintf("opened resume share with id: %s", id))
+	}
+}
+
+// referrerHost returns the lowercase host of the given referrer URL,
+// or an empty string if there is no valid referrer.
+func referrerHost(referrer string) string {
+	parsed, err := url.Parse(referrer)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
 type LoggingExemption struct {
 	PathRegex string
 	Method    string
 }
 
 // RouteLoggingMiddleware is a Gin middleware that logs each incoming request
-// and its corresponding response to the database.
-func RouteLoggingMiddleware(cfg *Config, exemptions []LoggingExemption) gin.HandlerFunc {
+// and its corresponding response using the RequestLogger, which writes them
+// to the database in the background once the response has been sent.
+func RouteLoggingMiddleware(logger *RequestLogger, exemptions []LoggingExemption) gin.HandlerFunc {
+	// exemptions are compiled once rather than on every request
+	patterns := make([]*regexp.Regexp, len(exemptions))
+	for i, exemption := range exemptions {
+		patterns[i] = regexp.MustCompile(exemption.PathRegex)
+	}
+
 	return func(c *gin.Context) {
 
 		path := c.Request.URL.Path
 		method := c.Request.Method
 
 		// check for exemptions
-		for _, exemption := range exemptions {
+		for i, exemption := range exemptions {
 			if !strings.EqualFold(method, exemption.Method) {
 				continue
 			}
 			// check if path matches regex
-			exp := regexp.MustCompile(exemption.PathRegex)
-			if exp.MatchString(path) {
+			if patterns[i].MatchString(path) {
 				log.Info(fmt.Sprintf("skipping logging for exempted route - Method: %s, Path: %s", method, path))
 				c.Next()
 				return
 			}
 		}
 
-		ip := c.ClientIP()
-
-		pgDsn := PostgresDSNFromConfig(cfg)
-		// Initialize database connection for logging
-		db, err := NewPGPersistence(pgDsn)
-		if err != nil {
-			log.Error(fmt.Sprintf("failed to connect to database for tracing: %v", err))
-			c.AbortWithStatusJSON(500, gin.H{
-				"error": "Internal server error",
-			})
-			return
-		}
-		defer db.Conn.Close()
-
 		log.Info(fmt.Sprintf("tracing request - Method: %s, Path: %s", method, path))
 
 		request := LoggedRequest{
 			Method:    strings.ToUpper(method),
 			Path:      path,
-			IPAddress: ip,
+			IPAddress: c.ClientIP(),
 			RequestTs: time.Now(),
 		}
-		// Log the request to the database
-		requestId, err := db.LogRequest(request)
-		if err != nil {
-			log.Warn(fmt.Sprintf("failed to log request: %v", err))
-		}
-
-		ts := time.Now()
 
 		c.Next()
 
-		elapsed := time.Since(ts).Milliseconds()
 		response := LoggedResponse{
-			RequestId:   requestId,
 			Status:      c.Writer.Status(),
-			TimeElapsed: elapsed,
+			TimeElapsed: time.Since(request.RequestTs).Milliseconds(),
 			ResponseTs:  time.Now(),
 		}
-		// Log the response to the database
-		if err := db.LogResponse(response); err != nil {
-			log.Warn(fmt.Sprintf("failed to log response: %v", err))
-		}
+		logger.Log(request, response)
 	}
 }
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminAuthMiddleware(t *testing.T) {
+	persistence := &TestPersistence{
+		APIKeys: map[string]APIKey{
+			"valid":   {Key: "valid", Owner: "admin", ExpiresAt: time.Now().Add(time.Hour)},
+			"expired": {Key: "expired", Owner: "admin", ExpiresAt: time.Now().Add(-time.Hour)},
+		},
+	}
+
+	events := &TestPublisher{}
+	router := gin.New()
+	router.Use(AdminAuthMiddleware(persistence, events))
+	router.GET("/admin", func(c *gin.Context) {
+		c.JSON(200, gin.H{"status": "ok"})
+	})
+
+	cases := []struct {
+		name     string
+		key      string
+		expected int
+	}{
+		{"Valid Key", "valid", 200},
+		{"Expired Key", "expired", 403},
+		{"Unknown Key", "unknown", 403},
+		{"Missing Key", "", 403},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			writer := httptest.NewRecorder()
+			request := httptest.NewRequest("GET", "/admin", nil)
+			if tc.key != "" {
+				request.Header.Set("X-API-Key", tc.key)
+			}
+
+			router.ServeHTTP(writer, request)
+			if writer.Code != tc.expected {
+				t.Errorf("Expected status code %d, got %d", tc.expected, writer.Code)
+			}
+		})
+	}
+
+	// only the authorized request publishes an event
+	if len(events.Events) != 1 || events.Events[0].Type != EventAPIKeyUsed {
+		t.Fatalf("Expected 1 api key used event, got %+v", events.Events)
+	}
+
+	data := events.Events[0].Data.(gin.H)
+	if data["owner"] != "admin" || data["path"] != "/admin" {
+		t.Errorf("Expected event for admin on /admin, got %+v", data)
+	}
+}
+
+func TestRouteLoggingMiddleware(t *testing.T) {
+	persistence := &TestPersistence{}
+	logger := newTestRequestLogger(t, persistence)
+
+	exemptions := []LoggingExemption{
+		{PathRegex: "^/version$", Method: "GET"},
+	}
+
+	router := gin.New()
+	router.Use(RouteLoggingMiddleware(logger, exemptions))
+	router.GET("/version", func(c *gin.Context) {
+		c.JSON(200, gin.H{"version": "v1"})
+	})
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(200, gin.H{"status": "ok"})
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/version", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+
+	// requests are written in the background, so wait for them to drain
+	if err := logger.Close(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(persistence.LoggedRequests) != 1 || persistence.LoggedRequests[0].Path != "/health" {
+		t.Fatalf("Expected only /health to be logged, got %+v", persistence.LoggedRequests)
+	}
+
+	if len(persistence.LoggedResponses) != 1 || persistence.LoggedResponses[0].RequestId != persistence.LoggedRequests[0].ID {
+		t.Errorf("Expected 1 logged response to the request, got %+v", persistence.LoggedResponses)
+	}
+	if persistence.LoggedResponses[0].Status != 200 {
+		t.Errorf("Expected status 200, got %d", persistence.LoggedResponses[0].Status)
+	}
+}
+
+func TestResumeDownloadMiddleware(t *testing.T) {
+	newRouter := func(logger *RequestLogger) *gin.Engine {
+		router := gin.New()
+		router.Use(ResumeDownloadMiddleware(logger))
+		router.GET("/resume", func(c *gin.Context) {
+			c.Set(resumeDownloadKey, ResumeDownload{Format: ResumeFormatPDF, Variant: "default", Locale: "en"})
+			response := RESTResponse{Code: 200, Payload: gin.H{"data": "resume"}, ETag: `"resume"`}
+			response.Send(c)
+		})
+		router.GET("/missing", func(c *gin.Context) {
+			NotFoundResponse.Send(c)
+		})
+		return router
+	}
+
+	cases := []struct {
+		name        string
+		path        string
+		ifNoneMatch string
+		recorded    bool
+	}{
+		{"Download", "/resume", "", true},
+		{"Not Modified", "/resume", `"resume"`, false},
+		{"Not A Resume", "/missing", "", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			persistence := &TestPersistence{}
+			logger := newTestRequestLogger(t, persistence)
+
+			request := httptest.NewRequest("GET", tc.path, nil)
+			request.Header.Set("If-None-Match", tc.ifNoneMatch)
+			request.Header.Set("Referer", "https://WWW.LinkedIn.com/in/someone?trk=secret")
+			request.Header.Set("User-Agent", strings.Repeat("a", 1000))
+			newRouter(logger).ServeHTTP(httptest.NewRecorder(), request)
+
+			// downloads are written in the background, so the
+			// logger is closed to wait for queued downloads
+			if err := logger.Close(context.Background()); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if recorded := len(persistence.ResumeDownloads) == 1; recorded != tc.recorded {
+				t.Fatalf("Expected download to be recorded %t, got %+v", tc.recorded, persistence.ResumeDownloads)
+			}
+			if !tc.recorded {
+				return
+			}
+
+			download := persistence.ResumeDownloads[0]
+			if download.Format != ResumeFormatPDF || download.Referrer != "www.linkedin.com" || len(download.UserAgent) != maxUserAgentLength || download.IPAddress == "" {
+				t.Errorf("Expected PDF download with referrer host and truncated user agent, got %+v", download)
+			}
+		})
+	}
+}
+
+func TestReferrerHost(t *testing.T) {
+	tests := map[string]string{
+		"":                               "",
+		"https://example.com/resume?q=1": "example.com",
+		"http://Example.com:8080/":       "example.com",
+		"android-app://com.slack/":       "com.slack",
+		"not a url":                      "",
+		"%zz":                            "",
+	}
+
+	for referrer, expected := range tests {
+		if host := referrerHost(referrer); host != expected {
+			t.Errorf("Expected host %q for %q, got %q", expected, referrer, host)
+		}
+	}
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	log "github.com/sirupsen/logrus"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrationLockKey is the key of the postgres advisory lock held while
+// migrations are applied, so that replicas starting at the same time
+// do not race each other.
+const migrationLockKey int64 = 2241859042
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+// migrationDialect contains the dialect specific statements
+// used to track applied migrations.
+type migrationDialect struct {
+	dir           string
+	createTable   string
+	listVersions  string
+	insertVersion string
+	deleteVersion string
+	lock          string
+	unlock        string
+}
+
+var (
+	postgresMigrationDialect = migrationDialect{
+		dir: "migrations/postgres",
+		// the tracking table lives outside of the base schema
+		// so that it survives rolling back the initial migration
+		createTable: `CREATE TABLE IF NOT EXISTS public.schema_migrations (
+			version BIGINT PRIMARY KEY NOT NULL,
+			name VARCHAR NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT now()
+		);`,
+		listVersions:  "SELECT version, applied_at FROM public.schema_migrations;",
+		insertVersion: "INSERT INTO public.schema_migrations (version, name) VALUES ($1, $2);",
+		deleteVersion: "DELETE FROM public.schema_migrations WHERE version=$1;",
+		lock:          "SELECT pg_advisory_lock($1);",
+		unlock:        "SELECT pg_advisory_unlock($1);",
+	}
+
+	sqliteMigrationDialect = migrationDialect{
+		dir: "migrations/sqlite",
+		createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY NOT NULL,
+			name TEXT NOT NULL,
+			applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);`,
+		listVersions:  "SELECT version, applied_at FROM schema_migrations;",
+		insertVersion: "INSERT INTO schema_migrations (version, name) VALUES (?, ?);",
+		deleteVersion: "DELETE FROM schema_migrations WHERE version=?;",
+	}
+)
+
+// Migrator applies the embedded SQL migrations to a database.
+type Migrator struct {
+	DB         *sql.DB
+	Migrations []Migration
+	dialect    migrationDialect
+	// owned is set if DB was opened by the
+	// migrator rather than the persistence
+	owned bool
+}
+
+// LoadMigrations parses all migrations in the given directory. Migration
+// files are named {version}_{name}.up.sql and {version}_{name}.down.sql.
+func LoadMigrations(files fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make(map[int]*Migration)
+	for _, entry := range entries {
+		var direction string
+		var base string
+		switch {
+		case strings.HasSuffix(entry.Name(), ".up.sql"):
+			direction, base = "up", strings.TrimSuffix(entry.Name(), ".up.sql")
+		case strings.HasSuffix(entry.Name(), ".down.sql"):
+			direction, base = "down", strings.TrimSuffix(entry.Name(), ".down.sql")
+		default:
+			continue
+		}
+
+		versionString, name, found := strings.Cut(base, "_")
+		if !found {
+			return nil, fmt.Errorf("invalid migration file name %s", entry.Name())
+		}
+		version, err := strconv.Atoi(versionString)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+
+		contents, err := fs.ReadFile(files, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, exists := migrations[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: name}
+			migrations[version] = migration
+		}
+		if direction == "up" {
+			migration.Up = string(contents)
+		} else {
+			migration.Down = string(contents)
+		}
+	}
+
+	var sorted []Migration
+	for _, migration := range migrations {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d is missing an up file", migration.Version)
+		}
+		sorted = append(sorted, *migration)
+	}
+	slices.SortFunc(sorted, func(a, b Migration) int {
+		return a.Version - b.Version
+	})
+	return sorted, nil
+}
+
+// NewMigrator creates a Migrator for the given persistence backend.
+// Returns an error for backends that do not use a SQL schema.
+func NewMigrator(db Persistence) (*Migrator, error) {
+	migrator := &Migrator{}
+	switch backend := db.(type) {
+	case *PGPersistence:
+		migrator.DB, migrator.dialect = stdlib.OpenDBFromPool(backend.Conn), postgresMigrationDialect
+		migrator.owned = true
+	case *SQLitePersistence:
+		migrator.DB, migrator.dialect = backend.Conn, sqliteMigrationDialect
+	default:
+		return nil, fmt.Errorf("persistence backend %T does not support migrations", db)
+	}
+
+	migrations, err := LoadMigrations(migrationFiles, migrator.dialect.dir)
+	if err != nil {
+		migrator.Close()
+		return nil, err
+	}
+	migrator.Migrations = migrations
+	return migrator, nil
+}
+
+// Close releases the connection opened by the migrator. Connections
+// shared with the persistence backend are left open.
+func (m *Migrator) Close() error {
+	if !m.owned {
+		return nil
+	}
+	return m.DB.Close()
+}
+
+// withLock runs fn on a single connection while holding the migration
+// lock. The lock is released once fn returns, or when the connection
+// is closed if the process dies.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.dialect.lock != "" {
+		log.Debug("acquiring migration lock")
+		if _, err := conn.ExecContext(ctx, m.dialect.lock, migrationLockKey); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer func() {
+			// unlock even if the parent context was cancelled
+			if _, err := conn.ExecContext(context.WithoutCancel(ctx), m.dialect.unlock, migrationLockKey); err != nil {
+				log.Warn(fmt.Sprintf("failed to release migration lock: %v", err))
+			}
+		}()
+	}
+
+	if _, err := conn.ExecContext(ctx, m.dialect.createTable); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+	return fn(conn)
+}
+
+// appliedVersions returns the applied migration versions
+// along with the time each version was applied.
+func (m *Migrator) appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, m.dialect.listVersions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// apply runs a single migration statement and records
+// the change to the tracking table in one transaction.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, statement string, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, statement); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Up applies all pending migrations in order and
+// returns the number of migrations applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.Migrations {
+			if _, exists := applied[migration.Version]; exists {
+				continue
+			}
+
+			log.Info(fmt.Sprintf("applying migration %04d_%s", migration.Version, migration.Name))
+			if err := m.apply(ctx, conn, migration.Up, m.dialect.insertVersion, migration.Version, migration.Name); err != nil {
+				return fmt.Errorf("failed to apply migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down rolls back the given number of most recently applied
+// migrations and returns the number of migrations rolled back.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range slices.Backward(m.Migrations) {
+			if count >= steps {
+				break
+			}
+			if _, exists := applied[migration.Version]; !exists {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %04d_%s cannot be rolled back", migration.Version, migration.Name)
+			}
+
+			log.Info(fmt.Sprintf("rolling back migration %04d_%s", migration.Version, migration.Name))
+			if err := m.apply(ctx, conn, migration.Down, m.dialect.deleteVersion, migration.Version); err != nil {
+				return fmt.Errorf("failed to roll back migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Status returns the status of every known migration.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.Migrations {
+			status := MigrationStatus{
+				Version: migration.Version,
+				Name:    migration.Name,
+			}
+			if appliedAt, exists := applied[migration.Version]; exists {
+				status.Applied = true
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// RunMigrateCommand runs the migrate subcommand with the given
+// arguments. Supported commands are up, down [steps] and status.
+func RunMigrateCommand(ctx context.Context, db Persistence, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up|down [steps]|status")
+	}
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	switch args[0] {
+	case "up":
+		count, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migration(s)\n", count)
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		count, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("rolled back %d migration(s)\n", count)
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.Applied {
+				appliedAt = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+	return nil
+}
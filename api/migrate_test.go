@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+
+	t.Run("Dialects Have Matching Versions", func(t *testing.T) {
+		postgres, err := LoadMigrations(migrationFiles, postgresMigrationDialect.dir)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		sqlite, err := LoadMigrations(migrationFiles, sqliteMigrationDialect.dir)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(postgres) != len(sqlite) {
+			t.Fatalf("Expected matching migration counts, got %d and %d", len(postgres), len(sqlite))
+		}
+
+		for i := range postgres {
+			if postgres[i].Version != sqlite[i].Version || postgres[i].Name != sqlite[i].Name {
+				t.Errorf("Expected matching migrations, got %04d_%s and %04d_%s",
+					postgres[i].Version, postgres[i].Name, sqlite[i].Version, sqlite[i].Name)
+			}
+			if postgres[i].Down == "" || sqlite[i].Down == "" {
+				t.Errorf("Expected migration %04d to have a down file", postgres[i].Version)
+			}
+		}
+	})
+
+	t.Run("Sorted By Version", func(t *testing.T) {
+		files := fstest.MapFS{
+			"m/0002_second.up.sql": {Data: []byte("SELECT 2;")},
+			"m/0001_first.up.sql":  {Data: []byte("SELECT 1;")},
+			"m/README.md":          {Data: []byte("ignored")},
+		}
+
+		migrations, err := LoadMigrations(files, "m")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(migrations) != 2 || migrations[0].Name != "first" || migrations[1].Name != "second" {
+			t.Errorf("Expected migrations first and second in order, got %+v", migrations)
+		}
+	})
+
+	t.Run("Invalid File Name", func(t *testing.T) {
+		files := fstest.MapFS{
+			"m/initial.up.sql": {Data: []byte("SELECT 1;")},
+		}
+
+		if _, err := LoadMigrations(files, "m"); err == nil {
+			t.Errorf("Expected error for invalid migration file name")
+		}
+	})
+
+	t.Run("Missing Up File", func(t *testing.T) {
+		files := fstest.MapFS{
+			"m/0001_initial.down.sql": {Data: []byte("SELECT 1;")},
+		}
+
+		if _, err := LoadMigrations(files, "m"); err == nil {
+			t.Errorf("Expected error for missing up file")
+		}
+	})
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+
+	// opening the database does not apply migrations, so
+	// that the migrate subcommand reports the actual state
+	fresh, err := NewSQLitePersistence(ctx, filepath.Join(t.TempDir(), "fresh.db"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer fresh.Close()
+	freshMigrator, _ := NewMigrator(fresh)
+	freshStatuses, err := freshMigrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, status := range freshStatuses {
+		if status.Applied {
+			t.Errorf("Expected migration %04d_%s to be pending", status.Version, status.Name)
+		}
+	}
+
+	db := newTestSQLitePersistence(t)
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// migrations are applied by newTestSQLitePersistence
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, status := range statuses {
+		if !status.Applied || status.AppliedAt == nil {
+			t.Errorf("Expected migration %04d_%s to be applied", status.Version, status.Name)
+		}
+	}
+
+	count, err := migrator.Down(ctx, len(statuses))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if count != len(statuses) {
+		t.Errorf("Expected %d migrations rolled back, got %d", len(statuses), count)
+	}
+
+	if _, err := db.ListContacts(ctx); err == nil {
+		t.Errorf("Expected error listing contacts after rolling back")
+	}
+
+	count, err = migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if count != len(statuses) {
+		t.Errorf("Expected %d migrations applied, got %d", len(statuses), count)
+	}
+
+	// applying again is a no-op
+	if count, _ := migrator.Up(ctx); count != 0 {
+		t.Errorf("Expected 0 migrations applied, got %d", count)
+	}
+
+	// the connection is shared with the persistence, so it stays open
+	if err := migrator.Close(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := db.ListContacts(ctx); err != nil {
+		t.Errorf("Expected persistence to remain usable, got %v", err)
+	}
+
+	if _, err := NewMigrator(&TestPersistence{}); err == nil {
+		t.Errorf("Expected error creating migrator for unsupported backend")
+	}
+}
@@ -0,0 +1,40 @@
+-- equivalent to alembic revision 224fdf859c42 (added initial tables).
+-- tables are created only if missing so that the migration can be
+-- applied to databases previously managed by alembic.
+CREATE SCHEMA IF NOT EXISTS base;
+
+CREATE TABLE IF NOT EXISTS base.contacts (
+    id VARCHAR PRIMARY KEY NOT NULL,
+    email VARCHAR UNIQUE NOT NULL,
+    name VARCHAR,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS base.contact_requests (
+    id VARCHAR PRIMARY KEY NOT NULL,
+    contact_id VARCHAR NOT NULL REFERENCES base.contacts (id) ON DELETE CASCADE,
+    message TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS base.logged_requests (
+    id VARCHAR PRIMARY KEY NOT NULL,
+    path VARCHAR NOT NULL,
+    method VARCHAR NOT NULL,
+    ip_address VARCHAR NOT NULL,
+    request_ts TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS base.logged_responses (
+    id VARCHAR PRIMARY KEY NOT NULL REFERENCES base.logged_requests (id) ON DELETE CASCADE,
+    status INTEGER NOT NULL,
+    time_elapsed INTEGER NOT NULL,
+    response_ts TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS base.api_keys (
+    key VARCHAR PRIMARY KEY NOT NULL,
+    owner VARCHAR NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS base.contact_requests_status_idx;
+
+ALTER TABLE base.contact_requests
+    DROP COLUMN IF EXISTS notes,
+    DROP COLUMN IF EXISTS status;
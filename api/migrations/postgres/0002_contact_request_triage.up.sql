@@ -0,0 +1,9 @@
+-- adds the triage status and internal notes to contact requests.
+-- existing requests are treated as not yet triaged.
+ALTER TABLE base.contact_requests
+    ADD COLUMN IF NOT EXISTS status VARCHAR NOT NULL DEFAULT 'new'
+        CHECK (status IN ('new', 'read', 'replied', 'archived', 'spam')),
+    ADD COLUMN IF NOT EXISTS notes TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS contact_requests_status_idx
+    ON base.contact_requests (status, created_at);
@@ -0,0 +1,20 @@
+-- persistent queue of webhook deliveries. pending deliveries are
+-- claimed by the dispatcher once next_attempt_at has passed.
+CREATE TABLE IF NOT EXISTS base.webhook_deliveries (
+    id VARCHAR PRIMARY KEY NOT NULL,
+    subscription VARCHAR NOT NULL,
+    event_id VARCHAR NOT NULL,
+    event_type VARCHAR NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT now(),
+    last_attempt_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_pending_idx
+    ON base.webhook_deliveries (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,2 @@
+ALTER TABLE base.contact_requests
+    DROP COLUMN IF EXISTS spam_score;
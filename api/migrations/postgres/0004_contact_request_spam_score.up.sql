@@ -0,0 +1,4 @@
+-- adds the score assigned by the spam filter to contact requests.
+-- existing requests were submitted before the filter existed.
+ALTER TABLE base.contact_requests
+    ADD COLUMN IF NOT EXISTS spam_score INTEGER NOT NULL DEFAULT 0;
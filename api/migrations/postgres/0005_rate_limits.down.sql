@@ -0,0 +1 @@
+DROP TABLE IF EXISTS base.rate_limits;
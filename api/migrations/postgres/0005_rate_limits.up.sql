@@ -0,0 +1,11 @@
+-- token buckets used by the postgres rate limit store. buckets are
+-- removed once full_at has passed, as they have refilled by then.
+CREATE TABLE IF NOT EXISTS base.rate_limits (
+    key VARCHAR PRIMARY KEY NOT NULL,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT now(),
+    full_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS rate_limits_full_at_idx
+    ON base.rate_limits (full_at);
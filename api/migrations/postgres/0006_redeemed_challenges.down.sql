@@ -0,0 +1 @@
+DROP TABLE IF EXISTS base.redeemed_challenges;
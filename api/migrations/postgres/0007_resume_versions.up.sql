@@ -0,0 +1,18 @@
+-- versions of the resume uploaded by admins. the active version of
+-- each variant, locale and format is served instead of the files.
+CREATE TABLE IF NOT EXISTS base.resume_versions (
+    id VARCHAR PRIMARY KEY NOT NULL,
+    variant VARCHAR NOT NULL,
+    locale VARCHAR NOT NULL,
+    format VARCHAR NOT NULL CHECK (format IN ('json', 'pdf')),
+    content BYTEA NOT NULL,
+    hash VARCHAR NOT NULL,
+    author VARCHAR NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    -- set when the version was last made active, e.g. by a rollback
+    activated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS resume_versions_active_idx
+    ON base.resume_versions (variant, locale, format) WHERE active;
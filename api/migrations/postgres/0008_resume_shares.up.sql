@@ -0,0 +1,17 @@
+-- signed links to the resume shared with recipients, along with
+-- how often each link was opened. tokens are derived from the id
+-- and expiry, so they are not stored.
+CREATE TABLE IF NOT EXISTS base.resume_shares (
+    id VARCHAR PRIMARY KEY NOT NULL,
+    variant VARCHAR NOT NULL,
+    locale VARCHAR NOT NULL,
+    format VARCHAR NOT NULL CHECK (format IN ('json', 'pdf', 'html', 'markdown', 'txt')),
+    recipient VARCHAR NOT NULL DEFAULT '',
+    single_use BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by VARCHAR NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP NOT NULL,
+    opens INTEGER NOT NULL DEFAULT 0,
+    first_opened_at TIMESTAMP,
+    last_opened_at TIMESTAMP
+);
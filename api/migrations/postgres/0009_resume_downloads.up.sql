@@ -0,0 +1,15 @@
+-- successful fetches of the resume, used for download
+-- statistics. only the host of the referrer is stored.
+CREATE TABLE IF NOT EXISTS base.resume_downloads (
+    id VARCHAR PRIMARY KEY NOT NULL,
+    format VARCHAR NOT NULL,
+    variant VARCHAR NOT NULL,
+    locale VARCHAR NOT NULL,
+    referrer VARCHAR NOT NULL DEFAULT '',
+    user_agent VARCHAR NOT NULL DEFAULT '',
+    ip_address VARCHAR NOT NULL,
+    downloaded_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS resume_downloads_downloaded_at_idx
+    ON base.resume_downloads (downloaded_at);
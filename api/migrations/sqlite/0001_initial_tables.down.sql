@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS logged_responses;
+DROP TABLE IF EXISTS logged_requests;
+DROP TABLE IF EXISTS contact_requests;
+DROP TABLE IF EXISTS contacts;
@@ -0,0 +1,37 @@
+-- equivalent to alembic revision 224fdf859c42 (added initial tables).
+-- SQLite has no schemas, so tables are created without the base prefix.
+CREATE TABLE IF NOT EXISTS contacts (
+    id TEXT PRIMARY KEY NOT NULL,
+    email TEXT UNIQUE NOT NULL,
+    name TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS contact_requests (
+    id TEXT PRIMARY KEY NOT NULL,
+    contact_id TEXT NOT NULL REFERENCES contacts (id) ON DELETE CASCADE,
+    message TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS logged_requests (
+    id TEXT PRIMARY KEY NOT NULL,
+    path TEXT NOT NULL,
+    method TEXT NOT NULL,
+    ip_address TEXT NOT NULL,
+    request_ts DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS logged_responses (
+    id TEXT PRIMARY KEY NOT NULL REFERENCES logged_requests (id) ON DELETE CASCADE,
+    status INTEGER NOT NULL,
+    time_elapsed INTEGER NOT NULL,
+    response_ts DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    key TEXT PRIMARY KEY NOT NULL,
+    owner TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL
+);
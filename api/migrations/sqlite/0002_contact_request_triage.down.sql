@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS contact_requests_status_idx;
+
+ALTER TABLE contact_requests DROP COLUMN notes;
+
+ALTER TABLE contact_requests DROP COLUMN status;
@@ -0,0 +1,9 @@
+-- adds the triage status and internal notes to contact requests.
+-- existing requests are treated as not yet triaged.
+ALTER TABLE contact_requests ADD COLUMN status TEXT NOT NULL DEFAULT 'new'
+    CHECK (status IN ('new', 'read', 'replied', 'archived', 'spam'));
+
+ALTER TABLE contact_requests ADD COLUMN notes TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS contact_requests_status_idx
+    ON contact_requests (status, created_at);
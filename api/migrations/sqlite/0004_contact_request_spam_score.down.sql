@@ -0,0 +1 @@
+ALTER TABLE contact_requests DROP COLUMN spam_score;
@@ -0,0 +1,3 @@
+-- adds the score assigned by the spam filter to contact requests.
+-- existing requests were submitted before the filter existed.
+ALTER TABLE contact_requests ADD COLUMN spam_score INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+-- rate limits are kept in memory when using the sqlite backend,
+-- as it only supports a single replica. this migration keeps the
+-- sqlite and postgres migration versions aligned.
+SELECT 1;
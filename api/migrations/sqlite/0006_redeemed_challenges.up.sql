@@ -0,0 +1,9 @@
+-- proof-of-work challenges that have already been used to submit
+-- the contact form. rows are removed once the challenge expires.
+CREATE TABLE IF NOT EXISTS redeemed_challenges (
+    id TEXT PRIMARY KEY NOT NULL,
+    expires_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS redeemed_challenges_expires_at_idx
+    ON redeemed_challenges (expires_at);
@@ -0,0 +1,18 @@
+-- versions of the resume uploaded by admins. the active version of
+-- each variant, locale and format is served instead of the files.
+CREATE TABLE IF NOT EXISTS resume_versions (
+    id TEXT PRIMARY KEY NOT NULL,
+    variant TEXT NOT NULL,
+    locale TEXT NOT NULL,
+    format TEXT NOT NULL CHECK (format IN ('json', 'pdf')),
+    content BLOB NOT NULL,
+    hash TEXT NOT NULL,
+    author TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- set when the version was last made active, e.g. by a rollback
+    activated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS resume_versions_active_idx
+    ON resume_versions (variant, locale, format) WHERE active;
@@ -0,0 +1,17 @@
+-- signed links to the resume shared with recipients, along with
+-- how often each link was opened. tokens are derived from the id
+-- and expiry, so they are not stored.
+CREATE TABLE IF NOT EXISTS resume_shares (
+    id TEXT PRIMARY KEY NOT NULL,
+    variant TEXT NOT NULL,
+    locale TEXT NOT NULL,
+    format TEXT NOT NULL CHECK (format IN ('json', 'pdf', 'html', 'markdown', 'txt')),
+    recipient TEXT NOT NULL DEFAULT '',
+    single_use BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL,
+    opens INTEGER NOT NULL DEFAULT 0,
+    first_opened_at DATETIME,
+    last_opened_at DATETIME
+);
@@ -0,0 +1,15 @@
+-- successful fetches of the resume, used for download
+-- statistics. only the host of the referrer is stored.
+CREATE TABLE IF NOT EXISTS resume_downloads (
+    id TEXT PRIMARY KEY NOT NULL,
+    format TEXT NOT NULL,
+    variant TEXT NOT NULL,
+    locale TEXT NOT NULL,
+    referrer TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL,
+    downloaded_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS resume_downloads_downloaded_at_idx
+    ON resume_downloads (downloaded_at);
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// mediaRange is a single media range of an Accept header.
+type mediaRange struct {
+	Type    string
+	Subtype string
+	Q       float64
+}
+
+// matches returns whether the range matches the given media type,
+// along with how specific the match is. Exact matches take precedence
+// over "type/*", which in turn take precedence over "*/*".
+func (r mediaRange) matches(mediaType string) (bool, int) {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	switch {
+	case r.Type == typ && r.Subtype == subtype:
+		return true, 2
+	case r.Type == typ && r.Subtype == "*":
+		return true, 1
+	case r.Type == "*" && r.Subtype == "*":
+		return true, 0
+	}
+	return false, 0
+}
+
+// parseAccept parses the media ranges of an Accept header. Parameters
+// other than the quality value are ignored, and invalid ranges or
+// quality values are skipped.
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(params[0])), "/")
+		if !ok || typ == "" || subtype == "" || (typ == "*" && subtype != "*") {
+			continue
+		}
+
+		if q, ok := parseQuality(params[1:]); ok {
+			ranges = append(ranges, mediaRange{Type: typ, Subtype: subtype, Q: q})
+		}
+	}
+	return ranges
+}
+
+// parseQuality returns the quality value in the given parameters, which
+// defaults to 1, and whether the quality value is valid.
+func parseQuality(params []string) (float64, bool) {
+	quality := 1.0
+	for _, param := range params {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if strings.ToLower(strings.TrimSpace(key)) != "q" {
+			continue
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || q < 0 || q > 1 {
+			return 0, false
+		}
+		quality = q
+	}
+	return quality, true
+}
+
+// AcceptsAnyMediaType returns whether the given Accept header is empty or
+// only contains "*/*", in which case the client has no preference.
+func AcceptsAnyMediaType(header string) bool {
+	for _, r := range parseAccept(header) {
+		if r.Type != "*" {
+			return false
+		}
+	}
+	return true
+}
+
+// NegotiateMediaType returns the offered media type preferred by the given
+// Accept header. The quality of each offer is taken from the most specific
+// matching range, and ties are broken by the order of the offers. The first
+// offer is returned if the header is empty, and an empty string is returned
+// if none of the offers are acceptable.
+func NegotiateMediaType(header string, offers ...string) string {
+	if strings.TrimSpace(header) == "" {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+
+	ranges := parseAccept(header)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			if ok, s := r.matches(offer); ok && s > specificity {
+				q, specificity = r.Q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// languageRange is a single language range of an Accept-Language header.
+type languageRange struct {
+	Tag string
+	Q   float64
+}
+
+// matches returns whether the range matches the given language tag,
+// along with how specific the match is. Exact matches take precedence
+// over ranges that are a prefix of the tag, e.g. "de" for "de-AT", which
+// in turn take precedence over tags that are a prefix of the range, e.g.
+// "de" for "de-DE", and finally "*".
+func (r languageRange) matches(tag string) (bool, int) {
+	tag = strings.ToLower(tag)
+	switch {
+	case r.Tag == tag:
+		return true, 3
+	case strings.HasPrefix(tag, r.Tag+"-"):
+		return true, 2
+	case strings.HasPrefix(r.Tag, tag+"-"):
+		return true, 1
+	case r.Tag == "*":
+		return true, 0
+	}
+	return false, 0
+}
+
+// parseAcceptLanguage parses the language ranges of an Accept-Language
+// header, skipping invalid quality values.
+func parseAcceptLanguage(header string) []languageRange {
+	var ranges []languageRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(params[0]))
+		if tag == "" {
+			continue
+		}
+		if q, ok := parseQuality(params[1:]); ok {
+			ranges = append(ranges, languageRange{Tag: tag, Q: q})
+		}
+	}
+	return ranges
+}
+
+// NegotiateLanguage returns the offered language tag preferred by the given
+// Accept-Language header, using the same rules as NegotiateMediaType.
+func NegotiateLanguage(header string, offers ...string) string {
+	if strings.TrimSpace(header) == "" {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+
+	ranges := parseAcceptLanguage(header)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			if ok, s := r.matches(offer); ok && s > specificity {
+				q, specificity = r.Q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestNegotiateMediaType(t *testing.T) {
+	offers := []string{"application/json", "application/pdf", "text/html"}
+
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"application/pdf", "application/pdf"},
+		{"APPLICATION/PDF", "application/pdf"},
+		{"application/json;q=0.5, application/pdf", "application/pdf"},
+		{"text/*;q=0.9, application/json;q=0.8", "text/html"},
+		// the most specific range sets the quality of an offer
+		{"*/*;q=0.9, application/json;q=0.1", "application/pdf"},
+		{"text/html;level=1;q=0.3, application/*;q=0.2", "text/html"},
+		// ties are broken by the order of the offers
+		{"application/pdf, application/json", "application/json"},
+		{"application/json;q=0, */*", "application/pdf"},
+		{"image/png", ""},
+		{"application/pdf;q=0", ""},
+		// invalid ranges are skipped
+		{"application/pdf;q=2, text/html", "text/html"},
+		{"pdf, */html, image/png", ""},
+	}
+	for _, tc := range tests {
+		if negotiated := NegotiateMediaType(tc.header, offers...); negotiated != tc.expected {
+			t.Errorf("Expected %q for Accept %q, got %q", tc.expected, tc.header, negotiated)
+		}
+	}
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	offers := []string{"en", "de", "fr-CA"}
+
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"", "en"},
+		{"*", "en"},
+		{"de", "de"},
+		{"DE", "de"},
+		{"de-DE, en;q=0.5", "de"},
+		{"fr", "fr-CA"},
+		{"en;q=0.5, de;q=0.8", "de"},
+		// the most specific range sets the quality of an offer
+		{"*;q=0.9, en;q=0.1", "de"},
+		{"de;q=0, *", "en"},
+		{"es", ""},
+		// invalid ranges are skipped
+		{"de;q=2, fr", "fr-CA"},
+	}
+	for _, tc := range tests {
+		if negotiated := NegotiateLanguage(tc.header, offers...); negotiated != tc.expected {
+			t.Errorf("Expected %q for Accept-Language %q, got %q", tc.expected, tc.header, negotiated)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"embed"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//go:embed templates/email
+var emailTemplateFiles embed.FS
+
+// ContactNotification contains the details of a new contact
+// request. It is also the data passed to email templates.
+type ContactNotification struct {
+	Name      string
+	Email     string
+	Message   string
+	ContactId string
+	RequestId string
+	CreatedAt time.Time
+}
+
+// Notifier is told about every new contact request. Implementations
+// must not block the caller, so that slow notification channels
+// never delay the response to the submitter.
+type Notifier interface {
+	NotifyContactRequest(notification ContactNotification)
+	// Close stops accepting notifications and waits for pending
+	// notifications to be sent, or for the context to be done.
+	Close(ctx context.Context) error
+}
+
+// NoopNotifier discards all notifications. It is
+// used when no notification channel is configured.
+type NoopNotifier struct{}
+
+func (NoopNotifier) NotifyContactRequest(notification ContactNotification) {}
+
+func (NoopNotifier) Close(ctx context.Context) error {
+	return nil
+}
+
+// email is a single rendered email message.
+type email struct {
+	To      string
+	ReplyTo string
+	Subject string
+	Body    string
+}
+
+// SMTPNotifier sends an email to the site owner for every new contact
+// request, and optionally an acknowledgement to the submitter. Emails
+// are sent from a background worker and retried with an exponential
+// backoff if the SMTP server cannot be reached.
+type SMTPNotifier struct {
+	Host        string
+	Port        int
+	Username    string
+	Password    string
+	From        string
+	OwnerEmail  string
+	Acknowledge bool
+	Timeout     time.Duration
+	MaxAttempts int
+	Backoff     time.Duration
+
+	templates *template.Template
+	queue     chan ContactNotification
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+	mu        sync.RWMutex
+	closed    bool
+}
+
+// LoadEmailTemplates parses the embedded email templates. Templates in
+// dir, if given, are parsed afterwards, so any template defined in dir
+// replaces the embedded template with the same name.
+func LoadEmailTemplates(dir string) (*template.Template, error) {
+	templates, err := template.ParseFS(emailTemplateFiles, "templates/email/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		return templates, nil
+	}
+	return templates.ParseFS(os.DirFS(dir), "*.tmpl")
+}
+
+// NewNotifier creates the Notifier configured in the given config.
+// A NoopNotifier is returned if no SMTP server is configured.
+func NewNotifier(cfg *Config) (Notifier, error) {
+	if cfg.SMTPHost == "" {
+		return NoopNotifier{}, nil
+	}
+
+	templates, err := LoadEmailTemplates(cfg.NotifyTemplateDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load email templates: %w", err)
+	}
+
+	notifier := &SMTPNotifier{
+		Host:        cfg.SMTPHost,
+		Port:        cfg.SMTPPort,
+		Username:    cfg.SMTPUsername,
+		Password:    cfg.SMTPPassword,
+		From:        cfg.SMTPFrom,
+		OwnerEmail:  cfg.NotifyEmail,
+		Acknowledge: cfg.NotifyAcknowledge,
+		Timeout:     cfg.SMTPTimeout,
+		MaxAttempts: cfg.NotifyMaxAttempts,
+		Backoff:     cfg.NotifyRetryBackoff,
+	}
+	notifier.Start(templates, cfg.NotifyQueueSize)
+	return notifier, nil
+}
+
+// Start starts the background worker that sends queued notifications.
+// At most queueSize notifications are buffered, and notifications
+// received while the queue is full are dropped.
+func (n *SMTPNotifier) Start(templates *template.Template, queueSize int) {
+	n.templates = templates
+	n.queue = make(chan ContactNotification, max(queueSize, 1))
+	n.ctx, n.cancel = context.WithCancel(context.Background())
+	n.done = make(chan struct{})
+
+	go func() {
+		defer close(n.done)
+		for notification := range n.queue {
+			n.process(notification)
+		}
+	}()
+}
+
+// NotifyContactRequest queues a notification without blocking.
+func (n *SMTPNotifier) NotifyContactRequest(notification ContactNotification) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if n.closed {
+		log.Warn(fmt.Sprintf("notifier closed, dropping notification for contact request %s", notification.RequestId))
+		return
+	}
+	select {
+	case n.queue <- notification:
+	default:
+		log.Warn(fmt.Sprintf("notification queue full, dropping notification for contact request %s", notification.RequestId))
+	}
+}
+
+// Close stops accepting notifications and waits for queued notifications
+// to be sent. Pending retries are abandoned once the context is done.
+func (n *SMTPNotifier) Close(ctx context.Context) error {
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.mu.Unlock()
+
+	select {
+	case <-n.done:
+		n.cancel()
+		return nil
+	case <-ctx.Done():
+		n.cancel()
+		<-n.done
+		return ctx.Err()
+	}
+}
+
+// process sends the owner notification and, if enabled,
+// the acknowledgement for a single contact request.
+func (n *SMTPNotifier) process(notification ContactNotification) {
+	owner, err := n.render("owner", n.OwnerEmail, notification)
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to render owner notification: %v", err))
+	} else {
+		// replies from the owner go straight to the submitter
+		owner.ReplyTo = notification.Email
+		if err := n.sendWithRetry(owner); err != nil {
+			log.Error(fmt.Sprintf("failed to send owner notification for contact request %s: %v", notification.RequestId, err))
+		}
+	}
+
+	if !n.Acknowledge {
+		return
+	}
+	acknowledgement, err := n.render("acknowledgement", notification.Email, notification)
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to render acknowledgement: %v", err))
+		return
+	}
+	if err := n.sendWithRetry(acknowledgement); err != nil {
+		log.Error(fmt.Sprintf("failed to send acknowledgement for contact request %s: %v", notification.RequestId, err))
+	}
+}
+
+// render executes the {name}_subject and {name}_body templates.
+func (n *SMTPNotifier) render(name string, to string, notification ContactNotification) (email, error) {
+	var subject, body bytes.Buffer
+	if err := n.templates.ExecuteTemplate(&subject, name+"_subject", notification); err != nil {
+		return email{}, err
+	}
+	if err := n.templates.ExecuteTemplate(&body, name+"_body", notification); err != nil {
+		return email{}, err
+	}
+	return email{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Body:    body.String(),
+	}, nil
+}
+
+// sendWithRetry sends an email, retrying failed attempts with an
+// exponential backoff until MaxAttempts is reached or the notifier
+// is closed.
+func (n *SMTPNotifier) sendWithRetry(message email) error {
+	attempts := max(n.MaxAttempts, 1)
+	backoff := n.Backoff
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = n.send(n.ctx, message); err == nil {
+			log.Info(fmt.Sprintf("sent email to %s", message.To))
+			return nil
+		}
+		log.Warn(fmt.Sprintf("attempt %d to send email to %s failed: %v", attempt, message.To, err))
+		if attempt == attempts {
+			break
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-n.ctx.Done():
+			return errors.Join(err, n.ctx.Err())
+		}
+	}
+	return err
+}
+
+// send delivers a single email. STARTTLS is used if offered by the
+// server, and credentials are only sent if a username is configured.
+func (n *SMTPNotifier) send(ctx context.Context, message email) error {
+	if n.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.Timeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(n.Host, strconv.Itoa(n.Port)))
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, n.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.Host}); err != nil {
+			return err
+		}
+	}
+	if n.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.Username, n.Password, n.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(n.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(message.To); err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(n.encode(message)); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// headerValue strips line breaks from a header value so that submitted
+// values cannot be used to inject additional headers.
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
+
+// encode builds the RFC 5322 message for an email. The body
+// is sent as quoted-printable encoded UTF-8 plain text.
+func (n *SMTPNotifier) encode(message email) []byte {
+	var buffer bytes.Buffer
+	headers := [][2]string{
+		{"From", headerValue(n.From)},
+		{"To", headerValue(message.To)},
+		{"Subject", mime.QEncoding.Encode("utf-8", headerValue(message.Subject))},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", `text/plain; charset="utf-8"`},
+		{"Content-Transfer-Encoding", "quoted-printable"},
+	}
+	if message.ReplyTo != "" {
+		headers = append(headers, [2]string{"Reply-To", headerValue(message.ReplyTo)})
+	}
+	for _, header := range headers {
+		fmt.Fprintf(&buffer, "%s: %s\r\n", header[0], header[1])
+	}
+	buffer.WriteString("\r\n")
+
+	writer := quotedprintable.NewWriter(&buffer)
+	writer.Write([]byte(message.Body))
+	writer.Close()
+	return buffer.Bytes()
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestNotifier records all notifications it receives.
+type TestNotifier struct {
+	Notifications []ContactNotification
+}
+
+func (t *TestNotifier) NotifyContactRequest(notification ContactNotification) {
+	t.Notifications = append(t.Notifications, notification)
+}
+
+func (t *TestNotifier) Close(ctx context.Context) error {
+	return nil
+}
+
+type testSMTPMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+// testSMTPServer is a minimal local SMTP server that records the messages
+// it receives. The first failures transactions are rejected with a 451.
+type testSMTPServer struct {
+	Addr     *net.TCPAddr
+	mu       sync.Mutex
+	messages []testSMTPMessage
+	failures int
+}
+
+func newTestSMTPServer(t *testing.T, failures int) *testSMTPServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &testSMTPServer{Addr: listener.Addr().(*net.TCPAddr), failures: failures}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.handle(conn)
+		}
+	}()
+	return server
+}
+
+func (s *testSMTPServer) handle(conn net.Conn) {
+	text := textproto.NewConn(conn)
+	defer text.Close()
+
+	text.PrintfLine("220 localhost test server")
+	var message testSMTPMessage
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			text.PrintfLine("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			s.mu.Lock()
+			reject := s.failures > 0
+			if reject {
+				s.failures--
+			}
+			s.mu.Unlock()
+
+			if reject {
+				text.PrintfLine("451 try again later")
+				continue
+			}
+			message = testSMTPMessage{From: line[len("MAIL FROM:"):]}
+			text.PrintfLine("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			message.To = append(message.To, line[len("RCPT TO:"):])
+			text.PrintfLine("250 OK")
+		case command == "DATA":
+			text.PrintfLine("354 send data")
+			lines, err := text.ReadDotLines()
+			if err != nil {
+				return
+			}
+			message.Data = strings.Join(lines, "\n")
+
+			s.mu.Lock()
+			s.messages = append(s.messages, message)
+			s.mu.Unlock()
+			text.PrintfLine("250 OK")
+		case command == "RSET", command == "NOOP":
+			text.PrintfLine("250 OK")
+		case command == "QUIT":
+			text.PrintfLine("221 bye")
+			return
+		default:
+			text.PrintfLine("500 unknown command")
+		}
+	}
+}
+
+func (s *testSMTPServer) Messages() []testSMTPMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]testSMTPMessage{}, s.messages...)
+}
+
+func newTestSMTPNotifier(t *testing.T, server *testSMTPServer, templateDir string) *SMTPNotifier {
+	t.Helper()
+
+	templates, err := LoadEmailTemplates(templateDir)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	notifier := &SMTPNotifier{
+		Host:        "127.0.0.1",
+		Port:        server.Addr.Port,
+		From:        "website@example.com",
+		OwnerEmail:  "owner@example.com",
+		Timeout:     time.Second,
+		MaxAttempts: 3,
+		Backoff:     10 * time.Millisecond,
+	}
+	notifier.Start(templates, 10)
+	return notifier
+}
+
+// messageHeaders returns the header section of a message.
+func messageHeaders(data string) string {
+	headers, _, _ := strings.Cut(data, "\n\n")
+	return headers
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	notification := ContactNotification{
+		Name:      "Alice",
+		Email:     "alice@example.com",
+		Message:   "Hello there",
+		ContactId: "1",
+		RequestId: "req1",
+		CreatedAt: time.Now(),
+	}
+
+	closeNotifier := func(t *testing.T, notifier *SMTPNotifier) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := notifier.Close(ctx); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	t.Run("Owner Notification and Acknowledgement", func(t *testing.T) {
+		server := newTestSMTPServer(t, 0)
+		notifier := newTestSMTPNotifier(t, server, "")
+		notifier.Acknowledge = true
+
+		notifier.NotifyContactRequest(notification)
+		closeNotifier(t, notifier)
+
+		messages := server.Messages()
+		if len(messages) != 2 {
+			t.Fatalf("Expected 2 messages, got %d", len(messages))
+		}
+
+		owner := messages[0]
+		if len(owner.To) != 1 || owner.To[0] != "<owner@example.com>" {
+			t.Errorf("Expected message to owner@example.com, got %v", owner.To)
+		}
+
+		headers := messageHeaders(owner.Data)
+		if !strings.Contains(headers, "Subject: New contact request from Alice") {
+			t.Errorf("Expected owner subject, got %s", headers)
+		}
+
+		if !strings.Contains(headers, "Reply-To: alice@example.com") {
+			t.Errorf("Expected Reply-To header, got %s", headers)
+		}
+
+		if !strings.Contains(owner.Data, "Hello there") {
+			t.Errorf("Expected message in body, got %s", owner.Data)
+		}
+
+		acknowledgement := messages[1]
+		if len(acknowledgement.To) != 1 || acknowledgement.To[0] != "<alice@example.com>" {
+			t.Errorf("Expected message to alice@example.com, got %v", acknowledgement.To)
+		}
+
+		if !strings.Contains(acknowledgement.Data, "Subject: Thanks for getting in touch") {
+			t.Errorf("Expected acknowledgement subject, got %s", acknowledgement.Data)
+		}
+
+		// submitted text is not echoed back to the submitter
+		if strings.Contains(acknowledgement.Data, "Hello there") || strings.Contains(acknowledgement.Data, "Alice") {
+			t.Errorf("Expected acknowledgement without submitted text, got %s", acknowledgement.Data)
+		}
+	})
+
+	t.Run("Retries Failed Attempts", func(t *testing.T) {
+		server := newTestSMTPServer(t, 2)
+		notifier := newTestSMTPNotifier(t, server, "")
+
+		notifier.NotifyContactRequest(notification)
+		closeNotifier(t, notifier)
+
+		if messages := server.Messages(); len(messages) != 1 {
+			t.Errorf("Expected 1 message after retrying, got %d", len(messages))
+		}
+	})
+
+	t.Run("Gives Up After Max Attempts", func(t *testing.T) {
+		server := newTestSMTPServer(t, 5)
+		notifier := newTestSMTPNotifier(t, server, "")
+
+		notifier.NotifyContactRequest(notification)
+		closeNotifier(t, notifier)
+
+		if messages := server.Messages(); len(messages) != 0 {
+			t.Errorf("Expected no messages, got %d", len(messages))
+		}
+	})
+
+	t.Run("Close Abandons Retries", func(t *testing.T) {
+		server := newTestSMTPServer(t, 5)
+		notifier := newTestSMTPNotifier(t, server, "")
+		notifier.Backoff = time.Hour
+
+		notifier.NotifyContactRequest(notification)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		if err := notifier.Close(ctx); err == nil {
+			t.Errorf("Expected deadline exceeded error, got nil")
+		}
+
+		// notifications after close are dropped
+		notifier.NotifyContactRequest(notification)
+	})
+
+	t.Run("Header Injection", func(t *testing.T) {
+		server := newTestSMTPServer(t, 0)
+		notifier := newTestSMTPNotifier(t, server, "")
+
+		injected := notification
+		injected.Name = "Alice\r\nBcc: mallory@example.com"
+		notifier.NotifyContactRequest(injected)
+		closeNotifier(t, notifier)
+
+		messages := server.Messages()
+		if len(messages) != 1 {
+			t.Fatalf("Expected 1 message, got %d", len(messages))
+		}
+
+		for _, header := range strings.Split(messageHeaders(messages[0].Data), "\n") {
+			if strings.HasPrefix(header, "Bcc:") {
+				t.Errorf("Expected no injected Bcc header, got %s", header)
+			}
+		}
+	})
+
+	t.Run("Template Overrides", func(t *testing.T) {
+		dir := t.TempDir()
+		override := `{{define "owner_subject"}}[website] {{.Email}}{{end}}`
+		if err := os.WriteFile(filepath.Join(dir, "owner.tmpl"), []byte(override), 0o644); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		server := newTestSMTPServer(t, 0)
+		notifier := newTestSMTPNotifier(t, server, dir)
+
+		notifier.NotifyContactRequest(notification)
+		closeNotifier(t, notifier)
+
+		messages := server.Messages()
+		if len(messages) != 1 {
+			t.Fatalf("Expected 1 message, got %d", len(messages))
+		}
+
+		if !strings.Contains(messages[0].Data, "Subject: [website] alice@example.com") {
+			t.Errorf("Expected overridden subject, got %s", messages[0].Data)
+		}
+
+		// templates that are not overridden use the defaults
+		if !strings.Contains(messages[0].Data, "Hello there") {
+			t.Errorf("Expected default body, got %s", messages[0].Data)
+		}
+	})
+}
+
+func TestNewNotifier(t *testing.T) {
+	notifier, err := NewNotifier(&Config{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, ok := notifier.(NoopNotifier); !ok {
+		t.Errorf("Expected NoopNotifier without SMTP host, got %T", notifier)
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+)
+
+// PDFFont is one of the standard Type1 fonts, which every PDF
+// reader provides, so that no fonts need to be embedded.
+type PDFFont string
+
+const (
+	PDFFontRegular PDFFont = "Helvetica"
+	PDFFontBold    PDFFont = "Helvetica-Bold"
+)
+
+// pdfFontNames are the resource names used for each font in content streams
+var pdfFontNames = map[PDFFont]string{
+	PDFFontRegular: "F1",
+	PDFFontBold:    "F2",
+}
+
+// widths of the printable ASCII characters from 32 to 126 in
+// thousandths of the font size, taken from the Adobe font metrics
+var pdfFontWidths = map[PDFFont][95]int{
+	PDFFontRegular: {
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	},
+	PDFFontBold: {
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	},
+}
+
+// pdfWinAnsi maps characters outside of ASCII and Latin-1 to their
+// WinAnsiEncoding code, along with their width in both fonts.
+var pdfWinAnsi = map[rune]struct {
+	code  byte
+	width int
+}{
+	'€': {0x80, 556},
+	'…': {0x85, 1000},
+	'‘': {0x91, 222},
+	'’': {0x92, 222},
+	'“': {0x93, 333},
+	'”': {0x94, 333},
+	'•': {0x95, 350},
+	'–': {0x96, 556},
+	'—': {0x97, 1000},
+}
+
+// pdfEncode converts text to WinAnsiEncoding, returning the encoded
+// text and its width in thousandths of the font size. Characters that
+// cannot be encoded are replaced with a question mark.
+func pdfEncode(text string, font PDFFont) ([]byte, int) {
+	widths := pdfFontWidths[font]
+	encoded := make([]byte, 0, len(text))
+	width := 0
+	for _, r := range text {
+		switch {
+		case r >= 32 && r <= 126:
+			encoded = append(encoded, byte(r))
+			width += widths[r-32]
+		case r >= 0xA0 && r <= 0xFF:
+			// Latin-1 characters share their WinAnsi codes
+			encoded = append(encoded, byte(r))
+			width += 556
+		default:
+			if char, ok := pdfWinAnsi[r]; ok {
+				encoded = append(encoded, char.code)
+				width += char.width
+			} else {
+				encoded = append(encoded, '?')
+				width += widths['?'-32]
+			}
+		}
+	}
+	return encoded, width
+}
+
+// PDFTextWidth returns the width of text set in the given font and size.
+func PDFTextWidth(text string, font PDFFont, size float64) float64 {
+	_, width := pdfEncode(text, font)
+	return float64(width) * size / 1000
+}
+
+// PDFColor is an RGB color with components between 0 and 1.
+type PDFColor struct {
+	R, G, B float64
+}
+
+// ParsePDFColor parses a hex color of the form "#rrggbb".
+func ParsePDFColor(hex string) (PDFColor, error) {
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(strings.TrimPrefix(hex, "#"), "%02x%02x%02x", &r, &g, &b); err != nil {
+		return PDFColor{}, fmt.Errorf("invalid color %q", hex)
+	}
+	return PDFColor{float64(r) / 255, float64(g) / 255, float64(b) / 255}, nil
+}
+
+// PDFDocument is a minimal PDF writer supporting text and lines
+// on any number of pages. Coordinates are given in points, with
+// the origin at the bottom left of the page.
+type PDFDocument struct {
+	Width  float64
+	Height float64
+	Title  string
+	pages  []*bytes.Buffer
+}
+
+// AddPage starts a new page, which receives all subsequent drawing.
+func (d *PDFDocument) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+// page returns the current page, adding one if there are none.
+func (d *PDFDocument) page() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	return d.pages[len(d.pages)-1]
+}
+
+// Text draws text with its baseline starting at x and y.
+func (d *PDFDocument) Text(x, y float64, text string, font PDFFont, size float64, color PDFColor) {
+	encoded, _ := pdfEncode(text, font)
+	fmt.Fprintf(d.page(), "BT %.3f %.3f %.3f rg /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n",
+		color.R, color.G, color.B, pdfFontNames[font], size, x, y, pdfEscape(encoded))
+}
+
+// Line draws a straight line between two points.
+func (d *PDFDocument) Line(x1, y1, x2, y2, width float64, color PDFColor) {
+	fmt.Fprintf(d.page(), "%.3f %.3f %.3f RG %.2f w %.2f %.2f m %.2f %.2f l S\n",
+		color.R, color.G, color.B, width, x1, y1, x2, y2)
+}
+
+// pdfEscape escapes the characters that delimit PDF strings.
+func pdfEscape(text []byte) []byte {
+	var escaped bytes.Buffer
+	for _, b := range text {
+		if b == '\\' || b == '(' || b == ')' {
+			escaped.WriteByte('\\')
+		}
+		escaped.WriteByte(b)
+	}
+	return escaped.Bytes()
+}
+
+// Bytes serialises the document. The output only depends on
+// the drawn content, so the same content produces the same PDF.
+func (d *PDFDocument) Bytes() ([]byte, error) {
+	d.page()
+
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	// objects 1 to 5 are fixed, and are followed
+	// by a page and content stream for each page
+	var kids []string
+	for i := range d.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 6+2*i))
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	title, _ := pdfEncode(d.Title, PDFFontRegular)
+	object(fmt.Sprintf("<< /Title (%s) /Producer (personal-website api) >>", pdfEscape(title)))
+
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", d.Width, d.Height, 7+2*i))
+
+		var compressed bytes.Buffer
+		writer := zlib.NewWriter(&compressed)
+		if _, err := writer.Write(page.Bytes()); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream",
+			compressed.Len(), compressed.Bytes()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes(), nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPDFEncode(t *testing.T) {
+	encoded, width := pdfEncode("A é€?☃", PDFFontRegular)
+	if !bytes.Equal(encoded, []byte{'A', ' ', 0xE9, 0x80, '?', '?'}) {
+		t.Errorf("Expected WinAnsi encoded text, got %v", encoded)
+	}
+
+	// A, space, é, €, and two question marks
+	if expected := 667 + 278 + 556 + 556 + 556 + 556; width != expected {
+		t.Errorf("Expected width %d, got %d", expected, width)
+	}
+
+	if PDFTextWidth("ii", PDFFontBold, 10) != 5.56 {
+		t.Errorf("Expected bold width 5.56, got %f", PDFTextWidth("ii", PDFFontBold, 10))
+	}
+}
+
+func TestParsePDFColor(t *testing.T) {
+	color, err := ParsePDFColor("#ff0033")
+	if err != nil || color != (PDFColor{1, 0, 0.2}) {
+		t.Errorf("Expected parsed color, got %v %v", color, err)
+	}
+
+	for _, invalid := range []string{"", "#ff", "#gggggg", "red"} {
+		if _, err := ParsePDFColor(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestPDFDocument(t *testing.T) {
+	render := func() []byte {
+		doc := &PDFDocument{Width: 200, Height: 100, Title: "Title (draft)"}
+		doc.Text(10, 80, `a (b) \ c`, PDFFontRegular, 12, PDFColor{})
+		doc.AddPage()
+		doc.Line(10, 10, 190, 10, 1, PDFColor{1, 0, 0})
+		output, err := doc.Bytes()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return output
+	}
+
+	output := render()
+	if !bytes.HasPrefix(output, []byte("%PDF-1.4")) || !bytes.HasSuffix(output, []byte("%%EOF\n")) {
+		t.Errorf("Expected PDF header and trailer, got %q", output)
+	}
+
+	for _, expected := range []string{"/Count 2", "/MediaBox [0 0 200.00 100.00]", `/Title (Title \(draft\))`, "xref\n0 10\n"} {
+		if !strings.Contains(string(output), expected) {
+			t.Errorf("Expected %q in PDF", expected)
+		}
+	}
+
+	if !bytes.Equal(output, render()) {
+		t.Errorf("Expected identical output for identical documents")
+	}
+}
+
+func TestPDFEscape(t *testing.T) {
+	if escaped := pdfEscape([]byte(`a (b) \ c`)); string(escaped) != `a \(b\) \\ c` {
+		t.Errorf("Expected escaped string, got %s", escaped)
+	}
+}
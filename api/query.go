@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// listColumns contains the column expressions used to
+// filter and order the results of a ListQuery.
+type listColumns struct {
+	createdAt string
+	id        string
+	// optional, only set for tables
+	// that store an email
+	email string
+	// optional, only set for tables
+	// that reference a contact
+	contactId string
+	// optional, only set for contact requests
+	status string
+}
+
+// postgresPlaceholder returns the postgres placeholder for the n-th argument.
+func postgresPlaceholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// sqlitePlaceholder returns the sqlite placeholder for the n-th argument.
+func sqlitePlaceholder(n int) string {
+	return "?" + strconv.Itoa(n)
+}
+
+// buildListQuery appends the filter, keyset pagination, ordering and
+// limit clauses for the given ListQuery to the select statement. One
+// more row than the page size is requested so that callers can tell
+// whether another page exists (see nextPage).
+func buildListQuery(selectStatement string, columns listColumns, query ListQuery, placeholder func(int) string) (string, []any) {
+	var conditions []string
+	var args []any
+
+	arg := func(value any) string {
+		args = append(args, value)
+		return placeholder(len(args))
+	}
+
+	if query.Email != "" && columns.email != "" {
+		conditions = append(conditions, fmt.Sprintf("%s = %s", columns.email, arg(query.Email)))
+	}
+	if query.ContactId != "" && columns.contactId != "" {
+		conditions = append(conditions, fmt.Sprintf("%s = %s", columns.contactId, arg(query.ContactId)))
+	}
+	if query.Status != "" && columns.status != "" {
+		conditions = append(conditions, fmt.Sprintf("%s = %s", columns.status, arg(string(query.Status))))
+	}
+	if query.Since != nil {
+		conditions = append(conditions, fmt.Sprintf("%s >= %s", columns.createdAt, arg(*query.Since)))
+	}
+	if query.Until != nil {
+		conditions = append(conditions, fmt.Sprintf("%s < %s", columns.createdAt, arg(*query.Until)))
+	}
+
+	order, comparison := "ASC", ">"
+	if query.Descending {
+		order, comparison = "DESC", "<"
+	}
+
+	// resume after the last row of the previous page. ties
+	// on created_at are broken using the unique id column
+	if query.Cursor != nil {
+		createdAt, id := arg(query.Cursor.CreatedAt), arg(query.Cursor.Id)
+		conditions = append(conditions, fmt.Sprintf("(%s %s %s OR (%s = %s AND %s %s %s))",
+			columns.createdAt, comparison, createdAt,
+			columns.createdAt, createdAt,
+			columns.id, comparison, id))
+	}
+
+	statement := selectStatement
+	if len(conditions) > 0 {
+		statement += "\n\t\tWHERE " + strings.Join(conditions, " AND ")
+	}
+	statement += fmt.Sprintf("\n\t\tORDER BY %s %s, %s %s\n\t\tLIMIT %s;",
+		columns.createdAt, order, columns.id, order, arg(query.Limit+1))
+	return statement, args
+}
+
+// nextPage trims items fetched using buildListQuery to the page size,
+// returning a cursor pointing at the last item if more items exist.
+func nextPage[T any](items []T, limit int, cursor func(T) ListCursor) ([]T, *ListCursor) {
+	if len(items) <= limit {
+		return items, nil
+	}
+	items = items[:limit]
+	next := cursor(items[limit-1])
+	return items, &next
+}
+
+func contactCursor(contact Contact) ListCursor {
+	return ListCursor{CreatedAt: contact.CreatedAt, Id: contact.Id}
+}
+
+func contactRequestCursor(request ContactRequest) ListCursor {
+	return ListCursor{CreatedAt: request.CreatedAt, Id: request.Id}
+}
+
+func webhookDeliveryCursor(delivery WebhookDelivery) ListCursor {
+	return ListCursor{CreatedAt: delivery.CreatedAt, Id: delivery.Id}
+}
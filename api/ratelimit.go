@@ -0,0 +1,427 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// rateLimitPruneInterval is the minimum interval
+	// between removing buckets that have refilled
+	rateLimitPruneInterval = time.Minute
+	// rateLimitMaxBody is the maximum size of request
+	// body read when rate limiting by email
+	rateLimitMaxBody = 1 << 20
+)
+
+type RateLimitStoreType string
+
+const (
+	RateLimitStoreMemory   RateLimitStoreType = "memory"
+	RateLimitStorePostgres RateLimitStoreType = "postgres"
+)
+
+// RateLimit defines a token bucket holding up to Burst tokens,
+// refilled at a rate of Requests tokens per Period.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// rate returns the number of tokens added per second.
+func (l RateLimit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// RateLimitResult contains the state of a bucket after taking a token.
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is the time until the next token
+	// is available if the request was not allowed
+	RetryAfter time.Duration
+	// Reset is the time until the bucket is full
+	Reset time.Duration
+}
+
+// restricts reports whether the result is more restrictive than
+// another result. Rejected results are more restrictive than allowed
+// results, and are compared by the time until a token is available.
+func (r RateLimitResult) restricts(other RateLimitResult) bool {
+	if r.Allowed != other.Allowed {
+		return !r.Allowed
+	}
+	if !r.Allowed {
+		return r.RetryAfter > other.RetryAfter
+	}
+	return r.Remaining < other.Remaining
+}
+
+// takeToken refills a bucket holding the given number of tokens after the
+// elapsed time, and takes a token if one is available. The new number of
+// tokens is returned along with the result.
+func takeToken(tokens float64, elapsed time.Duration, limit RateLimit) (float64, RateLimitResult) {
+	rate := limit.rate()
+	tokens = math.Min(float64(limit.Burst), tokens+max(elapsed.Seconds(), 0)*rate)
+
+	result := RateLimitResult{Limit: limit.Burst}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	result.Remaining = int(tokens)
+	result.Reset = time.Duration((float64(limit.Burst) - tokens) / rate * float64(time.Second))
+	return tokens, result
+}
+
+// RateLimitStore stores token buckets. Take must be atomic, so
+// that concurrent requests cannot take the same token.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error)
+}
+
+type rateLimitBucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+// MemoryRateLimitStore keeps token buckets in memory. Buckets are
+// not shared between replicas, and are lost on restart.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]rateLimitBucket
+	pruned  time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]rateLimitBucket)}
+}
+
+// Take takes a token from the bucket with the given key.
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	// buckets that have refilled are equivalent
+	// to new buckets, and can safely be removed
+	if now.Sub(s.pruned) > rateLimitPruneInterval {
+		for k, bucket := range s.buckets {
+			if bucket.full.Before(now) {
+				delete(s.buckets, k)
+			}
+		}
+		s.pruned = now
+	}
+
+	bucket, exists := s.buckets[key]
+	if !exists {
+		bucket = rateLimitBucket{tokens: float64(limit.Burst), updated: now}
+	}
+
+	tokens, result := takeToken(bucket.tokens, now.Sub(bucket.updated), limit)
+	s.buckets[key] = rateLimitBucket{tokens: tokens, updated: now, full: now.Add(result.Reset)}
+	return result, nil
+}
+
+// PGRateLimitStore keeps token buckets in PostgreSQL, so that limits are
+// shared between replicas. Buckets are refilled using the database clock.
+type PGRateLimitStore struct {
+	DB *PGPersistence
+
+	mu     sync.Mutex
+	pruned time.Time
+}
+
+// Take takes a token from the bucket with the given key. The bucket
+// row is locked while it is updated, so concurrent requests for the
+// same key are serialized.
+func (s *PGRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	ctx, cancel := s.DB.queryContext(ctx)
+	defer cancel()
+
+	s.prune(ctx)
+
+	tx, err := s.DB.Conn.Begin(ctx)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO base.rate_limits (key, tokens, updated_at, full_at)
+		VALUES ($1, $2, now(), now())
+		ON CONFLICT (key) DO NOTHING;`
+	if _, err := tx.Exec(ctx, query, key, float64(limit.Burst)); err != nil {
+		return RateLimitResult{}, err
+	}
+
+	var tokens, elapsed float64
+	err = tx.QueryRow(ctx, `
+		SELECT tokens, EXTRACT(EPOCH FROM now() - updated_at)::DOUBLE PRECISION
+		FROM base.rate_limits WHERE key=$1 FOR UPDATE;`, key).Scan(&tokens, &elapsed)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	tokens, result := takeToken(tokens, time.Duration(elapsed*float64(time.Second)), limit)
+	query = `
+		UPDATE base.rate_limits
+		SET tokens=$2, updated_at=now(), full_at=now() + make_interval(secs => $3)
+		WHERE key=$1;`
+	if _, err := tx.Exec(ctx, query, key, tokens, result.Reset.Seconds()); err != nil {
+		return RateLimitResult{}, err
+	}
+	return result, tx.Commit(ctx)
+}
+
+// prune removes buckets that have refilled, at most once
+// per prune interval. Failures are only logged.
+func (s *PGRateLimitStore) prune(ctx context.Context) {
+	s.mu.Lock()
+	if time.Since(s.pruned) < rateLimitPruneInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.pruned = time.Now()
+	s.mu.Unlock()
+
+	if _, err := s.DB.Conn.Exec(ctx, "DELETE FROM base.rate_limits WHERE full_at < now();"); err != nil {
+		log.Warn(fmt.Sprintf("failed to prune rate limits: %v", err))
+	}
+}
+
+// RateLimitRule applies a rate limit to all requests with the given
+// method to a route group. Requests are limited by client IP, and
+// additionally by the submitted email if ByEmail is set.
+type RateLimitRule struct {
+	Group    string        `json:"group" validate:"required,oneof=public admin"`
+	Method   string        `json:"method"`
+	Requests int           `json:"requests" validate:"required,min=1"`
+	Period   time.Duration `json:"-" validate:"required"`
+	Burst    int           `json:"burst" validate:"omitempty,min=1"`
+	ByEmail  bool          `json:"by_email"`
+}
+
+// UnmarshalJSON parses the period of a rule as a duration string, e.g. "10m".
+func (r *RateLimitRule) UnmarshalJSON(data []byte) error {
+	type rule RateLimitRule
+	var raw struct {
+		rule
+		Period string `json:"period"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*r = RateLimitRule(raw.rule)
+	if raw.Period == "" {
+		return nil
+	}
+	period, err := time.ParseDuration(raw.Period)
+	if err != nil || period <= 0 {
+		return fmt.Errorf("invalid period %q", raw.Period)
+	}
+	r.Period = period
+	return nil
+}
+
+// limit returns the token bucket used for the rule.
+func (r RateLimitRule) limit() RateLimit {
+	burst := r.Burst
+	if burst == 0 {
+		burst = r.Requests
+	}
+	return RateLimit{Requests: r.Requests, Period: r.Period, Burst: burst}
+}
+
+// DefaultRateLimitRules are used if no rules file is configured.
+var DefaultRateLimitRules = []RateLimitRule{
+	{Group: "public", Method: "POST", Requests: 5, Period: 10 * time.Minute, ByEmail: true},
+	{Group: "public", Method: "GET", Requests: 60, Period: time.Minute, Burst: 30},
+	{Group: "admin", Requests: 300, Period: time.Minute, Burst: 60},
+}
+
+// LoadRateLimitRules reads and validates the rate limit rules
+// defined in the given JSON file.
+func LoadRateLimitRules(path string) ([]RateLimitRule, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []RateLimitRule
+	if err := json.Unmarshal(contents, &rules); err != nil {
+		return nil, err
+	}
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	seen := make(map[string]bool)
+	for i, rule := range rules {
+		if err := validate.Struct(rule); err != nil {
+			return nil, fmt.Errorf("invalid rate limit rule %d: %w", i, err)
+		}
+
+		rule.Method = strings.ToUpper(rule.Method)
+		if rule.Method == "*" {
+			rule.Method = ""
+		}
+		// each request is matched by a single rule
+		key := rule.Group + " " + rule.Method
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate rate limit rule for %s %s", rule.Group, rule.Method)
+		}
+		seen[key] = true
+		rules[i] = rule
+	}
+	return rules, nil
+}
+
+// RateLimiter applies rate limit rules using the given store.
+type RateLimiter struct {
+	Store RateLimitStore
+	Rules []RateLimitRule
+}
+
+// NewRateLimiter creates the RateLimiter configured in the given config.
+// A RateLimiter without rules is returned if rate limiting is disabled.
+func NewRateLimiter(cfg *Config, db Persistence) (*RateLimiter, error) {
+	if !cfg.RateLimitEnabled {
+		return &RateLimiter{}, nil
+	}
+
+	rules := DefaultRateLimitRules
+	if cfg.RateLimitsPath != "" {
+		var err error
+		if rules, err = LoadRateLimitRules(cfg.RateLimitsPath); err != nil {
+			return nil, fmt.Errorf("failed to load rate limit rules: %w", err)
+		}
+	}
+
+	var store RateLimitStore
+	switch cfg.RateLimitStore {
+	case RateLimitStorePostgres:
+		pg, ok := db.(*PGPersistence)
+		if !ok {
+			return nil, errors.New("postgres rate limit store requires the postgres persistence backend")
+		}
+		store = &PGRateLimitStore{DB: pg}
+	default:
+		store = NewMemoryRateLimitStore()
+	}
+	return &RateLimiter{Store: store, Rules: rules}, nil
+}
+
+// rule returns the rule matching a request to the given group, preferring
+// rules for the request method over rules matching any method.
+func (l *RateLimiter) rule(group string, method string) (RateLimitRule, bool) {
+	var match RateLimitRule
+	found := false
+	for _, rule := range l.Rules {
+		if rule.Group != group {
+			continue
+		}
+		if strings.EqualFold(rule.Method, method) {
+			return rule, true
+		}
+		if rule.Method == "" {
+			match, found = rule, true
+		}
+	}
+	return match, found
+}
+
+// requestEmail returns the lowercase email submitted in a JSON request
+// body. The body is restored so that it can be read by the handler.
+func requestEmail(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, rateLimitMaxBody))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	if err != nil {
+		return ""
+	}
+
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if json.Unmarshal(body, &payload) != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(payload.Email))
+}
+
+// seconds rounds a duration up to whole seconds for use in headers.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// RateLimitMiddleware is a Gin middleware that applies the rate limit rules
+// for the given route group. Requests exceeding a limit are rejected with a
+// 429. If the store fails, requests are allowed rather than rejected.
+func RateLimitMiddleware(limiter *RateLimiter, group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		method := c.Request.Method
+		rule, exists := limiter.rule(group, method)
+		if !exists {
+			c.Next()
+			return
+		}
+
+		prefix := fmt.Sprintf("%s:%s:", group, strings.ToUpper(method))
+		keys := []string{prefix + "ip:" + c.ClientIP()}
+		if rule.ByEmail {
+			if email := requestEmail(c); email != "" {
+				keys = append(keys, prefix+"email:"+email)
+			}
+		}
+
+		// the most restrictive bucket is reported
+		// in the rate limit headers
+		var reported *RateLimitResult
+		for _, key := range keys {
+			result, err := limiter.Store.Take(RequestContext(c), key, rule.limit())
+			if err != nil {
+				log.Error(fmt.Sprintf("failed to check rate limit: %v", err))
+				c.Next()
+				return
+			}
+
+			if reported == nil || result.restricts(*reported) {
+				reported = &result
+			}
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(reported.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(reported.Remaining))
+		c.Header("X-RateLimit-Reset", seconds(reported.Reset))
+		if !reported.Allowed {
+			log.Warn(fmt.Sprintf("rate limit exceeded by %s for %s %s", c.ClientIP(), method, c.Request.URL.Path))
+			c.Header("Retry-After", seconds(reported.RetryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, TooManyRequestsPayload)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// failingRateLimitStore fails every attempt to take a token.
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("store unavailable")
+}
+
+func TestTakeToken(t *testing.T) {
+	limit := RateLimit{Requests: 60, Period: time.Minute, Burst: 2}
+
+	t.Run("Allows While Tokens Remain", func(t *testing.T) {
+		tokens, result := takeToken(2, 0, limit)
+		if !result.Allowed || result.Remaining != 1 || tokens != 1 {
+			t.Errorf("Expected allowed with 1 remaining, got %+v", result)
+		}
+
+		if result.Reset != time.Second {
+			t.Errorf("Expected reset after 1s, got %s", result.Reset)
+		}
+	})
+
+	t.Run("Rejects Empty Bucket", func(t *testing.T) {
+		tokens, result := takeToken(0.5, 0, limit)
+		if result.Allowed || tokens != 0.5 {
+			t.Errorf("Expected rejected without taking a token, got %+v", result)
+		}
+
+		if result.RetryAfter != 500*time.Millisecond {
+			t.Errorf("Expected retry after 500ms, got %s", result.RetryAfter)
+		}
+	})
+
+	t.Run("Refills Up To Burst", func(t *testing.T) {
+		tokens, result := takeToken(0, time.Hour, limit)
+		if !result.Allowed || tokens != 1 {
+			t.Errorf("Expected bucket refilled to burst, got %f tokens", tokens)
+		}
+	})
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryRateLimitStore()
+	limit := RateLimit{Requests: 1, Period: time.Hour, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		if result, _ := store.Take(ctx, "a", limit); !result.Allowed {
+			t.Errorf("Expected request %d to be allowed, got %+v", i+1, result)
+		}
+	}
+
+	if result, _ := store.Take(ctx, "a", limit); result.Allowed {
+		t.Errorf("Expected request 3 to be rejected, got %+v", result)
+	}
+
+	// buckets are independent
+	if result, _ := store.Take(ctx, "b", limit); !result.Allowed {
+		t.Errorf("Expected request for another key to be allowed, got %+v", result)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	newRouter := func(limiter *RateLimiter) *gin.Engine {
+		router := gin.New()
+		router.Use(RateLimitMiddleware(limiter, "public"))
+		router.GET("/resume", func(c *gin.Context) {
+			c.JSON(200, gin.H{"status": "ok"})
+		})
+		router.POST("/contacts", func(c *gin.Context) {
+			// the body must still be readable by handlers
+			body, _ := io.ReadAll(c.Request.Body)
+			c.String(201, string(body))
+		})
+		return router
+	}
+
+	send := func(router *gin.Engine, method string, ip string, body string) *httptest.ResponseRecorder {
+		path := "/resume"
+		if method == "POST" {
+			path = "/contacts"
+		}
+
+		writer := httptest.NewRecorder()
+		request := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		request.RemoteAddr = ip + ":1234"
+		router.ServeHTTP(writer, request)
+		return writer
+	}
+
+	t.Run("Rejects Requests Over Limit", func(t *testing.T) {
+		router := newRouter(&RateLimiter{
+			Store: NewMemoryRateLimitStore(),
+			Rules: []RateLimitRule{{Group: "public", Requests: 2, Period: time.Minute}},
+		})
+
+		writer := send(router, "GET", "1.1.1.1", "")
+		if writer.Code != 200 || writer.Header().Get("X-RateLimit-Limit") != "2" || writer.Header().Get("X-RateLimit-Remaining") != "1" {
+			t.Errorf("Expected 200 with rate limit headers, got %d %v", writer.Code, writer.Header())
+		}
+
+		send(router, "GET", "1.1.1.1", "")
+		writer = send(router, "GET", "1.1.1.1", "")
+		if writer.Code != 429 {
+			t.Fatalf("Expected status code 429, got %d", writer.Code)
+		}
+
+		if writer.Header().Get("Retry-After") != "30" || writer.Header().Get("X-RateLimit-Remaining") != "0" {
+			t.Errorf("Expected Retry-After of 30 seconds, got %v", writer.Header())
+		}
+
+		// other clients have their own buckets
+		if writer := send(router, "GET", "2.2.2.2", ""); writer.Code != 200 {
+			t.Errorf("Expected status code 200 for another IP, got %d", writer.Code)
+		}
+	})
+
+	t.Run("Method Rules Take Precedence", func(t *testing.T) {
+		router := newRouter(&RateLimiter{
+			Store: NewMemoryRateLimitStore(),
+			Rules: []RateLimitRule{
+				{Group: "public", Requests: 100, Period: time.Minute},
+				{Group: "public", Method: "POST", Requests: 1, Period: time.Minute},
+				{Group: "admin", Method: "GET", Requests: 1, Period: time.Minute},
+			},
+		})
+
+		send(router, "POST", "1.1.1.1", "{}")
+		if writer := send(router, "POST", "1.1.1.1", "{}"); writer.Code != 429 {
+			t.Errorf("Expected POST rule to apply, got %d", writer.Code)
+		}
+
+		for i := 0; i < 3; i++ {
+			if writer := send(router, "GET", "1.1.1.1", ""); writer.Code != 200 {
+				t.Errorf("Expected wildcard rule to apply to GET, got %d", writer.Code)
+			}
+		}
+	})
+
+	t.Run("Limits By Email", func(t *testing.T) {
+		router := newRouter(&RateLimiter{
+			Store: NewMemoryRateLimitStore(),
+			Rules: []RateLimitRule{{Group: "public", Method: "POST", Requests: 1, Period: time.Minute, ByEmail: true}},
+		})
+
+		body := `{"email": "Alice@example.com"}`
+		writer := send(router, "POST", "1.1.1.1", body)
+		if writer.Code != 201 || writer.Body.String() != body {
+			t.Fatalf("Expected body to be passed to handler, got %d %s", writer.Code, writer.Body.String())
+		}
+
+		// the same email from another IP is still limited
+		if writer := send(router, "POST", "2.2.2.2", `{"email": "alice@example.com"}`); writer.Code != 429 {
+			t.Errorf("Expected status code 429 for the same email, got %d", writer.Code)
+		}
+
+		if writer := send(router, "POST", "3.3.3.3", `{"email": "bob@example.com"}`); writer.Code != 201 {
+			t.Errorf("Expected status code 201 for another email, got %d", writer.Code)
+		}
+	})
+
+	t.Run("Allows Requests If Store Fails", func(t *testing.T) {
+		router := newRouter(&RateLimiter{
+			Store: failingRateLimitStore{},
+			Rules: []RateLimitRule{{Group: "public", Requests: 1, Period: time.Minute}},
+		})
+
+		if writer := send(router, "GET", "1.1.1.1", ""); writer.Code != 200 {
+			t.Errorf("Expected status code 200, got %d", writer.Code)
+		}
+	})
+
+	t.Run("No Rules", func(t *testing.T) {
+		router := newRouter(&RateLimiter{})
+
+		writer := send(router, "GET", "1.1.1.1", "")
+		if writer.Code != 200 || writer.Header().Get("X-RateLimit-Limit") != "" {
+			t.Errorf("Expected request without rate limit headers, got %d %v", writer.Code, writer.Header())
+		}
+	})
+}
+
+func TestLoadRateLimitRules(t *testing.T) {
+	write := func(t *testing.T, contents string) string {
+		path := filepath.Join(t.TempDir(), "rate_limits.json")
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return path
+	}
+
+	t.Run("Valid Rules", func(t *testing.T) {
+		path := write(t, `[
+			{"group": "public", "method": "post", "requests": 5, "period": "10m", "by_email": true},
+			{"group": "admin", "method": "*", "requests": 100, "period": "1m", "burst": 20}
+		]`)
+
+		rules, err := LoadRateLimitRules(path)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(rules) != 2 || rules[0].Method != "POST" || rules[0].Period != 10*time.Minute || !rules[0].ByEmail {
+			t.Errorf("Expected normalized POST rule, got %+v", rules)
+		}
+
+		if rules[1].Method != "" || rules[1].limit().Burst != 20 {
+			t.Errorf("Expected wildcard admin rule with burst 20, got %+v", rules[1])
+		}
+
+		if limit := rules[0].limit(); limit.Burst != 5 {
+			t.Errorf("Expected burst to default to requests, got %d", limit.Burst)
+		}
+	})
+
+	invalid := map[string]string{
+		"Unknown Group":   `[{"group": "private", "requests": 5, "period": "1m"}]`,
+		"Missing Period":  `[{"group": "public", "requests": 5}]`,
+		"Invalid Period":  `[{"group": "public", "requests": 5, "period": "-1m"}]`,
+		"No Requests":     `[{"group": "public", "requests": 0, "period": "1m"}]`,
+		"Duplicate Rules": `[{"group": "public", "method": "GET", "requests": 5, "period": "1m"}, {"group": "public", "method": "get", "requests": 1, "period": "1m"}]`,
+	}
+	for name, contents := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadRateLimitRules(write(t, contents)); err == nil {
+				t.Errorf("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	t.Run("Disabled", func(t *testing.T) {
+		limiter, err := NewRateLimiter(&Config{}, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(limiter.Rules) != 0 {
+			t.Errorf("Expected no rules when disabled, got %+v", limiter.Rules)
+		}
+	})
+
+	t.Run("Default Rules", func(t *testing.T) {
+		limiter, err := NewRateLimiter(&Config{RateLimitEnabled: true, RateLimitStore: RateLimitStoreMemory}, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if _, ok := limiter.Store.(*MemoryRateLimitStore); !ok || len(limiter.Rules) != len(DefaultRateLimitRules) {
+			t.Errorf("Expected memory store with default rules, got %T %+v", limiter.Store, limiter.Rules)
+		}
+	})
+
+	t.Run("Postgres Store Requires Postgres Backend", func(t *testing.T) {
+		db, _ := NewMemoryPersistence("")
+		if _, err := NewRateLimiter(&Config{RateLimitEnabled: true, RateLimitStore: RateLimitStorePostgres}, db); err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// loggedExchange is a request and the response sent to it.
+type loggedExchange struct {
+	Request  LoggedRequest
+	Response LoggedResponse
+}
+
+// loggedEntry is a queued exchange or resume download.
+type loggedEntry struct {
+	Exchange *loggedExchange
+	Download *ResumeDownload
+}
+
+// RequestLogger writes logged requests and responses, and downloads of
+// the resume, to the database from a background worker, so that logging
+// never adds queries to the request path. Entries are written in batches
+// once BatchSize entries are queued or every FlushInterval, whichever
+// comes first.
+type RequestLogger struct {
+	BatchSize     int
+	FlushInterval time.Duration
+
+	db      Persistence
+	queue   chan loggedEntry
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+	mu      sync.RWMutex
+	closed  bool
+	dropped atomic.Int64
+}
+
+// NewRequestLogger creates the RequestLogger configured in the
+// given config and starts its background worker.
+func NewRequestLogger(cfg *Config, db Persistence) *RequestLogger {
+	logger := &RequestLogger{
+		BatchSize:     cfg.RequestLogBatchSize,
+		FlushInterval: cfg.RequestLogFlushInterval,
+		db:            db,
+	}
+	logger.Start(cfg.RequestLogQueueSize)
+	return logger
+}
+
+// Start starts the background worker that writes queued entries. At
+// most queueSize entries are buffered, and entries logged while the
+// queue is full are dropped.
+func (l *RequestLogger) Start(queueSize int) {
+	l.BatchSize = max(l.BatchSize, 1)
+	if l.FlushInterval <= 0 {
+		l.FlushInterval = time.Second
+	}
+	l.queue = make(chan loggedEntry, max(queueSize, 1))
+	l.ctx, l.cancel = context.WithCancel(context.Background())
+	l.done = make(chan struct{})
+
+	go func() {
+		defer close(l.done)
+		l.run()
+	}()
+}
+
+// Log queues a request and its response without blocking.
+func (l *RequestLogger) Log(request LoggedRequest, response LoggedResponse) {
+	request.ID = strings.ReplaceAll(uuid.New().String(), "-", "")
+	response.RequestId = request.ID
+	l.enqueue(loggedEntry{Exchange: &loggedExchange{Request: request, Response: response}})
+}
+
+// LogDownload queues a download of the resume without blocking.
+func (l *RequestLogger) LogDownload(download ResumeDownload) {
+	l.enqueue(loggedEntry{Download: &download})
+}
+
+// enqueue queues an entry, dropping it if the queue is full.
+func (l *RequestLogger) enqueue(entry loggedEntry) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.closed {
+		l.dropped.Add(1)
+		return
+	}
+	select {
+	case l.queue <- entry:
+	default:
+		l.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of entries dropped since
+// startup because the queue was full or the logger closed.
+func (l *RequestLogger) Dropped() int64 {
+	return l.dropped.Load()
+}
+
+// Close stops accepting entries and waits for queued entries to be
+// written. A write still in progress is abandoned once the context is
+// done.
+func (l *RequestLogger) Close(ctx context.Context) error {
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.queue)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-l.done:
+		l.cancel()
+		return nil
+	case <-ctx.Done():
+		l.cancel()
+		<-l.done
+		return ctx.Err()
+	}
+}
+
+// run collects queued entries into batches until the queue is closed,
+// and writes the final batch before returning.
+func (l *RequestLogger) run() {
+	ticker := time.NewTicker(l.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]loggedEntry, 0, l.BatchSize)
+	var reported int64
+	for {
+		select {
+		case entry, ok := <-l.queue:
+			if !ok {
+				l.flush(batch)
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) < l.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+			// drops are reported once per interval rather
+			// than for every entry while the queue is full
+			if dropped := l.Dropped(); dropped > reported {
+				log.Warn(fmt.Sprintf("request log queue full, dropped %d entries", dropped-reported))
+				reported = dropped
+			}
+		}
+		l.flush(batch)
+		batch = batch[:0]
+	}
+}
+
+// flush writes a batch of entries to the database. Requests are
+// written together, while downloads are recorded one at a time.
+func (l *RequestLogger) flush(batch []loggedEntry) {
+	var requests []LoggedRequest
+	var responses []LoggedResponse
+	for _, entry := range batch {
+		if entry.Download != nil {
+			if err := l.db.RecordResumeDownload(l.ctx, *entry.Download); err != nil {
+				log.Warn(fmt.Sprintf("failed to record resume download: %v", err))
+			}
+			continue
+		}
+		requests = append(requests, entry.Exchange.Request)
+		responses = append(responses, entry.Exchange.Response)
+	}
+
+	if len(requests) == 0 {
+		return
+	}
+	if err := l.db.LogRequests(l.ctx, requests, responses); err != nil {
+		log.Warn(fmt.Sprintf("failed to log %d requests: %v", len(requests), err))
+	}
+}
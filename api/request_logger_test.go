@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestRequestLogger starts a RequestLogger writing
+// to the given persistence, which is closed after the test.
+func newTestRequestLogger(t *testing.T, db Persistence) *RequestLogger {
+	t.Helper()
+
+	logger := &RequestLogger{BatchSize: 10, FlushInterval: time.Hour, db: db}
+	logger.Start(100)
+	t.Cleanup(func() { logger.Close(context.Background()) })
+	return logger
+}
+
+// blockingPersistence blocks writes until it is released, and
+// records the size of each batch of requests it receives.
+type blockingPersistence struct {
+	TestPersistence
+	release chan struct{}
+	mu      sync.Mutex
+	batches []int
+}
+
+func (b *blockingPersistence) LogRequests(ctx context.Context, requests []LoggedRequest, responses []LoggedResponse) error {
+	<-b.release
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.batches = append(b.batches, len(requests))
+	return b.TestPersistence.LogRequests(ctx, requests, responses)
+}
+
+func TestRequestLogger(t *testing.T) {
+
+	t.Run("Drains On Close", func(t *testing.T) {
+		persistence := &TestPersistence{}
+		logger := &RequestLogger{BatchSize: 2, FlushInterval: time.Hour, db: persistence}
+		logger.Start(10)
+
+		for range 5 {
+			logger.Log(LoggedRequest{Method: "GET", Path: "/health"}, LoggedResponse{Status: 200})
+		}
+		if err := logger.Close(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(persistence.LoggedRequests) != 5 || len(persistence.LoggedResponses) != 5 {
+			t.Fatalf("Expected 5 requests and responses, got %d and %d", len(persistence.LoggedRequests), len(persistence.LoggedResponses))
+		}
+		for i, request := range persistence.LoggedRequests {
+			if request.ID == "" || persistence.LoggedResponses[i].RequestId != request.ID {
+				t.Errorf("Expected response to reference request %q, got %+v", request.ID, persistence.LoggedResponses[i])
+			}
+		}
+
+		// requests logged after closing are dropped
+		logger.Log(LoggedRequest{}, LoggedResponse{})
+		if dropped := logger.Dropped(); dropped != 1 {
+			t.Errorf("Expected 1 dropped request, got %d", dropped)
+		}
+	})
+
+	t.Run("Records Downloads", func(t *testing.T) {
+		persistence := &TestPersistence{}
+		logger := &RequestLogger{BatchSize: 10, FlushInterval: time.Hour, db: persistence}
+		logger.Start(10)
+
+		logger.Log(LoggedRequest{Method: "GET", Path: "/resume"}, LoggedResponse{Status: 200})
+		logger.LogDownload(ResumeDownload{Format: ResumeFormatPDF, Variant: "default", Locale: "en"})
+		logger.LogDownload(ResumeDownload{Format: ResumeFormatJSON, Variant: "default", Locale: "en"})
+		if err := logger.Close(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(persistence.LoggedRequests) != 1 || persistence.LoggedRequests[0].Path != "/resume" {
+			t.Errorf("Expected 1 logged request, got %+v", persistence.LoggedRequests)
+		}
+		if len(persistence.ResumeDownloads) != 2 || persistence.ResumeDownloads[0].Format != ResumeFormatPDF {
+			t.Errorf("Expected 2 resume downloads in order, got %+v", persistence.ResumeDownloads)
+		}
+	})
+
+	t.Run("Batches By Size", func(t *testing.T) {
+		persistence := &blockingPersistence{release: make(chan struct{})}
+		close(persistence.release)
+		logger := &RequestLogger{BatchSize: 3, FlushInterval: time.Hour, db: persistence}
+		logger.Start(10)
+
+		for range 7 {
+			logger.Log(LoggedRequest{}, LoggedResponse{})
+		}
+		logger.Close(context.Background())
+
+		// the final partial batch is written on close
+		if !slices.Equal(persistence.batches, []int{3, 3, 1}) {
+			t.Errorf("Expected batches of 3, 3 and 1, got %v", persistence.batches)
+		}
+	})
+
+	t.Run("Flushes On Interval", func(t *testing.T) {
+		persistence := &blockingPersistence{release: make(chan struct{})}
+		close(persistence.release)
+		logger := &RequestLogger{BatchSize: 100, FlushInterval: 10 * time.Millisecond, db: persistence}
+		logger.Start(10)
+		defer logger.Close(context.Background())
+
+		logger.Log(LoggedRequest{}, LoggedResponse{})
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			persistence.mu.Lock()
+			flushed := len(persistence.batches)
+			persistence.mu.Unlock()
+			if flushed == 1 {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Errorf("Expected request to be flushed within the interval")
+	})
+
+	t.Run("Drops When Full", func(t *testing.T) {
+		persistence := &blockingPersistence{release: make(chan struct{})}
+		logger := &RequestLogger{BatchSize: 1, FlushInterval: time.Hour, db: persistence}
+		logger.Start(2)
+
+		// the worker blocks on the first request, so only
+		// two more requests fit into the queue
+		logger.Log(LoggedRequest{}, LoggedResponse{})
+		for len(logger.queue) > 0 {
+			time.Sleep(time.Millisecond)
+		}
+		for range 5 {
+			logger.Log(LoggedRequest{}, LoggedResponse{})
+		}
+		if dropped := logger.Dropped(); dropped != 3 {
+			t.Errorf("Expected 3 dropped requests, got %d", dropped)
+		}
+
+		close(persistence.release)
+		logger.Close(context.Background())
+		if len(persistence.LoggedRequests) != 3 {
+			t.Errorf("Expected 3 logged requests, got %d", len(persistence.LoggedRequests))
+		}
+	})
+
+	t.Run("Close Timeout", func(t *testing.T) {
+		persistence := &blockingPersistence{release: make(chan struct{})}
+		logger := &RequestLogger{BatchSize: 1, FlushInterval: time.Hour, db: persistence}
+		logger.Start(1)
+		logger.Log(LoggedRequest{}, LoggedResponse{})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		go func() {
+			<-ctx.Done()
+			close(persistence.release)
+		}()
+		if err := logger.Close(ctx); err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+}
@@ -1,6 +1,17 @@
 package main
 
-import "github.com/gin-gonic/gin"
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+)
 
 var (
 	// 400 Bad Request
@@ -19,6 +30,52 @@ var (
 		Payload: ForbiddenPayload,
 	}
 
+	// 404 Not Found
+	NotFoundPayload = gin.H{"error": "Not Found"}
+
+	NotFoundResponse = RESTResponse{
+		Code:    404,
+		Payload: NotFoundPayload,
+	}
+
+	// 406 Not Acceptable
+	NotAcceptablePayload = gin.H{"error": "Not Acceptable"}
+
+	// 409 Conflict
+	ConflictPayload = gin.H{"error": "Conflict"}
+
+	ConflictResponse = RESTResponse{
+		Code:    409,
+		Payload: ConflictPayload,
+	}
+
+	// 410 Gone
+	GonePayload = gin.H{"error": "Gone"}
+
+	GoneResponse = RESTResponse{
+		Code:    410,
+		Payload: GonePayload,
+	}
+
+	// 413 Content Too Large
+	ContentTooLargePayload = gin.H{"error": "Content Too Large"}
+
+	ContentTooLargeResponse = RESTResponse{
+		Code:    413,
+		Payload: ContentTooLargePayload,
+	}
+
+	// 415 Unsupported Media Type
+	UnsupportedMediaTypePayload = gin.H{"error": "Unsupported Media Type"}
+
+	UnsupportedMediaTypeResponse = RESTResponse{
+		Code:    415,
+		Payload: UnsupportedMediaTypePayload,
+	}
+
+	// 429 Too Many Requests
+	TooManyRequestsPayload = gin.H{"error": "Too Many Requests"}
+
 	// 500 Internal Server Error
 	InternalServerErrorPayload = gin.H{"error": "Internal Server Error"}
 
@@ -34,4 +91,115 @@ var (
 		Code:    501,
 		Payload: NotImplementedPayload,
 	}
+
+	// 503 Service Unavailable
+	ServiceUnavailablePayload = gin.H{"error": "Service Unavailable"}
+
+	ServiceUnavailableResponse = RESTResponse{
+		Code:    503,
+		Payload: ServiceUnavailablePayload,
+	}
+
+	// 504 Gateway Timeout
+	GatewayTimeoutPayload = gin.H{"error": "Gateway Timeout"}
+
+	GatewayTimeoutResponse = RESTResponse{
+		Code:    504,
+		Payload: GatewayTimeoutPayload,
+	}
 )
+
+// NotAcceptableResponse returns a 406 listing
+// the media types that can be served.
+func NotAcceptableResponse(supported []string) RESTResponse {
+	return RESTResponse{
+		Code: 406,
+		Payload: gin.H{
+			"error":     NotAcceptablePayload["error"],
+			"supported": supported,
+		},
+	}
+}
+
+// PersistenceErrorResponse maps an error returned by the persistence
+// layer to the matching response, defaulting to a 500.
+func PersistenceErrorResponse(err error) RESTResponse {
+	var errNotFound ContactNotFoundError
+	var errRequestNotFound ContactRequestNotFoundError
+	var errVersionNotFound ResumeVersionNotFoundError
+	var errShareNotFound ResumeShareNotFoundError
+	var errShareUsed ResumeShareUsedError
+	var errConflict ContactConflictError
+	var errTransition InvalidStatusTransitionError
+
+	switch {
+	case errors.As(err, &errNotFound), errors.As(err, &errRequestNotFound), errors.As(err, &errVersionNotFound),
+		errors.As(err, &errShareNotFound):
+		return NotFoundResponse
+	case errors.As(err, &errConflict), errors.As(err, &errTransition):
+		return ConflictResponse
+	case errors.As(err, &errShareUsed):
+		return GoneResponse
+	case errors.Is(err, context.DeadlineExceeded) || pgconn.Timeout(err):
+		return GatewayTimeoutResponse
+	case errors.Is(err, context.Canceled):
+		return ServiceUnavailableResponse
+	default:
+		return InternalServerErrorResponse
+	}
+}
+
+// FileResponse is a file that is sent as an attachment
+// instead of being encoded into a JSON payload.
+type FileResponse struct {
+	Name        string
+	ContentType string
+	Content     []byte
+	ETag        string
+	ModTime     time.Time
+}
+
+// Send streams the file to the client. Range requests and
+// conditional requests using If-None-Match, If-Modified-Since
+// and If-Range are handled by http.ServeContent.
+func (f *FileResponse) Send(c *gin.Context) {
+	f.setHeaders(c, f.ETag)
+	http.ServeContent(c.Writer, c.Request, f.Name, f.ModTime, bytes.NewReader(f.Content))
+}
+
+// setHeaders sets the headers describing the file.
+func (f *FileResponse) setHeaders(c *gin.Context, etag string) {
+	c.Header("Content-Type", f.ContentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": f.Name}))
+	if etag != "" {
+		c.Header("ETag", etag)
+	}
+}
+
+// notModified returns whether a GET or HEAD request is conditional on a
+// representation other than the one identified by the given validators.
+// If-None-Match takes precedence over If-Modified-Since.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r == nil || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return false
+	}
+
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		if etag == "" {
+			return false
+		}
+		for _, candidate := range strings.Split(header, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || lastModified.IsZero() {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
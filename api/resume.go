@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"sync"
+	texttemplate "text/template"
+)
+
+//go:embed templates/resume
+var resumeTemplateFiles embed.FS
+
+// ResumeRenderer renders the JSON resume into other formats. HTML
+// is rendered with html/template, so that resume content is escaped,
+// while Markdown and plain text are rendered with text/template.
+// PDFs are laid out using the configured theme.
+type ResumeRenderer struct {
+	Theme ResumePDFTheme
+
+	html     *htmltemplate.Template
+	text     *texttemplate.Template
+	mu       sync.Mutex
+	pdfCache map[string]resumePDF
+}
+
+// parseTemplateGlobs parses the files in fsys matching the given
+// patterns, skipping patterns that do not match any files.
+func parseTemplateGlobs(fsys fs.FS, parse func(fs.FS, ...string) error, patterns ...string) error {
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		if err := parse(fsys, pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadResumeTemplates parses the embedded resume templates. Templates in
+// dir, if given, are parsed afterwards, so any template defined in dir
+// replaces the embedded template with the same name. Files ending in
+// .html.tmpl define HTML templates, and files ending in .md.tmpl or
+// .txt.tmpl define Markdown and plain text templates.
+func LoadResumeTemplates(dir string) (*ResumeRenderer, error) {
+	embedded, err := fs.Sub(resumeTemplateFiles, "templates/resume")
+	if err != nil {
+		return nil, err
+	}
+
+	renderer := &ResumeRenderer{
+		Theme:    DefaultResumePDFTheme,
+		html:     htmltemplate.New("resume"),
+		text:     texttemplate.New("resume"),
+		pdfCache: map[string]resumePDF{},
+	}
+	parseHTML := func(fsys fs.FS, patterns ...string) error {
+		_, err := renderer.html.ParseFS(fsys, patterns...)
+		return err
+	}
+	parseText := func(fsys fs.FS, patterns ...string) error {
+		_, err := renderer.text.ParseFS(fsys, patterns...)
+		return err
+	}
+
+	sources := []fs.FS{embedded}
+	if dir != "" {
+		sources = append(sources, os.DirFS(dir))
+	}
+	for _, fsys := range sources {
+		if err := parseTemplateGlobs(fsys, parseHTML, "*.html.tmpl"); err != nil {
+			return nil, err
+		}
+		if err := parseTemplateGlobs(fsys, parseText, "*.md.tmpl", "*.txt.tmpl"); err != nil {
+			return nil, err
+		}
+	}
+	return renderer, nil
+}
+
+// Render executes the template named after the given format.
+func (r *ResumeRenderer) Render(format ResumeFileFormat, resume *Resume) ([]byte, error) {
+	var buffer bytes.Buffer
+	var err error
+	switch format {
+	case ResumeFormatHTML:
+		err = r.html.ExecuteTemplate(&buffer, string(format), resume)
+	case ResumeFormatMarkdown, ResumeFormatText:
+		err = r.text.ExecuteTemplate(&buffer, string(format), resume)
+	default:
+		return nil, fmt.Errorf("cannot render resume as %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// ResumeChangeType is the type of a change between two JSON resumes.
+type ResumeChangeType string
+
+const (
+	ResumeChangeAdded   ResumeChangeType = "added"
+	ResumeChangeRemoved ResumeChangeType = "removed"
+	ResumeChangeChanged ResumeChangeType = "changed"
+)
+
+// ResumeChange is a single value that differs between two JSON resumes.
+// The path uses the same format as validation errors, e.g. work[0].position.
+type ResumeChange struct {
+	Path string           `json:"path"`
+	Type ResumeChangeType `json:"type"`
+	From any              `json:"from,omitempty"`
+	To   any              `json:"to,omitempty"`
+}
+
+// DiffResumes returns the changes between two JSON resumes, ordered by
+// path. Arrays are compared by index, so inserting an entry at the start
+// of a list changes every later entry.
+func DiffResumes(from, to []byte) ([]ResumeChange, error) {
+	var fromValue, toValue any
+	if err := json.Unmarshal(from, &fromValue); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(to, &toValue); err != nil {
+		return nil, err
+	}
+	return diffJSON("", fromValue, toValue, []ResumeChange{}), nil
+}
+
+// joinJSONPath appends an object key to the given path.
+func joinJSONPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// diffJSON appends the changes between two decoded JSON values to changes.
+func diffJSON(path string, from, to any, changes []ResumeChange) []ResumeChange {
+	switch fromValue := from.(type) {
+	case map[string]any:
+		if toValue, ok := to.(map[string]any); ok {
+			keys := slices.Collect(maps.Keys(fromValue))
+			for key := range toValue {
+				if _, ok := fromValue[key]; !ok {
+					keys = append(keys, key)
+				}
+			}
+			slices.Sort(keys)
+
+			for _, key := range keys {
+				f, inFrom := fromValue[key]
+				t, inTo := toValue[key]
+				switch {
+				case !inFrom:
+					changes = append(changes, ResumeChange{Path: joinJSONPath(path, key), Type: ResumeChangeAdded, To: t})
+				case !inTo:
+					changes = append(changes, ResumeChange{Path: joinJSONPath(path, key), Type: ResumeChangeRemoved, From: f})
+				default:
+					changes = diffJSON(joinJSONPath(path, key), f, t, changes)
+				}
+			}
+			return changes
+		}
+	case []any:
+		if toValue, ok := to.([]any); ok {
+			for i := range max(len(fromValue), len(toValue)) {
+				itemPath := fmt.Sprintf("%s[%d]", path, i)
+				switch {
+				case i >= len(fromValue):
+					changes = append(changes, ResumeChange{Path: itemPath, Type: ResumeChangeAdded, To: toValue[i]})
+				case i >= len(toValue):
+					changes = append(changes, ResumeChange{Path: itemPath, Type: ResumeChangeRemoved, From: fromValue[i]})
+				default:
+					changes = diffJSON(itemPath, fromValue[i], toValue[i], changes)
+				}
+			}
+			return changes
+		}
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		changes = append(changes, ResumeChange{Path: path, Type: ResumeChangeChanged, From: from, To: to})
+	}
+	return changes
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffResumes(t *testing.T) {
+	from := []byte(`{
+		"basics": {"name": "Jane Doe", "label": "Engineer"},
+		"work": [{"name": "Acme", "position": "Engineer"}],
+		"skills": [{"name": "Go"}, {"name": "SQL"}]
+	}`)
+	to := []byte(`{
+		"basics": {"name": "Jane Doe", "email": "jane@example.com"},
+		"work": [{"name": "Acme", "position": "Senior Engineer"}, {"name": "Initech"}],
+		"skills": [{"name": "Go"}]
+	}`)
+
+	changes, err := DiffResumes(from, to)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []ResumeChange{
+		{Path: "basics.email", Type: ResumeChangeAdded, To: "jane@example.com"},
+		{Path: "basics.label", Type: ResumeChangeRemoved, From: "Engineer"},
+		{Path: "skills[1]", Type: ResumeChangeRemoved, From: map[string]any{"name": "SQL"}},
+		{Path: "work[0].position", Type: ResumeChangeChanged, From: "Engineer", To: "Senior Engineer"},
+		{Path: "work[1]", Type: ResumeChangeAdded, To: map[string]any{"name": "Initech"}},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected changes %+v, got %+v", expected, changes)
+	}
+
+	if changes, _ := DiffResumes(from, from); len(changes) != 0 {
+		t.Errorf("Expected no changes, got %+v", changes)
+	}
+	if _, err := DiffResumes(from, []byte(`{`)); err == nil {
+		t.Errorf("Expected error for invalid JSON")
+	}
+}
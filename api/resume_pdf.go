@@ -0,0 +1,281 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// resumePDFCacheSize limits the number of generated PDFs kept in memory
+const resumePDFCacheSize = 16
+
+// page sizes in points
+var resumePDFPageSizes = map[string][2]float64{
+	"a4":     {595.28, 841.89},
+	"letter": {612, 792},
+}
+
+// resumePDFSections lists the resume sections that can be rendered
+var resumePDFSections = []string{"experience", "education", "skills"}
+
+// ResumePDFTheme controls the layout and appearance of generated PDFs.
+type ResumePDFTheme struct {
+	Title    string
+	PageSize string
+	Margin   float64
+	FontSize float64
+	Accent   PDFColor
+	// Sections lists the resume sections
+	// to render, in the order they appear
+	Sections []string
+}
+
+// DefaultResumePDFTheme is used unless a theme is configured.
+var DefaultResumePDFTheme = ResumePDFTheme{
+	Title:    "Resume",
+	PageSize: "a4",
+	Margin:   48,
+	FontSize: 10,
+	Accent:   PDFColor{31.0 / 255, 79.0 / 255, 120.0 / 255},
+	Sections: []string{"experience", "education", "skills"},
+}
+
+// NewResumePDFTheme creates the ResumePDFTheme configured in the given
+// config. Settings that are not configured are taken from the default theme.
+func NewResumePDFTheme(cfg *Config) (ResumePDFTheme, error) {
+	theme := DefaultResumePDFTheme
+	if cfg.ResumePDFTitle != "" {
+		theme.Title = cfg.ResumePDFTitle
+	}
+	if cfg.ResumePDFPageSize != "" {
+		theme.PageSize = cfg.ResumePDFPageSize
+	}
+	if cfg.ResumePDFMargin > 0 {
+		theme.Margin = cfg.ResumePDFMargin
+	}
+	if cfg.ResumePDFFontSize > 0 {
+		theme.FontSize = cfg.ResumePDFFontSize
+	}
+	if cfg.ResumePDFAccentColor != "" {
+		accent, err := ParsePDFColor(cfg.ResumePDFAccentColor)
+		if err != nil {
+			return ResumePDFTheme{}, err
+		}
+		theme.Accent = accent
+	}
+	if len(cfg.ResumePDFSections) > 0 {
+		theme.Sections = cfg.ResumePDFSections
+	}
+
+	if _, ok := resumePDFPageSizes[theme.PageSize]; !ok {
+		return ResumePDFTheme{}, fmt.Errorf("unknown page size %q", theme.PageSize)
+	}
+	for _, section := range theme.Sections {
+		if !slices.Contains(resumePDFSections, section) {
+			return ResumePDFTheme{}, fmt.Errorf("unknown resume section %q, expected one of %s", section, strings.Join(resumePDFSections, ","))
+		}
+	}
+	return theme, nil
+}
+
+// resumePDF is a generated PDF along with its ETag.
+type resumePDF struct {
+	content []byte
+	etag    string
+}
+
+// RenderPDF generates a PDF from the given resume. Generated PDFs are
+// cached using the hash of the JSON resume, so that the PDF is only
+// generated again once the resume changes. The returned ETag is derived
+// from the PDF itself, so that it also changes if the theme changes.
+func (r *ResumeRenderer) RenderPDF(resume *Resume, key string) ([]byte, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cached, ok := r.pdfCache[key]; ok {
+		return cached.content, cached.etag, nil
+	}
+
+	content, err := layoutResumePDF(r.Theme, resume)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// the cache is cleared rather than evicting individual
+	// entries, as the resume rarely changes
+	if len(r.pdfCache) >= resumePDFCacheSize {
+		clear(r.pdfCache)
+	}
+	etag := sha256.Sum256(content)
+	r.pdfCache[key] = resumePDF{content: content, etag: `"` + hex.EncodeToString(etag[:16]) + `"`}
+	return content, r.pdfCache[key].etag, nil
+}
+
+// pdfLayout places content on the pages of a document from top
+// to bottom, starting new pages once the bottom margin is reached.
+type pdfLayout struct {
+	doc   *PDFDocument
+	theme ResumePDFTheme
+	y     float64
+}
+
+var (
+	pdfTextColor  = PDFColor{0.13, 0.13, 0.13}
+	pdfMutedColor = PDFColor{0.4, 0.4, 0.4}
+)
+
+// width returns the width available for content.
+func (l *pdfLayout) width() float64 {
+	return l.doc.Width - 2*l.theme.Margin
+}
+
+// lineHeight returns the height of a line of text in the given size.
+func (l *pdfLayout) lineHeight(size float64) float64 {
+	return size * 1.35
+}
+
+// ensure starts a new page unless there is
+// enough space left for the given height.
+func (l *pdfLayout) ensure(height float64) {
+	if len(l.doc.pages) == 0 || l.y-height < l.theme.Margin {
+		l.doc.AddPage()
+		l.y = l.doc.Height - l.theme.Margin
+	}
+}
+
+// space moves down the page by the given height.
+func (l *pdfLayout) space(height float64) {
+	l.y -= height
+}
+
+// paragraph draws text wrapped to the available width. Bullet
+// points are drawn with the text indented past the bullet.
+func (l *pdfLayout) paragraph(text string, font PDFFont, size float64, color PDFColor, bullet bool) {
+	x := l.theme.Margin
+	if bullet {
+		x += size * 1.5
+	}
+
+	height := l.lineHeight(size)
+	for i, line := range wrapPDFText(text, font, size, l.width()-(x-l.theme.Margin)) {
+		l.ensure(height)
+		l.space(height)
+		if bullet && i == 0 {
+			l.doc.Text(l.theme.Margin+size*0.5, l.y, "•", PDFFontRegular, size, l.theme.Accent)
+		}
+		l.doc.Text(x, l.y, line, font, size, color)
+	}
+}
+
+// heading draws a section heading, keeping it on the
+// same page as at least a few lines of its content.
+func (l *pdfLayout) heading(text string) {
+	size := l.theme.FontSize * 1.4
+	l.ensure(l.lineHeight(size) + 4*l.lineHeight(l.theme.FontSize))
+	l.space(size)
+	l.paragraph(strings.ToUpper(text), PDFFontBold, size, l.theme.Accent, false)
+	l.space(3)
+	l.doc.Line(l.theme.Margin, l.y, l.doc.Width-l.theme.Margin, l.y, 0.75, l.theme.Accent)
+	l.space(2)
+}
+
+// wrapPDFText splits text into lines that fit within the given width.
+// Words wider than the width are placed on a line of their own.
+func wrapPDFText(text string, font PDFFont, size float64, width float64) []string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if line != "" && PDFTextWidth(candidate, font, size) > width {
+			lines = append(lines, line)
+			candidate = word
+		}
+		line = candidate
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// joinResumeFields joins the non-empty fields with the separator.
+func joinResumeFields(separator string, fields ...string) string {
+	var nonEmpty []string
+	for _, field := range fields {
+		if field != "" {
+			nonEmpty = append(nonEmpty, field)
+		}
+	}
+	return strings.Join(nonEmpty, separator)
+}
+
+// layoutResumePDF lays out the sections of the resume
+// configured in the theme, and serialises the document.
+func layoutResumePDF(theme ResumePDFTheme, resume *Resume) ([]byte, error) {
+	size, ok := resumePDFPageSizes[theme.PageSize]
+	if !ok {
+		return nil, fmt.Errorf("unknown page size %q", theme.PageSize)
+	}
+
+	title := resume.Basics.Name
+	if title == "" {
+		title = theme.Title
+	}
+	layout := &pdfLayout{
+		doc:   &PDFDocument{Width: size[0], Height: size[1], Title: title},
+		theme: theme,
+	}
+	base := theme.FontSize
+	layout.paragraph(title, PDFFontBold, base*2.2, theme.Accent, false)
+	layout.paragraph(resume.Basics.Label, PDFFontRegular, base*1.2, pdfMutedColor, false)
+
+	for _, section := range theme.Sections {
+		switch section {
+		case "experience":
+			if len(resume.Work) > 0 {
+				layout.heading("Experience")
+			}
+			for _, work := range resume.Work {
+				layout.space(base * 0.6)
+				layout.paragraph(joinResumeFields(", ", work.Position, work.Name), PDFFontBold, base*1.1, pdfTextColor, false)
+				layout.paragraph(joinResumeFields(" · ", work.DateRange(), work.Location), PDFFontRegular, base*0.9, pdfMutedColor, false)
+				layout.paragraph(work.Summary, PDFFontRegular, base, pdfTextColor, false)
+				for _, highlight := range work.Highlights {
+					layout.paragraph(highlight, PDFFontRegular, base, pdfTextColor, true)
+				}
+				if len(work.Stack) > 0 {
+					layout.paragraph("Stack: "+strings.Join(work.Stack, ", "), PDFFontRegular, base*0.9, pdfMutedColor, false)
+				}
+			}
+
+		case "education":
+			if len(resume.Education) > 0 {
+				layout.heading("Education")
+			}
+			for _, education := range resume.Education {
+				layout.space(base * 0.6)
+				layout.paragraph(joinResumeFields(", ", education.Degree(), education.Institution), PDFFontBold, base*1.1, pdfTextColor, false)
+				layout.paragraph(joinResumeFields(" · ", education.DateRange(), education.Location), PDFFontRegular, base*0.9, pdfMutedColor, false)
+				layout.paragraph(education.Score, PDFFontRegular, base, pdfTextColor, false)
+			}
+
+		case "skills":
+			if len(resume.Skills) > 0 {
+				layout.heading("Skills")
+			}
+			for _, skill := range resume.Skills {
+				layout.space(base * 0.3)
+				layout.paragraph(skill.Name, PDFFontBold, base, pdfTextColor, false)
+				layout.paragraph(strings.Join(skill.Keywords, ", "), PDFFontRegular, base, pdfTextColor, false)
+			}
+
+		default:
+			return nil, fmt.Errorf("unknown resume section %q", section)
+		}
+	}
+	return layout.doc.Bytes()
+}
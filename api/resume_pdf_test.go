@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRenderPDF(t *testing.T) {
+	resume, err := LoadResume("etc/resume.json")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	t.Run("Cached", func(t *testing.T) {
+		renderer := newTestResumeRenderer(t)
+		first, etag, err := renderer.RenderPDF(resume, "resume")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if !bytes.HasPrefix(first, []byte("%PDF-")) || !strings.HasPrefix(etag, `"`) {
+			t.Errorf("Expected PDF with quoted ETag, got %q", etag)
+		}
+
+		second, secondETag, _ := renderer.RenderPDF(resume, "resume")
+		if &first[0] != &second[0] || etag != secondETag {
+			t.Errorf("Expected cached PDF to be returned")
+		}
+
+		// a new renderer generates an identical PDF
+		third, thirdETag, _ := newTestResumeRenderer(t).RenderPDF(resume, "resume")
+		if !bytes.Equal(first, third) || etag != thirdETag {
+			t.Errorf("Expected identical PDF from identical resume")
+		}
+	})
+
+	t.Run("Theme Changes ETag", func(t *testing.T) {
+		_, etag, _ := newTestResumeRenderer(t).RenderPDF(resume, "resume")
+
+		renderer := newTestResumeRenderer(t)
+		renderer.Theme.PageSize = "letter"
+		if _, letterETag, _ := renderer.RenderPDF(resume, "resume"); letterETag == etag {
+			t.Errorf("Expected different ETag for a different theme")
+		}
+	})
+
+	t.Run("Page Breaks", func(t *testing.T) {
+		var highlights []string
+		for range 200 {
+			highlights = append(highlights, "Delivered a long running project on time")
+		}
+		long := &Resume{Work: []ResumeWork{{Position: "Engineer", Highlights: highlights}}}
+
+		output, err := layoutResumePDF(DefaultResumePDFTheme, long)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if strings.Contains(string(output), "/Count 1 ") {
+			t.Errorf("Expected resume to span multiple pages")
+		}
+	})
+
+	t.Run("Changed Resume", func(t *testing.T) {
+		renderer := newTestResumeRenderer(t)
+		_, etag, _ := renderer.RenderPDF(resume, "resume")
+
+		changed := *resume
+		changed.Basics.Label = "Changed"
+		if _, changedETag, _ := renderer.RenderPDF(&changed, "changed"); changedETag == etag {
+			t.Errorf("Expected different ETag for a changed resume")
+		}
+	})
+}
+
+func TestWrapPDFText(t *testing.T) {
+	lines := wrapPDFText("aaa bbb  ccc", PDFFontRegular, 10, PDFTextWidth("aaa bbb", PDFFontRegular, 10))
+	if strings.Join(lines, "|") != "aaa bbb|ccc" {
+		t.Errorf("Expected wrapped lines, got %q", lines)
+	}
+
+	// words wider than the line are not split
+	lines = wrapPDFText("unbreakable word", PDFFontRegular, 10, 1)
+	if strings.Join(lines, "|") != "unbreakable|word" {
+		t.Errorf("Expected one word per line, got %q", lines)
+	}
+}
+
+func TestNewResumePDFTheme(t *testing.T) {
+	theme, err := NewResumePDFTheme(&Config{})
+	if err != nil || theme.PageSize != DefaultResumePDFTheme.PageSize {
+		t.Errorf("Expected default theme, got %+v %v", theme, err)
+	}
+
+	theme, err = NewResumePDFTheme(&Config{
+		ResumePDFPageSize:    "letter",
+		ResumePDFAccentColor: "#000000",
+		ResumePDFSections:    []string{"skills"},
+	})
+	if err != nil || theme.PageSize != "letter" || theme.Accent != (PDFColor{}) || len(theme.Sections) != 1 {
+		t.Errorf("Expected configured theme, got %+v %v", theme, err)
+	}
+
+	if _, err := NewResumePDFTheme(&Config{ResumePDFAccentColor: "blue"}); err == nil {
+		t.Errorf("Expected error for invalid accent color")
+	}
+
+	if _, err := NewResumePDFTheme(&Config{ResumePDFSections: []string{"experience", "work"}}); err == nil {
+		t.Errorf("Expected error for unknown section")
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// resumeSection returns the field of the resume with the given JSON name.
+func resumeSection(resume reflect.Value, name string) (reflect.Value, bool) {
+	for i := range resume.NumField() {
+		tag, _, _ := strings.Cut(resume.Type().Field(i).Tag.Get("json"), ",")
+		if tag == name && tag != "$schema" {
+			return resume.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// Section returns the section of the resume with the given name, e.g. "work".
+func (r *Resume) Section(name string) (any, error) {
+	section, ok := resumeSection(reflect.ValueOf(r).Elem(), name)
+	if !ok {
+		return nil, UnknownResumeSectionError{Name: name}
+	}
+	return section.Interface(), nil
+}
+
+// Select returns a copy of the resume containing only the given sections.
+func (r *Resume) Select(names ...string) (*Resume, error) {
+	selected := &Resume{Schema: r.Schema}
+	source, target := reflect.ValueOf(r).Elem(), reflect.ValueOf(selected).Elem()
+	for _, name := range names {
+		section, ok := resumeSection(source, name)
+		if !ok {
+			return nil, UnknownResumeSectionError{Name: name}
+		}
+		field, _ := resumeSection(target, name)
+		field.Set(section)
+	}
+	return selected, nil
+}
+
+// resumeDateBefore returns whether date is before since, comparing
+// the dates at the precision of the least precise date, so that
+// "2020" is not before "2020-06".
+func resumeDateBefore(date, since string) bool {
+	n := min(len(date), len(since))
+	return date[:n] < since[:n]
+}
+
+// Since returns a copy of the resume without dated entries that ended,
+// or took place, before the given date. Ongoing entries and entries
+// without dates are kept.
+func (r *Resume) Since(since string) (*Resume, error) {
+	if _, err := parseResumeDate(since); err != nil {
+		return nil, err
+	}
+
+	// an entry is dropped if its date is set and before since
+	dropped := func(date string) bool {
+		return date != "" && resumeDateBefore(date, since)
+	}
+
+	filtered := *r
+	filtered.Work = slices.DeleteFunc(slices.Clone(r.Work), func(w ResumeWork) bool { return dropped(w.EndDate) })
+	filtered.Volunteer = slices.DeleteFunc(slices.Clone(r.Volunteer), func(v ResumeVolunteer) bool { return dropped(v.EndDate) })
+	filtered.Education = slices.DeleteFunc(slices.Clone(r.Education), func(e ResumeEducation) bool { return dropped(e.EndDate) })
+	filtered.Projects = slices.DeleteFunc(slices.Clone(r.Projects), func(p ResumeProject) bool { return dropped(p.EndDate) })
+	filtered.Awards = slices.DeleteFunc(slices.Clone(r.Awards), func(a ResumeAward) bool { return dropped(a.Date) })
+	filtered.Certificates = slices.DeleteFunc(slices.Clone(r.Certificates), func(c ResumeCertificate) bool { return dropped(c.Date) })
+	filtered.Publications = slices.DeleteFunc(slices.Clone(r.Publications), func(p ResumePublication) bool { return dropped(p.ReleaseDate) })
+	return &filtered, nil
+}
+
+// ResumeQuery restricts the resume returned to clients.
+type ResumeQuery struct {
+	// Fields lists the sections to include, or all if empty
+	Fields []string
+	// Since removes entries that ended before the date
+	Since string
+}
+
+// IsZero returns whether the query returns the whole resume.
+func (q ResumeQuery) IsZero() bool {
+	return len(q.Fields) == 0 && q.Since == ""
+}
+
+// ParseResumeQuery reads the fields and since query parameters,
+// where fields is a comma separated list of sections.
+func ParseResumeQuery(fields, since string) ResumeQuery {
+	query := ResumeQuery{Since: since}
+	for _, field := range strings.Split(fields, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			query.Fields = append(query.Fields, field)
+		}
+	}
+	return query
+}
+
+// Apply returns the resume restricted by the query.
+func (q ResumeQuery) Apply(resume *Resume) (*Resume, error) {
+	var err error
+	if q.Since != "" {
+		if resume, err = resume.Since(q.Since); err != nil {
+			return nil, err
+		}
+	}
+	if len(q.Fields) > 0 {
+		if resume, err = resume.Select(q.Fields...); err != nil {
+			return nil, err
+		}
+	}
+	return resume, nil
+}
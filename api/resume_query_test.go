@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func newTestResume() *Resume {
+	return &Resume{
+		Basics: ResumeBasics{Name: "Jane Doe"},
+		Work: []ResumeWork{
+			{Name: "Current", Position: "Engineer", StartDate: "2021-03"},
+			{Name: "Recent", Position: "Engineer", StartDate: "2018-07", EndDate: "2020"},
+			{Name: "Old", Position: "Engineer", StartDate: "2015-01", EndDate: "2018-06"},
+		},
+		Education: []ResumeEducation{{Institution: "University", StartDate: "2012", EndDate: "2015"}},
+		Skills:    []ResumeSkill{{Name: "Languages", Keywords: []string{"Go"}}},
+	}
+}
+
+func TestResumeSection(t *testing.T) {
+	resume := newTestResume()
+
+	section, err := resume.Section("skills")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if skills, ok := section.([]ResumeSkill); !ok || len(skills) != 1 {
+		t.Errorf("Expected skills section, got %v", section)
+	}
+
+	for _, name := range []string{"unknown", "$schema", "Work"} {
+		if _, err := resume.Section(name); !errors.As(err, &UnknownResumeSectionError{}) {
+			t.Errorf("Expected UnknownResumeSectionError for %q, got %v", name, err)
+		}
+	}
+}
+
+func TestResumeSelect(t *testing.T) {
+	resume := newTestResume()
+
+	selected, err := resume.Select("work", "skills")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(selected.Work) != 3 || len(selected.Skills) != 1 || selected.Education != nil || selected.Basics.Name != "" {
+		t.Errorf("Expected only work and skills, got %+v", selected)
+	}
+
+	if _, err := resume.Select("work", "unknown"); !errors.As(err, &UnknownResumeSectionError{}) {
+		t.Errorf("Expected UnknownResumeSectionError, got %v", err)
+	}
+}
+
+func TestResumeSince(t *testing.T) {
+	resume := newTestResume()
+
+	filtered, err := resume.Since("2020-06")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var names []string
+	for _, work := range filtered.Work {
+		names = append(names, work.Name)
+	}
+	// entries ending in 2020 are kept, as the month is unknown
+	if !slices.Equal(names, []string{"Current", "Recent"}) {
+		t.Errorf("Expected ongoing and recent work, got %v", names)
+	}
+	if len(filtered.Education) != 0 || len(filtered.Skills) != 1 {
+		t.Errorf("Expected only dated entries to be filtered, got %+v", filtered)
+	}
+
+	// the original resume is unchanged
+	if len(resume.Work) != 3 || resume.Work[2].Name != "Old" {
+		t.Errorf("Expected original resume to be unchanged, got %+v", resume.Work)
+	}
+
+	if _, err := resume.Since("June 2020"); err == nil {
+		t.Errorf("Expected error for invalid date")
+	}
+}
+
+func TestResumeQuery(t *testing.T) {
+	query := ParseResumeQuery(" work, ,skills ", "2019")
+	if !slices.Equal(query.Fields, []string{"work", "skills"}) || query.Since != "2019" || query.IsZero() {
+		t.Errorf("Expected parsed query, got %+v", query)
+	}
+
+	resume, err := query.Apply(newTestResume())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(resume.Work) != 2 || resume.Education != nil {
+		t.Errorf("Expected filtered work only, got %+v", resume)
+	}
+
+	if !ParseResumeQuery("", "").IsZero() {
+		t.Errorf("Expected empty query")
+	}
+}
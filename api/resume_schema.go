@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Resume is a resume following version 1.0.0 of the JSON Resume schema,
+// see https://jsonresume.org/schema. Fields marked as extensions are not
+// part of the schema, but are used by the UI.
+type Resume struct {
+	Schema       string              `json:"$schema,omitempty"`
+	Basics       ResumeBasics        `json:"basics"`
+	Work         []ResumeWork        `json:"work,omitempty" validate:"dive"`
+	Volunteer    []ResumeVolunteer   `json:"volunteer,omitempty" validate:"dive"`
+	Education    []ResumeEducation   `json:"education,omitempty" validate:"dive"`
+	Awards       []ResumeAward       `json:"awards,omitempty" validate:"dive"`
+	Certificates []ResumeCertificate `json:"certificates,omitempty" validate:"dive"`
+	Publications []ResumePublication `json:"publications,omitempty" validate:"dive"`
+	Skills       []ResumeSkill       `json:"skills,omitempty" validate:"dive"`
+	Languages    []ResumeLanguage    `json:"languages,omitempty" validate:"dive"`
+	Interests    []ResumeInterest    `json:"interests,omitempty" validate:"dive"`
+	References   []ResumeReference   `json:"references,omitempty" validate:"dive"`
+	Projects     []ResumeProject     `json:"projects,omitempty" validate:"dive"`
+	Meta         *ResumeMeta         `json:"meta,omitempty"`
+}
+
+type ResumeBasics struct {
+	Name     string          `json:"name" validate:"required"`
+	Label    string          `json:"label,omitempty"`
+	Image    string          `json:"image,omitempty"`
+	Email    string          `json:"email,omitempty" validate:"omitempty,email"`
+	Phone    string          `json:"phone,omitempty"`
+	URL      string          `json:"url,omitempty" validate:"omitempty,url"`
+	Summary  string          `json:"summary,omitempty"`
+	Location *ResumeLocation `json:"location,omitempty"`
+	Profiles []ResumeProfile `json:"profiles,omitempty" validate:"dive"`
+}
+
+type ResumeLocation struct {
+	Address     string `json:"address,omitempty"`
+	PostalCode  string `json:"postalCode,omitempty"`
+	City        string `json:"city,omitempty"`
+	CountryCode string `json:"countryCode,omitempty"`
+	Region      string `json:"region,omitempty"`
+}
+
+type ResumeProfile struct {
+	Network  string `json:"network" validate:"required"`
+	Username string `json:"username,omitempty"`
+	URL      string `json:"url,omitempty" validate:"omitempty,url"`
+}
+
+type ResumeWork struct {
+	Name        string   `json:"name" validate:"required"`
+	Position    string   `json:"position" validate:"required"`
+	Location    string   `json:"location,omitempty"`
+	Description string   `json:"description,omitempty"`
+	URL         string   `json:"url,omitempty" validate:"omitempty,url"`
+	StartDate   string   `json:"startDate" validate:"required,isodate"`
+	EndDate     string   `json:"endDate,omitempty" validate:"omitempty,isodate"`
+	Summary     string   `json:"summary,omitempty"`
+	Highlights  []string `json:"highlights,omitempty"`
+	// Stack is an extension listing the technologies used
+	Stack []string `json:"stack,omitempty"`
+}
+
+// DateRange returns the formatted dates of the position.
+func (w ResumeWork) DateRange() string {
+	return formatResumeDateRange(w.StartDate, w.EndDate)
+}
+
+type ResumeVolunteer struct {
+	Organization string   `json:"organization" validate:"required"`
+	Position     string   `json:"position,omitempty"`
+	URL          string   `json:"url,omitempty" validate:"omitempty,url"`
+	StartDate    string   `json:"startDate,omitempty" validate:"omitempty,isodate"`
+	EndDate      string   `json:"endDate,omitempty" validate:"omitempty,isodate"`
+	Summary      string   `json:"summary,omitempty"`
+	Highlights   []string `json:"highlights,omitempty"`
+}
+
+type ResumeEducation struct {
+	Institution string   `json:"institution" validate:"required"`
+	URL         string   `json:"url,omitempty" validate:"omitempty,url"`
+	Area        string   `json:"area,omitempty"`
+	StudyType   string   `json:"studyType,omitempty"`
+	StartDate   string   `json:"startDate,omitempty" validate:"omitempty,isodate"`
+	EndDate     string   `json:"endDate,omitempty" validate:"omitempty,isodate"`
+	Score       string   `json:"score,omitempty"`
+	Courses     []string `json:"courses,omitempty"`
+	// Location is an extension giving the location of the institution
+	Location string `json:"location,omitempty"`
+}
+
+// DateRange returns the formatted dates of the course.
+func (e ResumeEducation) DateRange() string {
+	return formatResumeDateRange(e.StartDate, e.EndDate)
+}
+
+// Degree returns the type and area of study, e.g. "BSc Physics".
+func (e ResumeEducation) Degree() string {
+	return strings.TrimSpace(e.StudyType + " " + e.Area)
+}
+
+type ResumeAward struct {
+	Title   string `json:"title" validate:"required"`
+	Date    string `json:"date,omitempty" validate:"omitempty,isodate"`
+	Awarder string `json:"awarder,omitempty"`
+	Summary string `json:"summary,omitempty"`
+}
+
+type ResumeCertificate struct {
+	Name   string `json:"name" validate:"required"`
+	Date   string `json:"date,omitempty" validate:"omitempty,isodate"`
+	URL    string `json:"url,omitempty" validate:"omitempty,url"`
+	Issuer string `json:"issuer,omitempty"`
+}
+
+type ResumePublication struct {
+	Name        string `json:"name" validate:"required"`
+	Publisher   string `json:"publisher,omitempty"`
+	ReleaseDate string `json:"releaseDate,omitempty" validate:"omitempty,isodate"`
+	URL         string `json:"url,omitempty" validate:"omitempty,url"`
+	Summary     string `json:"summary,omitempty"`
+}
+
+type ResumeSkill struct {
+	Name     string   `json:"name" validate:"required"`
+	Level    string   `json:"level,omitempty"`
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+type ResumeLanguage struct {
+	Language string `json:"language" validate:"required"`
+	Fluency  string `json:"fluency,omitempty"`
+}
+
+type ResumeInterest struct {
+	Name     string   `json:"name" validate:"required"`
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+type ResumeReference struct {
+	Name      string `json:"name" validate:"required"`
+	Reference string `json:"reference,omitempty"`
+}
+
+type ResumeProject struct {
+	Name        string   `json:"name" validate:"required"`
+	Description string   `json:"description,omitempty"`
+	Highlights  []string `json:"highlights,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+	StartDate   string   `json:"startDate,omitempty" validate:"omitempty,isodate"`
+	EndDate     string   `json:"endDate,omitempty" validate:"omitempty,isodate"`
+	URL         string   `json:"url,omitempty" validate:"omitempty,url"`
+	Roles       []string `json:"roles,omitempty"`
+	Entity      string   `json:"entity,omitempty"`
+	Type        string   `json:"type,omitempty"`
+}
+
+type ResumeMeta struct {
+	Canonical    string `json:"canonical,omitempty" validate:"omitempty,url"`
+	Version      string `json:"version,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// resumeDatePattern matches the partial ISO 8601 dates used by the schema
+var resumeDatePattern = regexp.MustCompile(`^\d{4}(-\d{2}(-\d{2})?)?$`)
+
+// parseResumeDate parses a date of the form YYYY, YYYY-MM or YYYY-MM-DD.
+func parseResumeDate(date string) (time.Time, error) {
+	if !resumeDatePattern.MatchString(date) {
+		return time.Time{}, fmt.Errorf("invalid date %q", date)
+	}
+	layout := "2006-01-02"[:len(date)]
+	return time.Parse(layout, date)
+}
+
+// formatResumeDateRange formats the dates as e.g. "Oct 2022 - Present".
+// Dates that only give a year are formatted as the year.
+func formatResumeDateRange(start, end string) string {
+	format := func(date string) string {
+		parsed, err := parseResumeDate(date)
+		if err != nil {
+			return date
+		}
+		if len(date) == 4 {
+			return parsed.Format("2006")
+		}
+		return parsed.Format("Jan 2006")
+	}
+
+	if start == "" {
+		return format(end)
+	}
+	if end == "" {
+		return format(start) + " - Present"
+	}
+	return format(start) + " - " + format(end)
+}
+
+// resumeIndexPattern matches list indices in the paths of decoding errors
+var resumeIndexPattern = regexp.MustCompile(`\.(\d+)`)
+
+// resumeJSONType describes the JSON type decoded into the given kind.
+func resumeJSONType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Struct, reflect.Map, reflect.Pointer:
+		return "an object"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	}
+	return "a number"
+}
+
+// resumeValidator validates resumes, reporting fields by their JSON names
+var resumeValidator = func() *validator.Validate {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		return name
+	})
+	validate.RegisterValidation("isodate", func(fl validator.FieldLevel) bool {
+		_, err := parseResumeDate(fl.Field().String())
+		return err == nil
+	})
+	return validate
+}()
+
+// resumeFieldMessage describes why a field failed validation.
+func resumeFieldMessage(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return "is required"
+	case "isodate":
+		return "must be a date of the form YYYY, YYYY-MM or YYYY-MM-DD"
+	case "email":
+		return "must be an email address"
+	case "url":
+		return "must be a URL"
+	}
+	return "failed " + err.Tag() + " validation"
+}
+
+// ParseResume decodes, normalises and validates a JSON resume.
+// Unknown fields are rejected, so that misspelt fields are reported
+// rather than silently dropped. Invalid fields are reported in a
+// ResumeValidationError.
+func ParseResume(contents []byte) (*Resume, error) {
+	decoder := json.NewDecoder(bytes.NewReader(contents))
+	decoder.DisallowUnknownFields()
+
+	var resume Resume
+	if err := decoder.Decode(&resume); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			// decoding reports list indices as path elements
+			field := resumeIndexPattern.ReplaceAllString(typeErr.Field, "[$1]")
+			return nil, ResumeValidationError{Fields: []ResumeFieldError{
+				{Field: field, Message: "must be " + resumeJSONType(typeErr.Type.Kind())},
+			}}
+		}
+		return nil, ResumeValidationError{Fields: []ResumeFieldError{{Message: err.Error()}}}
+	}
+
+	resume.normalise()
+	if err := resumeValidator.Struct(resume); err != nil {
+		var validationErrors validator.ValidationErrors
+		if !errors.As(err, &validationErrors) {
+			return nil, err
+		}
+
+		fields := make([]ResumeFieldError, len(validationErrors))
+		for i, fieldErr := range validationErrors {
+			// namespaces are prefixed with the struct name
+			_, field, _ := strings.Cut(fieldErr.Namespace(), ".")
+			fields[i] = ResumeFieldError{Field: field, Message: resumeFieldMessage(fieldErr)}
+		}
+		return nil, ResumeValidationError{Fields: fields}
+	}
+	return &resume, nil
+}
+
+// LoadResume reads and parses the JSON resume at the given path.
+func LoadResume(path string) (*Resume, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseResume(contents)
+}
+
+// normalise trims whitespace from all fields, removes empty
+// list items, and orders dated entries from most recent.
+func (r *Resume) normalise() {
+	normaliseResumeStrings(reflect.ValueOf(r).Elem())
+
+	// ISO 8601 dates sort lexically, and entries
+	// without an end date are ongoing
+	latest := func(start, end string) string {
+		if end == "" {
+			return "9999"
+		}
+		return end + start
+	}
+	slices.SortStableFunc(r.Work, func(a, b ResumeWork) int {
+		return strings.Compare(latest(b.StartDate, b.EndDate), latest(a.StartDate, a.EndDate))
+	})
+	slices.SortStableFunc(r.Education, func(a, b ResumeEducation) int {
+		return strings.Compare(latest(b.StartDate, b.EndDate), latest(a.StartDate, a.EndDate))
+	})
+}
+
+// normaliseResumeStrings trims the strings within the given value.
+func normaliseResumeStrings(value reflect.Value) {
+	switch value.Kind() {
+	case reflect.Pointer:
+		if !value.IsNil() {
+			normaliseResumeStrings(value.Elem())
+		}
+	case reflect.Struct:
+		for i := range value.NumField() {
+			normaliseResumeStrings(value.Field(i))
+		}
+	case reflect.String:
+		value.SetString(strings.TrimSpace(value.String()))
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.String {
+			items := slices.DeleteFunc(value.Interface().([]string), func(item string) bool {
+				return strings.TrimSpace(item) == ""
+			})
+			value.Set(reflect.ValueOf(items))
+		}
+		for i := range value.Len() {
+			normaliseResumeStrings(value.Index(i))
+		}
+	}
+}
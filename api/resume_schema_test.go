@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseResume(t *testing.T) {
+
+	t.Run("Valid Resume", func(t *testing.T) {
+		resume, err := LoadResume("etc/resume.json")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if resume.Basics.Name == "" || len(resume.Work) == 0 || len(resume.Skills) == 0 {
+			t.Errorf("Expected resume to be populated, got %+v", resume)
+		}
+	})
+
+	t.Run("Normalised", func(t *testing.T) {
+		resume, err := ParseResume([]byte(`{
+			"basics": {"name": "  Jane Doe "},
+			"work": [
+				{"name": "Old", "position": "Engineer", "startDate": "2015-01", "endDate": "2018-06"},
+				{"name": "Current", "position": "Engineer", "startDate": "2021-03"},
+				{"name": "Recent", "position": "Engineer", "startDate": "2018-07", "endDate": "2021-02", "highlights": ["a", " ", ""]}
+			]
+		}`))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if resume.Basics.Name != "Jane Doe" {
+			t.Errorf("Expected trimmed name, got %q", resume.Basics.Name)
+		}
+
+		var names []string
+		for _, work := range resume.Work {
+			names = append(names, work.Name)
+		}
+		if !slices.Equal(names, []string{"Current", "Recent", "Old"}) {
+			t.Errorf("Expected work ordered from most recent, got %v", names)
+		}
+
+		if !slices.Equal(resume.Work[1].Highlights, []string{"a"}) {
+			t.Errorf("Expected empty highlights to be removed, got %q", resume.Work[1].Highlights)
+		}
+	})
+
+	t.Run("Field Errors", func(t *testing.T) {
+		_, err := ParseResume([]byte(`{
+			"basics": {"name": "Jane Doe", "email": "jane"},
+			"work": [{"name": "Acme", "startDate": "Oct 2022"}]
+		}`))
+
+		var validationErr ResumeValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Expected ResumeValidationError, got %v", err)
+		}
+
+		var fields []string
+		for _, field := range validationErr.Fields {
+			fields = append(fields, field.Field)
+		}
+		expected := []string{"basics.email", "work[0].position", "work[0].startDate"}
+		if !slices.Equal(fields, expected) {
+			t.Errorf("Expected errors for %v, got %v", expected, validationErr.Fields)
+		}
+	})
+
+	t.Run("Unknown Field", func(t *testing.T) {
+		_, err := ParseResume([]byte(`{"basics": {"name": "Jane Doe"}, "experience": []}`))
+		if !errors.As(err, &ResumeValidationError{}) {
+			t.Errorf("Expected ResumeValidationError, got %v", err)
+		}
+	})
+
+	t.Run("Invalid Type", func(t *testing.T) {
+		_, err := ParseResume([]byte(`{"basics": {"name": "Jane Doe"}, "skills": [{"name": "Go", "keywords": "Go"}]}`))
+
+		var validationErr ResumeValidationError
+		if !errors.As(err, &validationErr) || validationErr.Fields[0].Field != "skills[0].keywords" {
+			t.Errorf("Expected error for skills[0].keywords, got %v", err)
+		}
+	})
+}
+
+func TestFormatResumeDateRange(t *testing.T) {
+	tests := map[[2]string]string{
+		{"2022-10", ""}:           "Oct 2022 - Present",
+		{"2016", "2019"}:          "2016 - 2019",
+		{"2019-06-01", "2022-02"}: "Jun 2019 - Feb 2022",
+		{"", "2020-01"}:           "Jan 2020",
+	}
+	for dates, expected := range tests {
+		if formatted := formatResumeDateRange(dates[0], dates[1]); formatted != expected {
+			t.Errorf("Expected %q for %v, got %q", expected, dates, formatted)
+		}
+	}
+}
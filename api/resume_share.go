@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResumeSharer mints and verifies signed links to the resume. The token
+// binds the ID of the share and its expiry, so that forged and expired
+// links are rejected without querying the database.
+type ResumeSharer struct {
+	// Secret is used to sign share tokens
+	Secret []byte
+	// TTL is the lifetime of links created without an expiry
+	TTL time.Duration
+	// MaxTTL is the longest lifetime that can be requested
+	MaxTTL time.Duration
+}
+
+// NewResumeSharer creates the ResumeSharer configured in the given config.
+func NewResumeSharer(cfg *Config) (*ResumeSharer, error) {
+	if cfg.ResumeShareTTL <= 0 || cfg.ResumeShareMaxTTL < cfg.ResumeShareTTL {
+		return nil, fmt.Errorf("invalid resume share ttl %s (max %s)", cfg.ResumeShareTTL, cfg.ResumeShareMaxTTL)
+	}
+
+	secret, err := loadSigningSecret("resume share link", cfg.ResumeShareSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	sharer := &ResumeSharer{
+		Secret: secret,
+		TTL:    cfg.ResumeShareTTL,
+		MaxTTL: cfg.ResumeShareMaxTTL,
+	}
+	return sharer, nil
+}
+
+// Token returns the token of the given share, of the form
+// "{id}.{expiry}.{signature}". Tokens are derived from the share, so
+// that they can be listed again without being stored.
+func (s *ResumeSharer) Token(share ResumeShare) string {
+	payload := fmt.Sprintf("%s.%d", share.Id, share.ExpiresAt.Unix())
+	return payload + "." + signPayload(s.Secret, "share", payload)
+}
+
+// Verify checks that the token was issued by this server and has not
+// expired, and returns the ID of the share. Verifying a token does not
+// record it as opened.
+func (s *ResumeSharer) Verify(token string, now time.Time) (string, error) {
+	fields := strings.Split(token, ".")
+	if len(fields) != 3 || fields[0] == "" {
+		return "", InvalidResumeShareTokenError{Reason: "malformed token"}
+	}
+
+	payload := strings.Join(fields[:2], ".")
+	if !verifyPayload(s.Secret, "share", payload, fields[2]) {
+		return "", InvalidResumeShareTokenError{Reason: "invalid signature"}
+	}
+
+	expires, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return "", InvalidResumeShareTokenError{Reason: "malformed token"}
+	}
+	if !now.Before(time.Unix(expires, 0)) {
+		return "", InvalidResumeShareTokenError{Reason: "token has expired", Expired: true}
+	}
+	return fields[0], nil
+}
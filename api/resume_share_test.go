@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestResumeSharer() *ResumeSharer {
+	return &ResumeSharer{
+		Secret: []byte("secret"),
+		TTL:    time.Hour,
+		MaxTTL: 24 * time.Hour,
+	}
+}
+
+func TestNewResumeSharer(t *testing.T) {
+	sharer, err := NewResumeSharer(&Config{ResumeShareTTL: time.Hour, ResumeShareMaxTTL: time.Hour})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(sharer.Secret) == 0 {
+		t.Errorf("Expected random secret, got none")
+	}
+
+	if _, err := NewResumeSharer(&Config{ResumeShareTTL: time.Hour, ResumeShareMaxTTL: time.Minute}); err == nil {
+		t.Errorf("Expected error for max ttl below ttl, got nil")
+	}
+}
+
+func TestResumeSharer(t *testing.T) {
+	sharer := newTestResumeSharer()
+	now := time.Now()
+	share := ResumeShare{Id: "share-1", ExpiresAt: now.Add(time.Hour)}
+	token := sharer.Token(share)
+
+	t.Run("Valid Token", func(t *testing.T) {
+		id, err := sharer.Verify(token, now)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if id != share.Id {
+			t.Errorf("Expected share ID %s, got %s", share.Id, id)
+		}
+	})
+
+	t.Run("Expired Token", func(t *testing.T) {
+		_, err := sharer.Verify(token, now.Add(2*time.Hour))
+
+		var errInvalid InvalidResumeShareTokenError
+		if !errors.As(err, &errInvalid) || !errInvalid.Expired {
+			t.Errorf("Expected expired InvalidResumeShareTokenError, got %v", err)
+		}
+	})
+
+	fields := strings.Split(token, ".")
+	// change the last character of the signature
+	last := token[len(token)-1]
+	tampered := token[:len(token)-1] + map[bool]string{true: "1", false: "0"}[last == '0']
+	cases := []struct {
+		name  string
+		token string
+	}{
+		{"Malformed Token", "abc"},
+		{"Missing ID", "." + fields[1] + "." + fields[2]},
+		{"Invalid Signature", tampered},
+		{"Extended Expiry", strings.Join([]string{fields[0], "9999999999", fields[2]}, ".")},
+		{"Other Share", strings.Join([]string{"share-2", fields[1], fields[2]}, ".")},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := sharer.Verify(tc.token, now)
+
+			var errInvalid InvalidResumeShareTokenError
+			if !errors.As(err, &errInvalid) || errInvalid.Expired {
+				t.Errorf("Expected InvalidResumeShareTokenError, got %v", err)
+			}
+		})
+	}
+
+	t.Run("Other Secret", func(t *testing.T) {
+		other := *sharer
+		other.Secret = []byte("other")
+		if _, err := other.Verify(token, now); err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+}
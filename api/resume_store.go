@@ -0,0 +1,508 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// resumeReloadDelay is the time waited after a change before reloading,
+// so that editors writing a file in several steps trigger a single reload
+const resumeReloadDelay = 100 * time.Millisecond
+
+const (
+	// DefaultResumeVariant is the name of the default variant
+	DefaultResumeVariant = "default"
+	// DefaultResumeLocale is the locale of resumes without one
+	DefaultResumeLocale = "en"
+)
+
+// ResumeFile is the content of a resume file held in memory.
+type ResumeFile struct {
+	// Path is the path of the file, or the name of the
+	// file for versions uploaded by admins
+	Path    string
+	Content []byte
+	// Hash is the hex encoded SHA-256 hash of the content
+	Hash    string
+	ModTime time.Time
+}
+
+// ETag returns a strong ETag for the given representation of the file.
+func (f *ResumeFile) ETag(representation string) string {
+	return fmt.Sprintf(`"%s-%s"`, f.Hash[:32], representation)
+}
+
+// ResumeSnapshot holds the files of a single variant of the resume
+// in a single language.
+type ResumeSnapshot struct {
+	Variant string
+	Locale  string
+	JSON    *ResumeFile
+	Resume  *Resume
+	// PDF is the static or uploaded PDF, if any
+	PDF *ResumeFile
+}
+
+// ResumeVariant lists the languages a variant of the resume is available in.
+type ResumeVariant struct {
+	Name string `json:"name"`
+	// Locales are ordered with the default locale first
+	Locales []string `json:"locales"`
+	Default bool     `json:"default"`
+}
+
+// resumeCatalog holds the snapshots of all variants loaded at the same
+// time, keyed by variant and ordered with the default locale first.
+type resumeCatalog map[string][]*ResumeSnapshot
+
+// ResumeVersionSource provides the active versions of the resume
+// uploaded by admins, which are served instead of the resume files.
+type ResumeVersionSource interface {
+	ListActiveResumeVersions(ctx context.Context) ([]ResumeVersion, error)
+}
+
+const (
+	// resumeVariantName matches the name of a variant, e.g. academic
+	resumeVariantName = `[a-z0-9_-]+`
+	// resumeLocale matches a locale, e.g. de or en-GB
+	resumeLocale = `[A-Za-z]{2,3}(?:-[A-Za-z0-9]{2,8})*`
+)
+
+var (
+	resumeVariantNamePattern = regexp.MustCompile(`^` + resumeVariantName + `$`)
+	resumeLocalePattern      = regexp.MustCompile(`^` + resumeLocale + `$`)
+	// resumeVariantPattern matches the names of resumes in a variants
+	// directory, e.g. short.json, short.de.json or academic.en-GB.pdf
+	resumeVariantPattern = regexp.MustCompile(`^(` + resumeVariantName + `)(?:\.(` + resumeLocale + `))?\.(json|pdf)$`)
+)
+
+// ResumeStore keeps the resume files in memory, and reloads them when
+// they change. A new catalog replaces the current one atomically, so
+// requests are served from a consistent snapshot without locking. If a
+// changed resume is invalid, the previous catalog continues to be served.
+// Resumes are either loaded from a directory of named variants, or from
+// a single JSON resume and static PDF which form the default variant.
+// Active versions uploaded by admins replace the files of their variant
+// and locale, or add a new variant or locale, and are polled for changes
+// made by other instances of the API.
+type ResumeStore struct {
+	JSONPath string
+	PDFPath  string
+	Dir      string
+	// DefaultVariant and DefaultLocale are served when the
+	// client does not request a variant or language
+	DefaultVariant string
+	DefaultLocale  string
+	// Versions is optional
+	Versions ResumeVersionSource
+
+	current atomic.Pointer[resumeCatalog]
+	// fingerprint identifies the content of the current catalog,
+	// so that reloads without changes keep the current catalog
+	fingerprint string
+	watcher     *fsnotify.Watcher
+	stop        chan struct{}
+	wg          sync.WaitGroup
+	mu          sync.Mutex
+}
+
+// NewResumeStore loads the resume files configured in the given config,
+// along with the active versions provided by the given source if not nil,
+// and starts watching them for changes if enabled.
+func NewResumeStore(cfg *Config, versions ResumeVersionSource) (*ResumeStore, error) {
+	store := &ResumeStore{
+		JSONPath:       cfg.ResumePathJSON,
+		PDFPath:        cfg.ResumePathPDF,
+		Dir:            cfg.ResumeDir,
+		DefaultVariant: cmp.Or(cfg.ResumeDefaultVariant, DefaultResumeVariant),
+		DefaultLocale:  cmp.Or(cfg.ResumeDefaultLocale, DefaultResumeLocale),
+		Versions:       versions,
+	}
+	if err := store.Reload(context.Background()); err != nil {
+		return nil, err
+	}
+
+	if cfg.ResumeWatch {
+		if err := store.Watch(); err != nil {
+			return nil, fmt.Errorf("failed to watch resume files: %w", err)
+		}
+	}
+	if versions != nil && cfg.ResumePollInterval > 0 {
+		store.Poll(cfg.ResumePollInterval)
+	}
+	return store, nil
+}
+
+// loadResumeFile reads the file at the given path into memory.
+func loadResumeFile(path string) (*ResumeFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(content)
+	return &ResumeFile{
+		Path:    path,
+		Content: content,
+		Hash:    hex.EncodeToString(sum[:]),
+		// Last-Modified has a resolution of seconds
+		ModTime: info.ModTime().UTC().Truncate(time.Second),
+	}, nil
+}
+
+// loadResumeSnapshot loads and validates a JSON resume, along with
+// the static PDF at pdfPath if given.
+func loadResumeSnapshot(variant, locale, jsonPath, pdfPath string) (*ResumeSnapshot, error) {
+	snapshot := &ResumeSnapshot{Variant: variant, Locale: locale}
+	var err error
+	if snapshot.JSON, err = loadResumeFile(jsonPath); err != nil {
+		return nil, err
+	}
+	if snapshot.Resume, err = ParseResume(snapshot.JSON.Content); err != nil {
+		return nil, fmt.Errorf("invalid resume %s: %w", jsonPath, err)
+	}
+
+	if pdfPath != "" {
+		if snapshot.PDF, err = loadResumeFile(pdfPath); err != nil {
+			return nil, err
+		}
+	}
+	return snapshot, nil
+}
+
+// loadDir loads the resumes in the variants directory, which are named
+// after their variant and, optionally, their locale. Resumes without a
+// locale are in the default locale, and PDFs named after a JSON resume
+// are served instead of generating the PDF.
+func (s *ResumeStore) loadDir() (resumeCatalog, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type resumePaths struct{ json, pdf string }
+	paths := map[[2]string]*resumePaths{}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		match := resumeVariantPattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			if ext := filepath.Ext(entry.Name()); ext == ".json" || ext == ".pdf" {
+				return nil, fmt.Errorf("invalid resume name %s, expected <variant>[.<locale>].%s", entry.Name(), ext[1:])
+			}
+			continue
+		}
+
+		key := [2]string{match[1], cmp.Or(match[2], s.DefaultLocale)}
+		if paths[key] == nil {
+			paths[key] = &resumePaths{}
+		}
+		path := &paths[key].json
+		if match[3] == "pdf" {
+			path = &paths[key].pdf
+		}
+		if *path != "" {
+			return nil, fmt.Errorf("duplicate resume %s and %s", filepath.Base(*path), entry.Name())
+		}
+		*path = filepath.Join(s.Dir, entry.Name())
+	}
+
+	catalog := resumeCatalog{}
+	for key, path := range paths {
+		if path.json == "" {
+			return nil, fmt.Errorf("no JSON resume for %s", path.pdf)
+		}
+		snapshot, err := loadResumeSnapshot(key[0], key[1], path.json, path.pdf)
+		if err != nil {
+			return nil, err
+		}
+		catalog[key[0]] = append(catalog[key[0]], snapshot)
+	}
+	return catalog, nil
+}
+
+// applyVersions replaces the files in the catalog with the active versions
+// uploaded by admins. PDFs without a JSON resume of the same variant and
+// locale are skipped, as the JSON resume is required to serve other formats.
+func (s *ResumeStore) applyVersions(ctx context.Context, catalog resumeCatalog) error {
+	versions, err := s.Versions.ListActiveResumeVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list resume versions: %w", err)
+	}
+	// JSON versions are applied first, as they may add the
+	// variant or locale that a PDF version belongs to
+	slices.SortStableFunc(versions, func(a, b ResumeVersion) int {
+		return strings.Compare(string(a.Format), string(b.Format))
+	})
+
+	for _, version := range versions {
+		file := &ResumeFile{
+			Path:    fmt.Sprintf("resume-%s.%s", version.Id, version.Format),
+			Content: version.Content,
+			Hash:    version.Hash,
+			// rolling back to an older version must not
+			// move the modification time backwards
+			ModTime: version.ActivatedAt.UTC().Truncate(time.Second),
+		}
+
+		snapshots := catalog[version.Variant]
+		index := slices.IndexFunc(snapshots, func(snapshot *ResumeSnapshot) bool {
+			return snapshot.Locale == version.Locale
+		})
+		switch version.Format {
+		case ResumeFormatJSON:
+			resume, err := ParseResume(version.Content)
+			if err != nil {
+				return fmt.Errorf("invalid resume version %s: %w", version.Id, err)
+			}
+			if index < 0 {
+				catalog[version.Variant] = append(snapshots, &ResumeSnapshot{Variant: version.Variant, Locale: version.Locale})
+				index = len(snapshots)
+			}
+			// a static PDF of the replaced resume is out of date,
+			// so the PDF is generated unless a PDF version is active
+			catalog[version.Variant][index].JSON = file
+			catalog[version.Variant][index].Resume = resume
+			catalog[version.Variant][index].PDF = nil
+		case ResumeFormatPDF:
+			if index < 0 {
+				log.Error(fmt.Sprintf("no JSON resume for resume version %s, skipping", version.Id))
+				continue
+			}
+			snapshots[index].PDF = file
+		}
+	}
+	return nil
+}
+
+// fingerprint identifies the content and modification times of all
+// files in the catalog.
+func (c resumeCatalog) fingerprint() string {
+	var parts []string
+	for _, snapshots := range c {
+		for _, snapshot := range snapshots {
+			for _, file := range []*ResumeFile{snapshot.JSON, snapshot.PDF} {
+				if file != nil {
+					parts = append(parts, fmt.Sprintf("%s/%s/%s/%d", snapshot.Variant, snapshot.Locale, file.Hash, file.ModTime.Unix()))
+				}
+			}
+		}
+	}
+	slices.Sort(parts)
+	return strings.Join(parts, ",")
+}
+
+// Reload loads and validates the resume files and active versions,
+// replacing the current catalog if successful and changed.
+func (s *ResumeStore) Reload(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	catalog := resumeCatalog{}
+	if s.Dir != "" {
+		var err error
+		if catalog, err = s.loadDir(); err != nil {
+			return err
+		}
+	} else {
+		snapshot, err := loadResumeSnapshot(s.DefaultVariant, s.DefaultLocale, s.JSONPath, s.PDFPath)
+		if err != nil {
+			return err
+		}
+		catalog[s.DefaultVariant] = []*ResumeSnapshot{snapshot}
+	}
+
+	if s.Versions != nil {
+		if err := s.applyVersions(ctx, catalog); err != nil {
+			return err
+		}
+	}
+
+	if _, ok := catalog[s.DefaultVariant]; !ok {
+		return fmt.Errorf("default resume variant %s not found", s.DefaultVariant)
+	}
+	for _, snapshots := range catalog {
+		slices.SortFunc(snapshots, func(a, b *ResumeSnapshot) int {
+			// the default locale is served if none of the
+			// locales are acceptable, so is ordered first
+			switch {
+			case a.Locale == s.DefaultLocale:
+				return -1
+			case b.Locale == s.DefaultLocale:
+				return 1
+			}
+			return strings.Compare(a.Locale, b.Locale)
+		})
+	}
+
+	fingerprint := catalog.fingerprint()
+	if fingerprint == s.fingerprint {
+		return nil
+	}
+	s.fingerprint = fingerprint
+	s.current.Store(&catalog)
+	log.Info(fmt.Sprintf("loaded %d resume variant(s)", len(catalog)))
+	return nil
+}
+
+// Snapshot returns the current snapshot of the default variant
+// in the default locale, or its first locale if not available.
+func (s *ResumeStore) Snapshot() *ResumeSnapshot {
+	return (*s.current.Load())[s.DefaultVariant][0]
+}
+
+// Select returns the current snapshot of the given variant, or the default
+// variant if empty, in the language preferred by the given Accept-Language
+// header. The first locale of the variant is used if none are acceptable.
+func (s *ResumeStore) Select(variant, acceptLanguage string) (*ResumeSnapshot, error) {
+	snapshots, ok := (*s.current.Load())[cmp.Or(variant, s.DefaultVariant)]
+	if !ok {
+		return nil, UnknownResumeVariantError{Name: variant}
+	}
+
+	locales := make([]string, len(snapshots))
+	for i, snapshot := range snapshots {
+		locales[i] = snapshot.Locale
+	}
+	if index := slices.Index(locales, NegotiateLanguage(acceptLanguage, locales...)); index >= 0 {
+		return snapshots[index], nil
+	}
+	return snapshots[0], nil
+}
+
+// Lookup returns the current snapshot of the given variant and locale.
+func (s *ResumeStore) Lookup(variant, locale string) (*ResumeSnapshot, bool) {
+	for _, snapshot := range (*s.current.Load())[variant] {
+		if snapshot.Locale == locale {
+			return snapshot, true
+		}
+	}
+	return nil, false
+}
+
+// Variants lists the current variants, ordered by name.
+func (s *ResumeStore) Variants() []ResumeVariant {
+	catalog := *s.current.Load()
+	variants := make([]ResumeVariant, 0, len(catalog))
+	for name, snapshots := range catalog {
+		variant := ResumeVariant{Name: name, Default: name == s.DefaultVariant}
+		for _, snapshot := range snapshots {
+			variant.Locales = append(variant.Locales, snapshot.Locale)
+		}
+		variants = append(variants, variant)
+	}
+	slices.SortFunc(variants, func(a, b ResumeVariant) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return variants
+}
+
+// Watch reloads the resume files whenever they change. The directories
+// containing the files are watched rather than the files themselves, so
+// that files replaced by renaming, as done by many editors and by
+// Kubernetes when updating mounted ConfigMaps, continue to be watched.
+func (s *ResumeStore) Watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	dirs := []string{s.Dir}
+	if s.Dir == "" {
+		dirs = []string{filepath.Dir(s.JSONPath)}
+		if s.PDFPath != "" {
+			dirs = append(dirs, filepath.Dir(s.PDFPath))
+		}
+	}
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return err
+		}
+	}
+
+	s.watcher = watcher
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		var reload <-chan time.Time
+		for {
+			select {
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// any change within the directories triggers a reload, as
+				// files may be replaced by renaming a different file
+				reload = time.After(resumeReloadDelay)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Error(fmt.Sprintf("failed to watch resume files: %v", err))
+			case <-reload:
+				reload = nil
+				if err := s.Reload(context.Background()); err != nil {
+					log.Error(fmt.Sprintf("failed to reload resume, serving previous version: %v", err))
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Poll reloads the resume at the given interval, so that versions
+// activated by other instances of the API are served.
+func (s *ResumeStore) Poll(interval time.Duration) {
+	s.stop = make(chan struct{})
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				if err := s.Reload(context.Background()); err != nil {
+					log.Error(fmt.Sprintf("failed to reload resume, serving previous version: %v", err))
+				}
+			}
+		}
+	}()
+}
+
+// Close stops watching and polling the resume files.
+func (s *ResumeStore) Close() error {
+	var err error
+	if s.watcher != nil {
+		err = s.watcher.Close()
+	}
+	if s.stop != nil {
+		close(s.stop)
+	}
+	s.wg.Wait()
+	return err
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestResumeStore(t *testing.T, config *Config) *ResumeStore {
+	t.Helper()
+
+	store, err := NewResumeStore(config, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// copyResume copies the example resume into a temporary directory.
+func copyResume(t *testing.T) string {
+	t.Helper()
+
+	contents, err := os.ReadFile("etc/resume.json")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "resume.json")
+	if err := os.WriteFile(path, contents, 0o644); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return path
+}
+
+// writeResumeVariants writes resumes with the given file names into
+// a temporary directory, using the file name as the name in the resume.
+func writeResumeVariants(t *testing.T, names ...string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, name := range names {
+		content := fmt.Sprintf(`{"basics": {"name": %q}}`, name)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	return dir
+}
+
+func TestResumeStore(t *testing.T) {
+
+	t.Run("Loads Files", func(t *testing.T) {
+		store := newTestResumeStore(t, &Config{ResumePathJSON: "etc/resume.json", ResumePathPDF: "etc/resume.pdf"})
+
+		snapshot := store.Snapshot()
+		if snapshot.Resume == nil || snapshot.PDF == nil || len(snapshot.JSON.Hash) != 64 {
+			t.Fatalf("Expected resume files to be loaded, got %+v", snapshot)
+		}
+
+		if etag := snapshot.JSON.ETag("json"); etag != `"`+snapshot.JSON.Hash[:32]+`-json"` {
+			t.Errorf("Expected ETag derived from the content hash, got %s", etag)
+		}
+	})
+
+	t.Run("Missing File", func(t *testing.T) {
+		if _, err := NewResumeStore(&Config{ResumePathJSON: "etc/resume.json", ResumePathPDF: "etc/missing.pdf"}, nil); err == nil {
+			t.Errorf("Expected error for missing PDF")
+		}
+	})
+
+	t.Run("Invalid Change Keeps Previous Snapshot", func(t *testing.T) {
+		path := copyResume(t)
+		store := newTestResumeStore(t, &Config{ResumePathJSON: path})
+		previous := store.Snapshot()
+
+		if err := os.WriteFile(path, []byte(`{"basics": {}}`), 0o644); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := store.Reload(context.Background()); err == nil {
+			t.Errorf("Expected error for invalid resume")
+		}
+
+		if store.Snapshot() != previous {
+			t.Errorf("Expected previous snapshot to be kept")
+		}
+	})
+
+	t.Run("Watch", func(t *testing.T) {
+		path := copyResume(t)
+		store := newTestResumeStore(t, &Config{ResumePathJSON: path, ResumeWatch: true})
+		previous := store.Snapshot()
+
+		// replace the file by renaming, as editors do
+		replacement := filepath.Join(filepath.Dir(path), "resume.json.tmp")
+		if err := os.WriteFile(replacement, []byte(`{"basics": {"name": "Jane Doe"}}`), 0o644); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := os.Rename(replacement, path); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for store.Snapshot() == previous && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if name := store.Snapshot().Resume.Basics.Name; name != "Jane Doe" {
+			t.Errorf("Expected reloaded resume, got %s", name)
+		}
+	})
+
+	t.Run("Loads Variants", func(t *testing.T) {
+		dir := writeResumeVariants(t, "default.json", "default.de.json", "academic.en-GB.json", "notes.txt")
+		store := newTestResumeStore(t, &Config{ResumeDir: dir})
+
+		if name := store.Snapshot().Resume.Basics.Name; name != "default.json" {
+			t.Errorf("Expected default variant in default locale, got %s", name)
+		}
+
+		expected := []ResumeVariant{
+			{Name: "academic", Locales: []string{"en-GB"}},
+			{Name: "default", Locales: []string{"en", "de"}, Default: true},
+		}
+		if variants := store.Variants(); !reflect.DeepEqual(variants, expected) {
+			t.Errorf("Expected variants %+v, got %+v", expected, variants)
+		}
+	})
+
+	t.Run("Invalid Variants", func(t *testing.T) {
+		tests := map[string][]string{
+			"Missing Default": {"short.json"},
+			"Invalid Name":    {"default.json", "Short Resume.json"},
+			"Duplicate":       {"default.json", "default.en.json"},
+			"PDF Only":        {"default.json", "short.pdf"},
+		}
+		for name, files := range tests {
+			if _, err := NewResumeStore(&Config{ResumeDir: writeResumeVariants(t, files...)}, nil); err == nil {
+				t.Errorf("Expected error for %s", name)
+			}
+		}
+	})
+
+	t.Run("Select", func(t *testing.T) {
+		dir := writeResumeVariants(t, "default.json", "default.de.json", "short.fr.json")
+		store := newTestResumeStore(t, &Config{ResumeDir: dir})
+
+		tests := []struct {
+			variant        string
+			acceptLanguage string
+			expected       string
+		}{
+			{"", "", "default.json"},
+			{"", "de-DE,de;q=0.9,en;q=0.8", "default.de.json"},
+			{"default", "es", "default.json"},
+			// variants without the default locale fall back to their first locale
+			{"short", "en", "short.fr.json"},
+		}
+		for _, tc := range tests {
+			snapshot, err := store.Select(tc.variant, tc.acceptLanguage)
+			if err != nil || snapshot.Resume.Basics.Name != tc.expected {
+				t.Errorf("Expected %s for %q %q, got %+v %v", tc.expected, tc.variant, tc.acceptLanguage, snapshot, err)
+			}
+		}
+
+		if _, err := store.Select("academic", ""); !errors.As(err, &UnknownResumeVariantError{}) {
+			t.Errorf("Expected UnknownResumeVariantError, got %v", err)
+		}
+	})
+
+	t.Run("Versions", func(t *testing.T) {
+		dir := writeResumeVariants(t, "default.json", "default.de.json")
+		if err := os.WriteFile(filepath.Join(dir, "default.pdf"), []byte("%PDF-1.4"), 0o644); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		activatedAt := time.Now().Add(-time.Hour)
+		persistence := &TestPersistence{ResumeVersions: []ResumeVersion{
+			{Id: "1", Variant: "default", Locale: "en", Format: ResumeFormatJSON, Content: []byte(`{"basics": {"name": "Jane Doe"}}`), Hash: "1", Active: true, ActivatedAt: activatedAt},
+			{Id: "2", Variant: "academic", Locale: "en", Format: ResumeFormatJSON, Content: []byte(`{"basics": {"name": "Dr Jane Doe"}}`), Hash: "2", Active: true},
+			{Id: "3", Variant: "academic", Locale: "en", Format: ResumeFormatPDF, Content: []byte("%PDF-1.4"), Hash: "3", Active: true},
+			// PDFs without a JSON resume are skipped
+			{Id: "4", Variant: "short", Locale: "en", Format: ResumeFormatPDF, Content: []byte("%PDF-1.4"), Hash: "4", Active: true},
+			{Id: "5", Variant: "default", Locale: "de", Format: ResumeFormatJSON, Content: []byte(`{"basics": {"name": "Inactive"}}`), Hash: "5"},
+		}}
+		store, err := NewResumeStore(&Config{ResumeDir: dir}, persistence)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		defer store.Close()
+
+		snapshot := store.Snapshot()
+		if snapshot.Resume.Basics.Name != "Jane Doe" || !snapshot.JSON.ModTime.Equal(activatedAt.UTC().Truncate(time.Second)) {
+			t.Errorf("Expected active version to replace the file, got %+v", snapshot.JSON)
+		}
+		if snapshot.PDF != nil {
+			t.Errorf("Expected static PDF of the replaced resume to be dropped, got %s", snapshot.PDF.Path)
+		}
+		if german, _ := store.Lookup("default", "de"); german.Resume.Basics.Name != "default.de.json" {
+			t.Errorf("Expected inactive version to be ignored, got %s", german.Resume.Basics.Name)
+		}
+		if academic, ok := store.Lookup("academic", "en"); !ok || academic.PDF == nil || academic.PDF.Path != "resume-3.pdf" {
+			t.Errorf("Expected variant added by versions, got %+v", academic)
+		}
+		if _, ok := store.Lookup("short", "en"); ok {
+			t.Errorf("Expected PDF without JSON resume to be skipped")
+		}
+
+		// reloads without changes keep the current snapshot
+		if err := store.Reload(context.Background()); err != nil || store.Snapshot() != snapshot {
+			t.Errorf("Expected unchanged snapshot, got %v", err)
+		}
+
+		persistence.ResumeVersions[0].Content = []byte(`{"basics": {}}`)
+		if err := store.Reload(context.Background()); err == nil {
+			t.Errorf("Expected error for invalid resume version")
+		}
+	})
+
+	t.Run("Poll", func(t *testing.T) {
+		db, _ := NewMemoryPersistence("")
+		store, err := NewResumeStore(&Config{ResumePathJSON: copyResume(t), ResumePollInterval: 10 * time.Millisecond}, db)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		defer store.Close()
+
+		// versions activated by other instances are picked up
+		version := ResumeVersion{Variant: DefaultResumeVariant, Locale: DefaultResumeLocale, Format: ResumeFormatJSON, Content: []byte(`{"basics": {"name": "Jane Doe"}}`), Hash: "1"}
+		if _, err := db.CreateResumeVersion(context.Background(), version); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for store.Snapshot().Resume.Basics.Name != "Jane Doe" && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if name := store.Snapshot().Resume.Basics.Name; name != "Jane Doe" {
+			t.Errorf("Expected polled resume version, got %s", name)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestResumeRenderer(t *testing.T) *ResumeRenderer {
+	t.Helper()
+
+	renderer, err := LoadResumeTemplates("")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return renderer
+}
+
+func TestResumeRenderer(t *testing.T) {
+	renderer := newTestResumeRenderer(t)
+	resume := &Resume{
+		Basics: ResumeBasics{Name: "Jane Doe", Label: "Engineer"},
+		Work: []ResumeWork{{
+			Position:   "Engineer",
+			Name:       "Acme <Labs>",
+			StartDate:  "2020-01",
+			Location:   "London",
+			Summary:    "Built things",
+			Stack:      []string{"Go", "Python"},
+			Highlights: []string{"Shipped it"},
+		}},
+		Education: []ResumeEducation{{
+			StudyType:   "BSc",
+			Area:        "Physics",
+			Institution: "University",
+			StartDate:   "2016",
+			EndDate:     "2019",
+			Location:    "Nottingham",
+			Score:       "First Class Honours",
+		}},
+		Skills: []ResumeSkill{{Name: "Languages", Keywords: []string{"Go", "Python"}}},
+	}
+
+	tests := map[ResumeFileFormat][]string{
+		ResumeFormatHTML:     {"<h1>Jane Doe</h1>", "<h3>Engineer, Acme &lt;Labs&gt;</h3>", "Jan 2020 - Present", "<li>Shipped it</li>", "Go, Python", "BSc Physics"},
+		ResumeFormatMarkdown: {"# Jane Doe", "### Engineer, Acme <Labs>", "- Shipped it", "**Stack:** Go, Python", "*2016 - 2019 · Nottingham*", "- **Languages:** Go, Python"},
+		ResumeFormatText:     {"Jane Doe", "EXPERIENCE", "  * Shipped it", "Stack: Go, Python", "Languages: Go, Python"},
+	}
+	for format, expected := range tests {
+		t.Run(string(format), func(t *testing.T) {
+			rendered, err := renderer.Render(format, resume)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			for _, substring := range expected {
+				if !strings.Contains(string(rendered), substring) {
+					t.Errorf("Expected %q in rendered resume, got %s", substring, rendered)
+				}
+			}
+		})
+	}
+
+	t.Run("Unsupported Format", func(t *testing.T) {
+		if _, err := renderer.Render(ResumeFormatPDF, resume); err == nil {
+			t.Errorf("Expected error, got nil")
+		}
+	})
+}
+
+func TestLoadResumeTemplates(t *testing.T) {
+	dir := t.TempDir()
+	override := `{{define "markdown"}}# Custom {{len .Skills}}{{end}}`
+	if err := os.WriteFile(filepath.Join(dir, "custom.md.tmpl"), []byte(override), 0o644); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	renderer, err := LoadResumeTemplates(dir)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	rendered, err := renderer.Render(ResumeFormatMarkdown, &Resume{Skills: make([]ResumeSkill, 2)})
+	if err != nil || string(rendered) != "# Custom 2" {
+		t.Errorf("Expected overridden markdown template, got %q %v", rendered, err)
+	}
+
+	// templates that are not overridden are still available
+	if _, err := renderer.Render(ResumeFormatText, &Resume{}); err != nil {
+		t.Errorf("Expected embedded text template, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "broken.html.tmpl"), []byte(`{{define "html"}}{{.`), 0o644); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := LoadResumeTemplates(dir); err == nil {
+		t.Errorf("Expected error for invalid template")
+	}
+}
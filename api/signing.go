@@ -0,0 +1,41 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// loadSigningSecret returns the configured secret used to sign the
+// named tokens. A random secret is generated if none is configured, in
+// which case tokens are only valid for the lifetime of the process.
+func loadSigningSecret(name, configured string) ([]byte, error) {
+	if configured != "" {
+		return []byte(configured), nil
+	}
+
+	log.Warn(fmt.Sprintf("no %s secret configured, %s signed by this process will not survive restarts", name, name))
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// signPayload returns the HMAC-SHA256 signature of the payload. The
+// domain is signed along with the payload, so that a token of one kind
+// cannot be passed off as another kind signed with the same secret.
+func signPayload(secret []byte, domain, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(domain + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyPayload checks the signature of the payload in constant time.
+func verifyPayload(secret []byte, domain, payload, signature string) bool {
+	return hmac.Equal([]byte(signature), []byte(signPayload(secret, domain, payload)))
+}
@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestLoadSigningSecret(t *testing.T) {
+	secret, err := loadSigningSecret("test tokens", "configured")
+	if err != nil || string(secret) != "configured" {
+		t.Errorf("Expected configured secret, got %q %v", secret, err)
+	}
+
+	first, _ := loadSigningSecret("test tokens", "")
+	second, _ := loadSigningSecret("test tokens", "")
+	if len(first) != 32 || string(first) == string(second) {
+		t.Errorf("Expected distinct random secrets, got %x and %x", first, second)
+	}
+}
+
+func TestSignPayload(t *testing.T) {
+	secret := []byte("secret")
+	signature := signPayload(secret, "challenge", "payload")
+
+	if !verifyPayload(secret, "challenge", "payload", signature) {
+		t.Errorf("Expected signature to be verified")
+	}
+
+	cases := []struct {
+		name      string
+		secret    []byte
+		domain    string
+		payload   string
+		signature string
+	}{
+		{"Other Secret", []byte("other"), "challenge", "payload", signature},
+		{"Other Domain", secret, "share", "payload", signature},
+		{"Other Payload", secret, "challenge", "tampered", signature},
+		{"Empty Signature", secret, "challenge", "payload", ""},
+	}
+	for _, tc := range cases {
+		if verifyPayload(tc.secret, tc.domain, tc.payload, tc.signature) {
+			t.Errorf("Expected %s to be rejected", tc.name)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scores added by each spam check. a submission is
+// flagged once its total reaches the filter threshold.
+// tokens signed by another replica or before a restart
+// cannot be verified, so an invalid token scores no
+// higher than a missing one
+const (
+	spamScoreHoneypot     = 100
+	spamScoreBlocklist    = 100
+	spamScoreMissingToken = 40
+	spamScoreInvalidToken = 40
+	spamScoreTooFast      = 60
+	spamScoreExpiredToken = 20
+	spamScorePerLink      = 20
+	spamScorePerKeyword   = 25
+)
+
+// spamLinkPattern matches plain, HTML and BBCode links
+var spamLinkPattern = regexp.MustCompile(`(?i)https?://|www\.|<a\s|\[url`)
+
+// SpamResult contains the outcome of checking a submission.
+type SpamResult struct {
+	Score   int
+	Reasons []string
+	Flagged bool
+}
+
+// SpamFilter scores contact form submissions using a set of layered
+// checks. Flagged submissions are still stored, so that false positives
+// can be recovered by the owner.
+type SpamFilter struct {
+	// Secret is used to sign form tokens
+	Secret      []byte
+	MinFillTime time.Duration
+	MaxTokenAge time.Duration
+	MaxLinks    int
+	Keywords    []string
+	Threshold   int
+	Blocklist   *SpamBlocklist
+}
+
+// NewSpamFilter creates the SpamFilter configured in the given config.
+func NewSpamFilter(cfg *Config) (*SpamFilter, error) {
+	secret, err := loadSigningSecret("form token", cfg.SpamTokenSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	blocklist := &SpamBlocklist{}
+	if cfg.SpamBlocklistPath != "" {
+		if blocklist, err = LoadSpamBlocklist(cfg.SpamBlocklistPath); err != nil {
+			return nil, fmt.Errorf("failed to load spam blocklist: %w", err)
+		}
+	}
+
+	var keywords []string
+	for _, keyword := range cfg.SpamKeywords {
+		if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" {
+			keywords = append(keywords, keyword)
+		}
+	}
+
+	filter := &SpamFilter{
+		Secret:      secret,
+		MinFillTime: cfg.SpamMinFillTime,
+		MaxTokenAge: cfg.SpamMaxTokenAge,
+		MaxLinks:    cfg.SpamMaxLinks,
+		Keywords:    keywords,
+		Threshold:   cfg.SpamThreshold,
+		Blocklist:   blocklist,
+	}
+	return filter, nil
+}
+
+// IssueToken returns a signed form token of the form "{timestamp}.{signature}".
+// The token is requested when the contact form is rendered, and submitted
+// along with the form so that the time taken to fill it can be checked.
+func (f *SpamFilter) IssueToken(now time.Time) string {
+	issued := strconv.FormatInt(now.Unix(), 10)
+	return issued + "." + signPayload(f.Secret, "contact-form", issued)
+}
+
+// checkToken validates a form token, returning the score
+// and reason if the token is missing, invalid or too recent.
+func (f *SpamFilter) checkToken(token string, now time.Time) (int, string) {
+	if token == "" {
+		return spamScoreMissingToken, "missing form token"
+	}
+
+	timestamp, signature, _ := strings.Cut(token, ".")
+	issued, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || !verifyPayload(f.Secret, "contact-form", timestamp, signature) {
+		return spamScoreInvalidToken, "invalid form token"
+	}
+
+	elapsed := now.Sub(time.Unix(issued, 0))
+	if elapsed < f.MinFillTime {
+		return spamScoreTooFast, fmt.Sprintf("form submitted after %s", elapsed.Truncate(time.Millisecond))
+	}
+	if f.MaxTokenAge > 0 && elapsed > f.MaxTokenAge {
+		return spamScoreExpiredToken, "expired form token"
+	}
+	return 0, ""
+}
+
+// Check scores a submission from the given IP address.
+func (f *SpamFilter) Check(body ContactRequestBody, ip string, now time.Time) SpamResult {
+	var result SpamResult
+	add := func(score int, reason string) {
+		result.Score += score
+		result.Reasons = append(result.Reasons, reason)
+	}
+
+	// the honeypot field is hidden from humans,
+	// so only bots fill it in
+	if body.Website != "" {
+		add(spamScoreHoneypot, "honeypot field filled")
+	}
+
+	if entry, blocked := f.Blocklist.Match(body.Email, ip); blocked {
+		add(spamScoreBlocklist, fmt.Sprintf("blocklisted %s", entry))
+	}
+
+	if score, reason := f.checkToken(body.Token, now); score > 0 {
+		add(score, reason)
+	}
+
+	text := strings.ToLower(body.Name + "\n" + body.Message)
+	if links := len(spamLinkPattern.FindAllStringIndex(text, -1)); links > f.MaxLinks {
+		add(spamScorePerLink*(links-f.MaxLinks), fmt.Sprintf("%d links", links))
+	}
+
+	for _, keyword := range f.Keywords {
+		if strings.Contains(text, keyword) {
+			add(spamScorePerKeyword, fmt.Sprintf("keyword %q", keyword))
+		}
+	}
+
+	result.Flagged = result.Score >= f.Threshold
+	return result
+}
+
+// SpamBlocklist contains blocked email addresses, email
+// domains and IP addresses or networks.
+type SpamBlocklist struct {
+	emails   map[string]bool
+	domains  map[string]bool
+	networks []*net.IPNet
+}
+
+// LoadSpamBlocklist reads a blocklist file containing one entry per line.
+// Entries containing an "@" block a single email address, IP addresses
+// and CIDR ranges block submissions from those addresses, and any other
+// entry blocks an email domain along with all of its subdomains. Empty
+// lines and lines starting with "#" are ignored.
+func LoadSpamBlocklist(path string) (*SpamBlocklist, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	blocklist := &SpamBlocklist{
+		emails:  make(map[string]bool),
+		domains: make(map[string]bool),
+	}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		entry := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		if strings.Contains(entry, "@") {
+			blocklist.emails[entry] = true
+		} else if _, network, err := net.ParseCIDR(entry); err == nil {
+			blocklist.networks = append(blocklist.networks, network)
+		} else if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			blocklist.networks = append(blocklist.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		} else {
+			blocklist.domains[strings.TrimPrefix(entry, "*.")] = true
+		}
+	}
+	return blocklist, scanner.Err()
+}
+
+// Match returns the blocklist entry matching the given email or IP
+// address. A nil blocklist does not match anything.
+func (b *SpamBlocklist) Match(email string, ip string) (string, bool) {
+	if b == nil {
+		return "", false
+	}
+
+	email = strings.ToLower(email)
+	if b.emails[email] {
+		return "email " + email, true
+	}
+
+	_, domain, _ := strings.Cut(email, "@")
+	for domain != "" {
+		if b.domains[domain] {
+			return "domain " + domain, true
+		}
+		_, domain, _ = strings.Cut(domain, ".")
+	}
+
+	if parsed := net.ParseIP(ip); parsed != nil {
+		for _, network := range b.networks {
+			if network.Contains(parsed) {
+				return "IP address " + ip, true
+			}
+		}
+	}
+	return "", false
+}
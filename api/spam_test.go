@@ -0,0 +1,162 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSpamFilter() *SpamFilter {
+	return &SpamFilter{
+		Secret:      []byte("secret"),
+		MinFillTime: 3 * time.Second,
+		MaxTokenAge: time.Hour,
+		MaxLinks:    2,
+		Keywords:    []string{"casino"},
+		Threshold:   50,
+	}
+}
+
+func TestSpamFilter(t *testing.T) {
+	filter := newTestSpamFilter()
+	now := time.Now()
+	// token issued long enough ago to pass the fill time check
+	token := filter.IssueToken(now.Add(-time.Minute))
+
+	body := ContactRequestBody{
+		Name:    "Alice",
+		Email:   "alice@example.com",
+		Message: "Hello, I would like to talk about a project",
+		Token:   token,
+	}
+
+	t.Run("Clean Submission", func(t *testing.T) {
+		result := filter.Check(body, "1.1.1.1", now)
+		if result.Flagged || result.Score != 0 {
+			t.Errorf("Expected clean submission, got %+v", result)
+		}
+	})
+
+	cases := []struct {
+		name    string
+		modify  func(body *ContactRequestBody)
+		flagged bool
+	}{
+		{"Honeypot", func(body *ContactRequestBody) { body.Website = "https://example.com" }, true},
+		{"Missing Token", func(body *ContactRequestBody) { body.Token = "" }, false},
+		{"Invalid Token", func(body *ContactRequestBody) { body.Token = "123.abc" }, false},
+		{"Tampered Token", func(body *ContactRequestBody) { body.Token = filter.IssueToken(now.Add(-time.Hour))[:11] + token[11:] }, false},
+		{"Token From Another Secret", func(body *ContactRequestBody) {
+			other := *filter
+			other.Secret = []byte("other")
+			body.Token = other.IssueToken(now.Add(-time.Minute))
+		}, false},
+		{"Keyword With Invalid Token", func(body *ContactRequestBody) {
+			body.Message = "Best CASINO bonuses"
+			body.Token = "123.abc"
+		}, true},
+		{"Submitted Too Fast", func(body *ContactRequestBody) { body.Token = filter.IssueToken(now) }, true},
+		{"Expired Token", func(body *ContactRequestBody) { body.Token = filter.IssueToken(now.Add(-2 * time.Hour)) }, false},
+		{"Links Within Limit", func(body *ContactRequestBody) { body.Message = "see https://a.com and www.b.com" }, false},
+		{"Too Many Links", func(body *ContactRequestBody) {
+			body.Message = "https://a.com https://b.com https://c.com <a href='d'> [url=e]"
+		}, true},
+		{"Keyword With Missing Token", func(body *ContactRequestBody) {
+			body.Message = "Best CASINO bonuses"
+			body.Token = ""
+		}, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			submission := body
+			tc.modify(&submission)
+
+			result := filter.Check(submission, "1.1.1.1", now)
+			if result.Flagged != tc.flagged {
+				t.Errorf("Expected flagged %t, got %+v", tc.flagged, result)
+			}
+
+			if result.Score > 0 && len(result.Reasons) == 0 {
+				t.Errorf("Expected reasons to be recorded, got %+v", result)
+			}
+		})
+	}
+
+	t.Run("Blocklist", func(t *testing.T) {
+		blocked := *filter
+		blocked.Blocklist = &SpamBlocklist{
+			emails: map[string]bool{"alice@example.com": true},
+		}
+
+		result := blocked.Check(body, "1.1.1.1", now)
+		if !result.Flagged || !strings.Contains(result.Reasons[0], "alice@example.com") {
+			t.Errorf("Expected blocklisted email to be flagged, got %+v", result)
+		}
+	})
+}
+
+func TestLoadSpamBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	contents := `# known spammers
+bot@example.com
+Spam.example
+*.bulk.example
+
+203.0.113.7
+198.51.100.0/24
+2001:db8::/32
+`
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	blocklist, err := LoadSpamBlocklist(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	cases := []struct {
+		email   string
+		ip      string
+		blocked bool
+	}{
+		{"alice@example.com", "1.1.1.1", false},
+		{"BOT@example.com", "1.1.1.1", true},
+		{"alice@spam.example", "1.1.1.1", true},
+		{"alice@mail.spam.example", "1.1.1.1", true},
+		{"alice@bulk.example", "1.1.1.1", true},
+		{"alice@notspam.example", "1.1.1.1", false},
+		{"alice@example.com", "203.0.113.7", true},
+		{"alice@example.com", "203.0.113.8", false},
+		{"alice@example.com", "198.51.100.42", true},
+		{"alice@example.com", "2001:db8::1", true},
+	}
+
+	for _, tc := range cases {
+		if _, blocked := blocklist.Match(tc.email, tc.ip); blocked != tc.blocked {
+			t.Errorf("Expected blocked %t for %s from %s, got %t", tc.blocked, tc.email, tc.ip, blocked)
+		}
+	}
+
+	if _, err := LoadSpamBlocklist(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Errorf("Expected error for missing blocklist")
+	}
+}
+
+func TestNewSpamFilter(t *testing.T) {
+	filter, err := NewSpamFilter(&Config{SpamKeywords: []string{" Casino ", ""}, SpamThreshold: 50})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(filter.Secret) == 0 {
+		t.Errorf("Expected a random secret to be generated")
+	}
+
+	if len(filter.Keywords) != 1 || filter.Keywords[0] != "casino" {
+		t.Errorf("Expected normalized keywords, got %v", filter.Keywords)
+	}
+}
@@ -0,0 +1,905 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type SQLitePersistence struct {
+	Conn *sql.DB
+}
+
+func (db *SQLitePersistence) HealthCheck(ctx context.Context) error {
+	return db.Conn.PingContext(ctx)
+}
+
+func (db *SQLitePersistence) GetContact(ctx context.Context, email string) (*Contact, error) {
+	var contact Contact
+	err := db.Conn.QueryRowContext(ctx,
+		"SELECT id, COALESCE(name, ''), email, created_at FROM contacts WHERE email=?", email).
+		Scan(&contact.Id, &contact.Name, &contact.Email, &contact.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ContactNotFoundError{Email: email}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// GetContactById retrieves a single contact by ID
+func (db *SQLitePersistence) GetContactById(ctx context.Context, id string) (*Contact, error) {
+	var contact Contact
+	err := db.Conn.QueryRowContext(ctx,
+		"SELECT id, COALESCE(name, ''), email, created_at FROM contacts WHERE id=?", id).
+		Scan(&contact.Id, &contact.Name, &contact.Email, &contact.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ContactNotFoundError{Id: id}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// UpdateContact updates the name and/or email of a contact
+// and returns the updated contact
+func (db *SQLitePersistence) UpdateContact(ctx context.Context, id string, update ContactUpdate) (*Contact, error) {
+	// fields not provided in the update are passed
+	// as NULL, so COALESCE keeps the current value
+	query := `
+		UPDATE contacts
+		SET name = COALESCE(?2, name), email = COALESCE(?3, email)
+		WHERE id=?1
+		RETURNING id, COALESCE(name, ''), email, created_at;`
+
+	var contact Contact
+	err := db.Conn.QueryRowContext(ctx, query, id, update.Name, update.Email).
+		Scan(&contact.Id, &contact.Name, &contact.Email, &contact.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ContactNotFoundError{Id: id}
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return nil, ContactConflictError{Email: *update.Email}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// DeleteContact deletes a contact. All contact requests
+// for the contact are removed by the ON DELETE CASCADE
+func (db *SQLitePersistence) DeleteContact(ctx context.Context, id string) error {
+	result, err := db.Conn.ExecContext(ctx, "DELETE FROM contacts WHERE id=?;", id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ContactNotFoundError{Id: id}
+	}
+	return nil
+}
+
+// CreateContact stores a new contact in the database
+func (db *SQLitePersistence) CreateContact(ctx context.Context, contact Contact) (string, error) {
+	id := uuid.New().String()
+	id = strings.ReplaceAll(id, "-", "")
+
+	query := `
+		INSERT INTO contacts (id, name, email, created_at)
+		VALUES (?, ?, ?, ?);`
+	_, err := db.Conn.ExecContext(ctx, query,
+		id, contact.Name, contact.Email, time.Now().UTC())
+	return id, err
+}
+
+// ListContacts retrieves all contacts from the database
+func (db *SQLitePersistence) ListContacts(ctx context.Context) ([]Contact, error) {
+	var contacts []Contact
+	query := `SELECT id, COALESCE(name, ''), email, created_at FROM contacts;`
+	rows, err := db.Conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var contact Contact
+		if err := rows.Scan(&contact.Id, &contact.Name, &contact.Email, &contact.CreatedAt); err != nil {
+			return nil, err
+		}
+		contacts = append(contacts, contact)
+	}
+	return contacts, rows.Err()
+}
+
+// QueryContacts retrieves a page of contacts matching the given query
+func (db *SQLitePersistence) QueryContacts(ctx context.Context, query ListQuery) ([]Contact, *ListCursor, error) {
+	statement, args := buildListQuery(
+		"SELECT id, COALESCE(name, ''), email, created_at FROM contacts",
+		listColumns{createdAt: "created_at", id: "id", email: "email"},
+		sqliteListQuery(query), sqlitePlaceholder)
+
+	rows, err := db.Conn.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var contacts []Contact
+	for rows.Next() {
+		var contact Contact
+		if err := rows.Scan(&contact.Id, &contact.Name, &contact.Email, &contact.CreatedAt); err != nil {
+			return nil, nil, err
+		}
+		contacts = append(contacts, contact)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	contacts, next := nextPage(contacts, query.Limit, contactCursor)
+	return contacts, next, nil
+}
+
+// CreateContactRequest stores a new contact request in the database
+func (db *SQLitePersistence) CreateContactRequest(ctx context.Context, entry ContactRequest) (string, error) {
+	id := uuid.New().String()
+	id = strings.ReplaceAll(id, "-", "")
+
+	status := entry.Status
+	if status == "" {
+		status = ContactRequestStatusNew
+	}
+
+	query := `
+		INSERT INTO contact_requests (id, contact_id, message, status, notes, spam_score, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?);`
+	_, err := db.Conn.ExecContext(ctx, query,
+		id, entry.ContactId, entry.Message, status, entry.Notes, entry.SpamScore, time.Now().UTC())
+	return id, err
+}
+
+// ListContactRequests retrieves all contact requests from the database
+func (db *SQLitePersistence) ListContactRequests(ctx context.Context) ([]ContactRequest, error) {
+	var requests []ContactRequest
+	// inner join to contacts to get email
+	query := `SELECT
+		cr.id,
+		cr.contact_id,
+		c.email,
+		cr.message,
+		cr.status,
+		cr.notes,
+		cr.spam_score,
+		cr.created_at
+	FROM
+		contact_requests cr
+	INNER JOIN
+		contacts c ON cr.contact_id = c.id;`
+
+	rows, err := db.Conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var request ContactRequest
+		if err := rows.Scan(&request.Id, &request.ContactId, &request.Email, &request.Message,
+			&request.Status, &request.Notes, &request.SpamScore, &request.CreatedAt); err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+	return requests, rows.Err()
+}
+
+// QueryContactRequests retrieves a page of contact requests matching the given query
+func (db *SQLitePersistence) QueryContactRequests(ctx context.Context, query ListQuery) ([]ContactRequest, *ListCursor, error) {
+	// inner join to contacts to get email
+	statement, args := buildListQuery(`SELECT
+			cr.id,
+			cr.contact_id,
+			c.email,
+			cr.message,
+			cr.status,
+			cr.notes,
+			cr.spam_score,
+			cr.created_at
+		FROM
+			contact_requests cr
+		INNER JOIN
+			contacts c ON cr.contact_id = c.id`,
+		listColumns{createdAt: "cr.created_at", id: "cr.id", email: "c.email", contactId: "cr.contact_id", status: "cr.status"},
+		sqliteListQuery(query), sqlitePlaceholder)
+
+	rows, err := db.Conn.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var requests []ContactRequest
+	for rows.Next() {
+		var request ContactRequest
+		if err := rows.Scan(&request.Id, &request.ContactId, &request.Email, &request.Message,
+			&request.Status, &request.Notes, &request.SpamScore, &request.CreatedAt); err != nil {
+			return nil, nil, err
+		}
+		requests = append(requests, request)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	requests, next := nextPage(requests, query.Limit, contactRequestCursor)
+	return requests, next, nil
+}
+
+// UpdateContactRequest updates the status and/or notes of a contact
+// request and returns the updated request. The transition is validated
+// and applied in one transaction.
+func (db *SQLitePersistence) UpdateContactRequest(ctx context.Context, id string, update ContactRequestUpdate) (*ContactRequest, error) {
+	tx, err := db.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var current ContactRequestStatus
+	err = tx.QueryRowContext(ctx,
+		"SELECT status FROM contact_requests WHERE id=?", id).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ContactRequestNotFoundError{Id: id}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if update.Status != nil && !current.CanTransitionTo(*update.Status) {
+		return nil, InvalidStatusTransitionError{From: current, To: *update.Status}
+	}
+
+	// fields not provided in the update are passed
+	// as NULL, so COALESCE keeps the current value
+	query := `
+		UPDATE contact_requests
+		SET status = COALESCE(?2, status), notes = COALESCE(?3, notes)
+		WHERE id=?1;`
+	if _, err := tx.ExecContext(ctx, query, id, update.Status, update.Notes); err != nil {
+		return nil, err
+	}
+
+	var request ContactRequest
+	err = tx.QueryRowContext(ctx, `SELECT
+			cr.id,
+			cr.contact_id,
+			c.email,
+			cr.message,
+			cr.status,
+			cr.notes,
+			cr.spam_score,
+			cr.created_at
+		FROM
+			contact_requests cr
+		INNER JOIN
+			contacts c ON cr.contact_id = c.id
+		WHERE cr.id=?;`, id).
+		Scan(&request.Id, &request.ContactId, &request.Email, &request.Message,
+			&request.Status, &request.Notes, &request.SpamScore, &request.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &request, tx.Commit()
+}
+
+// CreateWebhookDeliveries queues the given deliveries in a single
+// transaction. Deliveries are due for their first attempt immediately
+func (db *SQLitePersistence) CreateWebhookDeliveries(ctx context.Context, deliveries []WebhookDelivery) error {
+	tx, err := db.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO webhook_deliveries (id, subscription, event_id, event_type, payload, status, next_attempt_at, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?7);`
+
+	now := time.Now().UTC()
+	for _, delivery := range deliveries {
+		id := strings.ReplaceAll(uuid.New().String(), "-", "")
+		if _, err := tx.ExecContext(ctx, query, id, delivery.Subscription, delivery.EventId,
+			delivery.EventType, string(delivery.Payload), WebhookDeliveryPending, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ClaimWebhookDeliveries claims up to limit pending deliveries that are
+// due, and pushes back their next attempt by the lease so that they are
+// not claimed again while being delivered
+func (db *SQLitePersistence) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = ?2
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= ?1
+			ORDER BY next_attempt_at
+			LIMIT ?3
+		)
+		RETURNING ` + webhookDeliveryColumns + `;`
+
+	now := time.Now().UTC()
+	rows, err := db.Conn.QueryContext(ctx, query, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// UpdateWebhookDelivery records the outcome of a delivery attempt
+func (db *SQLitePersistence) UpdateWebhookDelivery(ctx context.Context, delivery WebhookDelivery) error {
+	var lastAttemptAt *time.Time
+	if delivery.LastAttemptAt != nil {
+		utc := delivery.LastAttemptAt.UTC()
+		lastAttemptAt = &utc
+	}
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = ?2, attempts = ?3, response_status = ?4, last_error = ?5,
+			next_attempt_at = ?6, last_attempt_at = ?7
+		WHERE id=?1;`
+	_, err := db.Conn.ExecContext(ctx, query, delivery.Id, delivery.Status, delivery.Attempts,
+		delivery.ResponseStatus, delivery.LastError, delivery.NextAttemptAt.UTC(), lastAttemptAt)
+	return err
+}
+
+// QueryWebhookDeliveries retrieves a page of webhook deliveries matching the given query
+func (db *SQLitePersistence) QueryWebhookDeliveries(ctx context.Context, query ListQuery) ([]WebhookDelivery, *ListCursor, error) {
+	statement, args := buildListQuery(
+		"SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries",
+		listColumns{createdAt: "created_at", id: "id"},
+		sqliteListQuery(query), sqlitePlaceholder)
+
+	rows, err := db.Conn.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	deliveries, next := nextPage(deliveries, query.Limit, webhookDeliveryCursor)
+	return deliveries, next, nil
+}
+
+// RedeemChallenge marks a solved challenge as used, returning a
+// ChallengeUsedError if it has already been redeemed. Expired
+// challenges are removed, as they can no longer be verified
+func (db *SQLitePersistence) RedeemChallenge(ctx context.Context, id string, expiresAt time.Time) error {
+	_, err := db.Conn.ExecContext(ctx, "DELETE FROM redeemed_challenges WHERE expires_at < ?;", time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO redeemed_challenges (id, expires_at)
+		VALUES (?, ?)
+		ON CONFLICT (id) DO NOTHING;`
+
+	result, err := db.Conn.ExecContext(ctx, query, id, expiresAt.UTC())
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ChallengeUsedError{Id: id}
+	}
+	return nil
+}
+
+// CreateResumeVersion stores a new version of a resume and makes it
+// the active version of its variant, locale and format
+func (db *SQLitePersistence) CreateResumeVersion(ctx context.Context, version ResumeVersion) (*ResumeVersion, error) {
+	tx, err := db.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE resume_versions SET active = FALSE
+		WHERE variant=? AND locale=? AND format=? AND active;`,
+		version.Variant, version.Locale, version.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO resume_versions (id, variant, locale, format, content, hash, author, active, created_at, activated_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, TRUE, ?8, ?8)
+		RETURNING ` + resumeVersionColumns + `;`
+	id := strings.ReplaceAll(uuid.New().String(), "-", "")
+	created, err := scanResumeVersion(tx.QueryRowContext(ctx, query, id, version.Variant, version.Locale,
+		version.Format, version.Content, version.Hash, version.Author, time.Now().UTC()), false)
+	if err != nil {
+		return nil, err
+	}
+	return &created, tx.Commit()
+}
+
+// ListResumeVersions lists all versions of the resume without
+// their content, most recent first
+func (db *SQLitePersistence) ListResumeVersions(ctx context.Context) ([]ResumeVersion, error) {
+	query := `SELECT ` + resumeVersionColumns + ` FROM resume_versions ORDER BY created_at DESC, id DESC;`
+	rows, err := db.Conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []ResumeVersion{}
+	for rows.Next() {
+		version, err := scanResumeVersion(rows, false)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// GetResumeVersion retrieves a single version of the resume
+// along with its content
+func (db *SQLitePersistence) GetResumeVersion(ctx context.Context, id string) (*ResumeVersion, error) {
+	query := `SELECT ` + resumeVersionColumns + `, content FROM resume_versions WHERE id=?;`
+	version, err := scanResumeVersion(db.Conn.QueryRowContext(ctx, query, id), true)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ResumeVersionNotFoundError{Id: id}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// ActivateResumeVersion makes the given version the active version
+// of its variant, locale and format, e.g. to roll back a change
+func (db *SQLitePersistence) ActivateResumeVersion(ctx context.Context, id string) (*ResumeVersion, error) {
+	tx, err := db.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var variant, locale, format string
+	err = tx.QueryRowContext(ctx,
+		"SELECT variant, locale, format FROM resume_versions WHERE id=?", id).
+		Scan(&variant, &locale, &format)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ResumeVersionNotFoundError{Id: id}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE resume_versions SET active = FALSE
+		WHERE variant=? AND locale=? AND format=? AND active AND id<>?;`,
+		variant, locale, format, id)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE resume_versions SET active = TRUE, activated_at = ? WHERE id=? RETURNING ` + resumeVersionColumns + `;`
+	version, err := scanResumeVersion(tx.QueryRowContext(ctx, query, time.Now().UTC(), id), false)
+	if err != nil {
+		return nil, err
+	}
+	return &version, tx.Commit()
+}
+
+// ListActiveResumeVersions lists the active version of each
+// variant, locale and format along with its content
+func (db *SQLitePersistence) ListActiveResumeVersions(ctx context.Context) ([]ResumeVersion, error) {
+	query := `SELECT ` + resumeVersionColumns + `, content FROM resume_versions WHERE active;`
+	rows, err := db.Conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []ResumeVersion
+	for rows.Next() {
+		version, err := scanResumeVersion(rows, true)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// CreateResumeShare stores a new link to the resume
+func (db *SQLitePersistence) CreateResumeShare(ctx context.Context, share ResumeShare) (*ResumeShare, error) {
+	query := `
+		INSERT INTO resume_shares (id, variant, locale, format, recipient, single_use, created_by, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING ` + resumeShareColumns + `;`
+	id := strings.ReplaceAll(uuid.New().String(), "-", "")
+	created, err := scanResumeShare(db.Conn.QueryRowContext(ctx, query, id, share.Variant, share.Locale, share.Format,
+		share.Recipient, share.SingleUse, share.CreatedBy, time.Now().UTC(), share.ExpiresAt.UTC()))
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// ListResumeShares lists all links to the resume, most recent first
+func (db *SQLitePersistence) ListResumeShares(ctx context.Context) ([]ResumeShare, error) {
+	query := `SELECT ` + resumeShareColumns + ` FROM resume_shares ORDER BY created_at DESC, id DESC;`
+	rows, err := db.Conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []ResumeShare{}
+	for rows.Next() {
+		share, err := scanResumeShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
+
+// GetResumeShare retrieves a link to the resume by id
+func (db *SQLitePersistence) GetResumeShare(ctx context.Context, id string) (*ResumeShare, error) {
+	query := `SELECT ` + resumeShareColumns + ` FROM resume_shares WHERE id=?;`
+	share, err := scanResumeShare(db.Conn.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ResumeShareNotFoundError{Id: id}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// OpenResumeShare records that a link to the resume was opened. Single
+// use links that have already been opened return a ResumeShareUsedError
+func (db *SQLitePersistence) OpenResumeShare(ctx context.Context, id string) (*ResumeShare, error) {
+	query := `
+		UPDATE resume_shares
+		SET opens = opens + 1, first_opened_at = COALESCE(first_opened_at, ?2), last_opened_at = ?2
+		WHERE id=?1 AND (NOT single_use OR opens = 0)
+		RETURNING ` + resumeShareColumns + `;`
+	share, err := scanResumeShare(db.Conn.QueryRowContext(ctx, query, id, time.Now().UTC()))
+	if errors.Is(err, sql.ErrNoRows) {
+		var exists bool
+		err = db.Conn.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM resume_shares WHERE id=?);", id).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, ResumeShareUsedError{Id: id}
+		}
+		return nil, ResumeShareNotFoundError{Id: id}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// LogRequests logs a batch of requests and their responses to the
+// database using multi-row inserts in a single transaction
+func (db *SQLitePersistence) LogRequests(ctx context.Context, requests []LoggedRequest, responses []LoggedResponse) error {
+	tx, err := db.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if len(requests) > 0 {
+		args := make([]any, 0, len(requests)*5)
+		for _, request := range requests {
+			args = append(args, request.ID, request.Method, request.Path, request.RequestTs.UTC(), request.IPAddress)
+		}
+		query := `INSERT INTO logged_requests (id, method, path, request_ts, ip_address) VALUES ` +
+			strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?), ", len(requests)), ", ") + `;`
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+
+	if len(responses) > 0 {
+		args := make([]any, 0, len(responses)*4)
+		for _, response := range responses {
+			args = append(args, response.RequestId, response.Status, response.TimeElapsed, response.ResponseTs.UTC())
+		}
+		query := `INSERT INTO logged_responses (id, status, time_elapsed, response_ts) VALUES ` +
+			strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?), ", len(responses)), ", ") + `;`
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RecordResumeDownload records a successful fetch of the resume
+func (db *SQLitePersistence) RecordResumeDownload(ctx context.Context, download ResumeDownload) error {
+	id := strings.ReplaceAll(uuid.New().String(), "-", "")
+	query := `
+		INSERT INTO resume_downloads (id, format, variant, locale, referrer, user_agent, ip_address, downloaded_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?);`
+	_, err := db.Conn.ExecContext(ctx, query, id, download.Format, download.Variant, download.Locale,
+		download.Referrer, download.UserAgent, download.IPAddress, download.DownloadedAt.UTC())
+	return err
+}
+
+// GetResumeStats aggregates the resume downloads in the given period.
+// Downloads are stored in UTC, so days are UTC days
+func (db *SQLitePersistence) GetResumeStats(ctx context.Context, query ResumeStatsQuery) (*ResumeStats, error) {
+	since, until := query.Since.UTC(), query.Until.UTC()
+	stats := ResumeStats{
+		Since:        since,
+		Until:        until,
+		Daily:        []ResumeDailyDownloads{},
+		TopReferrers: []ReferrerCount{},
+	}
+
+	totalsQuery := `
+		SELECT COUNT(*), COUNT(DISTINCT ip_address)
+		FROM resume_downloads
+		WHERE downloaded_at >= ? AND downloaded_at < ?;`
+	if err := db.Conn.QueryRowContext(ctx, totalsQuery, since, until).Scan(&stats.TotalDownloads, &stats.UniqueDownloaders); err != nil {
+		return nil, err
+	}
+
+	// timestamps are stored as text starting with the date
+	dailyQuery := `
+		SELECT substr(downloaded_at, 1, 10) AS day, format, COUNT(*)
+		FROM resume_downloads
+		WHERE downloaded_at >= ? AND downloaded_at < ?
+		GROUP BY day, format
+		ORDER BY day;`
+	rows, err := db.Conn.QueryContext(ctx, dailyQuery, since, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var date string
+		var format ResumeFileFormat
+		var count int
+		if err := rows.Scan(&date, &format, &count); err != nil {
+			return nil, err
+		}
+		stats.Daily = addDailyDownloads(stats.Daily, date, format, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// release the single connection before
+	// running the next query
+	rows.Close()
+
+	referrerQuery := `
+		SELECT referrer, COUNT(*) AS downloads
+		FROM resume_downloads
+		WHERE downloaded_at >= ? AND downloaded_at < ? AND referrer <> ''
+		GROUP BY referrer
+		ORDER BY downloads DESC, referrer
+		LIMIT ?;`
+	referrerRows, err := db.Conn.QueryContext(ctx, referrerQuery, since, until, query.Referrers)
+	if err != nil {
+		return nil, err
+	}
+	defer referrerRows.Close()
+
+	for referrerRows.Next() {
+		var referrer ReferrerCount
+		if err := referrerRows.Scan(&referrer.Referrer, &referrer.Count); err != nil {
+			return nil, err
+		}
+		stats.TopReferrers = append(stats.TopReferrers, referrer)
+	}
+	if err := referrerRows.Err(); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetRequestStats retrieves aggregated request statistics from the database
+func (db *SQLitePersistence) GetRequestStats(ctx context.Context) (*RequestStats, error) {
+
+	var stats RequestStats
+
+	statsQuery := `SELECT
+		COUNT(*) AS total_requests,
+		COUNT(DISTINCT ip_address) AS unique_ip_count
+	FROM
+		logged_requests;`
+
+	if err := db.Conn.QueryRowContext(ctx, statsQuery).Scan(&stats.TotalRequests, &stats.UniqueIPCount); err != nil {
+		return nil, err
+	}
+
+	pathQuery := `SELECT
+			COUNT(path) AS request_count, path
+		FROM
+			logged_requests
+		GROUP BY
+			path
+		ORDER BY
+			request_count DESC;`
+
+	rows, err := db.Conn.QueryContext(ctx, pathQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pathCounts := make(map[string]int)
+
+	for rows.Next() {
+		var count int
+		var path string
+		if err := rows.Scan(&count, &path); err != nil {
+			return nil, err
+		}
+		pathCounts[path] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// release the single connection before
+	// running the next query
+	rows.Close()
+
+	stats.PathCounts = pathCounts
+
+	statusQuery := `SELECT
+			COUNT(status) AS status_count, status
+		FROM
+			logged_responses
+		GROUP BY
+			status
+		ORDER BY
+			status_count DESC;`
+
+	statusRows, err := db.Conn.QueryContext(ctx, statusQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer statusRows.Close()
+
+	statusCounts := make(map[int]int)
+
+	for statusRows.Next() {
+		var count int
+		var status int
+		if err := statusRows.Scan(&count, &status); err != nil {
+			return nil, err
+		}
+		statusCounts[status] = count
+	}
+	if err := statusRows.Err(); err != nil {
+		return nil, err
+	}
+
+	stats.StatusCounts = statusCounts
+
+	return &stats, nil
+}
+
+// GetAPIKey retrieves an API key from the database
+func (db *SQLitePersistence) GetAPIKey(ctx context.Context, key string) (*APIKey, error) {
+
+	var apiKey APIKey
+	err := db.Conn.QueryRowContext(ctx,
+		"SELECT key, owner, created_at, expires_at FROM api_keys WHERE key=?", key).
+		Scan(&apiKey.Key, &apiKey.Owner, &apiKey.CreatedAt, &apiKey.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, APIKeyNotFoundError{Key: key}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+// sqliteListQuery converts all timestamps in the query to UTC. SQLite
+// stores timestamps as text, so they must be compared in the same
+// timezone as the stored values to be ordered correctly.
+func sqliteListQuery(query ListQuery) ListQuery {
+	if query.Since != nil {
+		since := query.Since.UTC()
+		query.Since = &since
+	}
+	if query.Until != nil {
+		until := query.Until.UTC()
+		query.Until = &until
+	}
+	if query.Cursor != nil {
+		cursor := *query.Cursor
+		cursor.CreatedAt = cursor.CreatedAt.UTC()
+		query.Cursor = &cursor
+	}
+	return query
+}
+
+// Close closes the underlying database file.
+func (db *SQLitePersistence) Close() error {
+	return db.Conn.Close()
+}
+
+// NewSQLitePersistence opens (or creates) the SQLite database at the
+// given path. Migrations are applied separately using the Migrator.
+func NewSQLitePersistence(ctx context.Context, path string) (*SQLitePersistence, error) {
+	// enable foreign keys to match postgres ON DELETE CASCADE
+	// behaviour, and wait on locks rather than failing
+	dsn := "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	conn, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite only supports a single writer, so
+	// serialize access through a single connection
+	conn.SetMaxOpenConns(1)
+
+	db := &SQLitePersistence{
+		Conn: conn,
+	}
+
+	if err := conn.PingContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,531 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newTestSQLitePersistence(t *testing.T) *SQLitePersistence {
+	t.Helper()
+
+	db, err := NewSQLitePersistence(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return db
+}
+
+func TestSQLitePersistence(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Health Check", func(t *testing.T) {
+		db := newTestSQLitePersistence(t)
+
+		if err := db.HealthCheck(ctx); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("Create and Get Contact", func(t *testing.T) {
+		db := newTestSQLitePersistence(t)
+
+		id, err := db.CreateContact(ctx, Contact{Name: "Alice", Email: "alice@example.com"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		contact, err := db.GetContact(ctx, "alice@example.com")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if contact.Id != id || contact.Name != "Alice" {
+			t.Errorf("Expected contact %s named Alice, got %+v", id, contact)
+		}
+
+		if contact.CreatedAt.IsZero() {
+			t.Errorf("Expected created_at to be set")
+		}
+
+		if _, err := db.CreateContact(ctx, Contact{Name: "Alice", Email: "alice@example.com"}); err == nil {
+			t.Errorf("Expected unique constraint error for duplicate email")
+		}
+	})
+
+	t.Run("Contact Not Found", func(t *testing.T) {
+		db := newTestSQLitePersistence(t)
+
+		_, err := db.GetContact(ctx, "missing@example.com")
+		if _, ok := err.(ContactNotFoundError); !ok {
+			t.Errorf("Expected ContactNotFoundError, got %v", err)
+		}
+	})
+
+	t.Run("List Contact Requests", func(t *testing.T) {
+		db := newTestSQLitePersistence(t)
+
+		contactId, _ := db.CreateContact(ctx, Contact{Name: "Bob", Email: "bob@example.com"})
+		if _, err := db.CreateContactRequest(ctx, ContactRequest{ContactId: contactId, Message: "Hello"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if _, err := db.CreateContactRequest(ctx, ContactRequest{ContactId: "missing", Message: "Hello"}); err == nil {
+			t.Errorf("Expected foreign key error for unknown contact")
+		}
+
+		requests, err := db.ListContactRequests(ctx)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(requests) != 1 || requests[0].Email != "bob@example.com" {
+			t.Errorf("Expected 1 request for bob@example.com, got %+v", requests)
+		}
+	})
+
+	t.Run("Query Contact Requests", func(t *testing.T) {
+		db := newTestSQLitePersistence(t)
+
+		aliceId, _ := db.CreateContact(ctx, Contact{Name: "Alice", Email: "alice@example.com"})
+		bobId, _ := db.CreateContact(ctx, Contact{Name: "Bob", Email: "bob@example.com"})
+		for i := range 5 {
+			contactId := aliceId
+			if i%2 == 1 {
+				contactId = bobId
+			}
+			if _, err := db.CreateContactRequest(ctx, ContactRequest{ContactId: contactId, Message: "Hello"}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		}
+
+		var requests []ContactRequest
+		query := ListQuery{Limit: 2, Descending: true}
+		for {
+			page, next, err := db.QueryContactRequests(ctx, query)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			requests = append(requests, page...)
+			if next == nil {
+				break
+			}
+			query.Cursor = next
+		}
+
+		if len(requests) != 5 {
+			t.Fatalf("Expected 5 requests, got %d", len(requests))
+		}
+
+		for i := 1; i < len(requests); i++ {
+			if requests[i].CreatedAt.After(requests[i-1].CreatedAt) {
+				t.Errorf("Expected requests in descending order")
+			}
+		}
+
+		filtered, _, err := db.QueryContactRequests(ctx, ListQuery{Limit: 10, Email: "bob@example.com"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(filtered) != 2 {
+			t.Errorf("Expected 2 requests for bob@example.com, got %d", len(filtered))
+		}
+
+		future := time.Now().Add(time.Hour)
+		filtered, _, _ = db.QueryContactRequests(ctx, ListQuery{Limit: 10, Since: &future})
+		if len(filtered) != 0 {
+			t.Errorf("Expected no requests since %s, got %d", future, len(filtered))
+		}
+	})
+
+	t.Run("Update and Delete Contact", func(t *testing.T) {
+		db := newTestSQLitePersistence(t)
+
+		aliceId, _ := db.CreateContact(ctx, Contact{Name: "Alice", Email: "alice@example.com"})
+		_, _ = db.CreateContact(ctx, Contact{Name: "Bob", Email: "bob@example.com"})
+		_, _ = db.CreateContactRequest(ctx, ContactRequest{ContactId: aliceId, Message: "Hello"})
+
+		name := "Alice Smith"
+		contact, err := db.UpdateContact(ctx, aliceId, ContactUpdate{Name: &name})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if contact.Name != name || contact.Email != "alice@example.com" {
+			t.Errorf("Expected only name to be updated, got %+v", contact)
+		}
+
+		email := "bob@example.com"
+		if _, err := db.UpdateContact(ctx, aliceId, ContactUpdate{Email: &email}); err == nil {
+			t.Errorf("Expected ContactConflictError, got nil")
+		} else if _, ok := err.(ContactConflictError); !ok {
+			t.Errorf("Expected ContactConflictError, got %v", err)
+		}
+
+		if _, err := db.UpdateContact(ctx, "missing", ContactUpdate{Name: &name}); err == nil {
+			t.Errorf("Expected ContactNotFoundError, got nil")
+		}
+
+		if err := db.DeleteContact(ctx, aliceId); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if _, err := db.GetContactById(ctx, aliceId); err == nil {
+			t.Errorf("Expected ContactNotFoundError after delete")
+		}
+
+		requests, _, _ := db.QueryContactRequests(ctx, ListQuery{Limit: 10, ContactId: aliceId})
+		if len(requests) != 0 {
+			t.Errorf("Expected contact requests to be deleted, got %d", len(requests))
+		}
+
+		if err := db.DeleteContact(ctx, aliceId); err == nil {
+			t.Errorf("Expected ContactNotFoundError deleting twice")
+		}
+	})
+
+	t.Run("Triage Contact Request", func(t *testing.T) {
+		db := newTestSQLitePersistence(t)
+
+		contactId, _ := db.CreateContact(ctx, Contact{Name: "Bob", Email: "bob@example.com"})
+		id, _ := db.CreateContactRequest(ctx, ContactRequest{ContactId: contactId, Message: "Hello"})
+		_, _ = db.CreateContactRequest(ctx, ContactRequest{ContactId: contactId, Message: "Hello again"})
+
+		read, notes := ContactRequestStatusRead, "replied by phone"
+		request, err := db.UpdateContactRequest(ctx, id, ContactRequestUpdate{Status: &read, Notes: &notes})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if request.Status != read || request.Notes != notes || request.Email != "bob@example.com" {
+			t.Errorf("Expected read request with notes for bob@example.com, got %+v", request)
+		}
+
+		spam := ContactRequestStatusSpam
+		if _, err := db.UpdateContactRequest(ctx, id, ContactRequestUpdate{Status: &spam}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		replied := ContactRequestStatusReplied
+		if _, err := db.UpdateContactRequest(ctx, id, ContactRequestUpdate{Status: &replied}); err == nil {
+			t.Errorf("Expected InvalidStatusTransitionError, got nil")
+		} else if _, ok := err.(InvalidStatusTransitionError); !ok {
+			t.Errorf("Expected InvalidStatusTransitionError, got %v", err)
+		}
+
+		if _, err := db.UpdateContactRequest(ctx, "missing", ContactRequestUpdate{Notes: &notes}); err == nil {
+			t.Errorf("Expected ContactRequestNotFoundError, got nil")
+		} else if _, ok := err.(ContactRequestNotFoundError); !ok {
+			t.Errorf("Expected ContactRequestNotFoundError, got %v", err)
+		}
+
+		requests, _, err := db.QueryContactRequests(ctx, ListQuery{Limit: 10, Status: ContactRequestStatusNew})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(requests) != 1 || requests[0].Id == id {
+			t.Errorf("Expected 1 new request, got %+v", requests)
+		}
+
+		requests, _, _ = db.QueryContactRequests(ctx, ListQuery{Limit: 10, Status: spam})
+		if len(requests) != 1 || requests[0].Id != id || requests[0].Notes != notes {
+			t.Errorf("Expected request %s marked as spam, got %+v", id, requests)
+		}
+	})
+
+	t.Run("Contact Request Spam Score", func(t *testing.T) {
+		db := newTestSQLitePersistence(t)
+
+		contactId, _ := db.CreateContact(ctx, Contact{Name: "Bot", Email: "bot@example.com"})
+		if _, err := db.CreateContactRequest(ctx, ContactRequest{
+			ContactId: contactId, Message: "spam", Status: ContactRequestStatusSpam, SpamScore: 140,
+		}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		requests, _, err := db.QueryContactRequests(ctx, ListQuery{Limit: 10})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(requests) != 1 || requests[0].SpamScore != 140 || requests[0].Status != ContactRequestStatusSpam {
+			t.Errorf("Expected spam request with score 140, got %+v", requests)
+		}
+	})
+
+	t.Run("Redeem Challenge", func(t *testing.T) {
+		db := newTestSQLitePersistence(t)
+
+		if err := db.RedeemChallenge(ctx, "challenge1", time.Now().Add(time.Minute)); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		var errUsed ChallengeUsedError
+		if err := db.RedeemChallenge(ctx, "challenge1", time.Now().Add(time.Minute)); !errors.As(err, &errUsed) {
+			t.Errorf("Expected ChallengeUsedError, got %v", err)
+		}
+
+		// expired challenges are removed when redeeming
+		if err := db.RedeemChallenge(ctx, "challenge2", time.Now().Add(-time.Minute)); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := db.RedeemChallenge(ctx, "challenge2", time.Now().Add(time.Minute)); err != nil {
+			t.Errorf("Expected expired challenge to be removed, got %v", err)
+		}
+	})
+
+	t.Run("Resume Versions", func(t *testing.T) {
+		db := newTestSQLitePersistence(t)
+
+		first, err := db.CreateResumeVersion(ctx, ResumeVersion{Variant: "default", Locale: "en", Format: ResumeFormatJSON, Content: []byte(`{"basics": {}}`), Hash: "first", Author: "admin"})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !first.Active || first.Size != 14 || first.Content != nil || first.CreatedAt.IsZero() {
+			t.Errorf("Expected active version without content, got %+v", first)
+		}
+
+		second, _ := db.CreateResumeVersion(ctx, ResumeVersion{Variant: "default", Locale: "en", Format: ResumeFormatJSON, Content: []byte(`{}`), Hash: "second", Author: "admin"})
+		db.CreateResumeVersion(ctx, ResumeVersion{Variant: "default", Locale: "de", Format: ResumeFormatJSON, Content: []byte(`{}`), Hash: "third", Author: "admin"})
+
+		versions, err := db.ListResumeVersions(ctx)
+		if err != nil || len(versions) != 3 || versions[1].Id != second.Id || versions[2].Active {
+			t.Errorf("Expected versions most recent first with the first inactive, got %+v %v", versions, err)
+		}
+
+		// rolling back makes the first version active again
+		if _, err := db.ActivateResumeVersion(ctx, first.Id); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		active, _ := db.ListActiveResumeVersions(ctx)
+		hashes := []string{}
+		for _, version := range active {
+			hashes = append(hashes, version.Hash)
+		}
+		slices.Sort(hashes)
+		if !slices.Equal(hashes, []string{"first", "third"}) {
+			t.Errorf("Expected first and third versions to be active, got %v", hashes)
+		}
+
+		version, err := db.GetResumeVersion(ctx, first.Id)
+		if err != nil || string(version.Content) != `{"basics": {}}` || version.ActivatedAt.Before(version.CreatedAt) {
+			t.Errorf("Expected version with content, got %+v %v", version, err)
+		}
+
+		var errNotFound ResumeVersionNotFoundError
+		if _, err := db.GetResumeVersion(ctx, "missing"); !errors.As(err, &errNotFound) {
+			t.Errorf("Expected ResumeVersionNotFoundError, got %v", err)
+		}
+		if _, err := db.ActivateResumeVersion(ctx, "missing"); !errors.As(err, &errNotFound) {
+			t.Errorf("Expected ResumeVersionNotFoundError, got %v", err)
+		}
+	})
+
+	t.Run("Resume Downloads", func(t *testing.T) {
+		db := newTestSQLitePersistence(t)
+
+		day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+		downloads := []ResumeDownload{
+			{Format: ResumeFormatPDF, Referrer: "linkedin.com", IPAddress: "1.1.1.1", DownloadedAt: day.Add(time.Hour)},
+			{Format: ResumeFormatPDF, Referrer: "linkedin.com", IPAddress: "1.1.1.1", DownloadedAt: day.Add(2 * time.Hour)},
+			{Format: ResumeFormatJSON, Referrer: "github.com", IPAddress: "2.2.2.2", DownloadedAt: day.Add(3 * time.Hour)},
+			{Format: ResumeFormatPDF, IPAddress: "3.3.3.3", DownloadedAt: day.Add(25 * time.Hour)},
+			// outside of the queried period
+			{Format: ResumeFormatPDF, Referrer: "example.com", IPAddress: "4.4.4.4", DownloadedAt: day.Add(-time.Hour)},
+		}
+		for _, download := range downloads {
+			download.Variant, download.Locale = "default", "en"
+			if err := db.RecordResumeDownload(ctx, download); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		}
+
+		stats, err := db.GetResumeStats(ctx, ResumeStatsQuery{Since: day, Until: day.AddDate(0, 0, 7), Referrers: 1})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if stats.TotalDownloads != 4 || stats.UniqueDownloaders != 3 {
+			t.Errorf("Expected 4 downloads by 3 downloaders, got %+v", stats)
+		}
+		expected := []ResumeDailyDownloads{
+			{Date: "2026-03-01", Formats: map[ResumeFileFormat]int{ResumeFormatPDF: 2, ResumeFormatJSON: 1}},
+			{Date: "2026-03-02", Formats: map[ResumeFileFormat]int{ResumeFormatPDF: 1}},
+		}
+		if !reflect.DeepEqual(stats.Daily, expected) {
+			t.Errorf("Expected daily downloads %+v, got %+v", expected, stats.Daily)
+		}
+		if !slices.Equal(stats.TopReferrers, []ReferrerCount{{Referrer: "linkedin.com", Count: 2}}) {
+			t.Errorf("Expected linkedin.com as top referrer, got %+v", stats.TopReferrers)
+		}
+
+		empty, err := db.GetResumeStats(ctx, ResumeStatsQuery{Since: day.AddDate(1, 0, 0), Until: day.AddDate(1, 0, 1), Referrers: 10})
+		if err != nil || empty.TotalDownloads != 0 || len(empty.Daily) != 0 || empty.TopReferrers == nil {
+			t.Errorf("Expected empty stats, got %+v %v", empty, err)
+		}
+	})
+
+	t.Run("Resume Shares", func(t *testing.T) {
+		db := newTestSQLitePersistence(t)
+
+		expiresAt := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+		share, err := db.CreateResumeShare(ctx, ResumeShare{Variant: "default", Locale: "en", Format: ResumeFormatPDF, Recipient: "recruiter", SingleUse: true, CreatedBy: "admin", ExpiresAt: expiresAt})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if share.Id == "" || share.Opens != 0 || share.FirstOpenedAt != nil || !share.ExpiresAt.Equal(expiresAt) {
+			t.Errorf("Expected unopened share, got %+v", share)
+		}
+		reusable, _ := db.CreateResumeShare(ctx, ResumeShare{Variant: "default", Locale: "en", Format: ResumeFormatJSON, CreatedBy: "admin", ExpiresAt: expiresAt})
+
+		opened, err := db.OpenResumeShare(ctx, share.Id)
+		if err != nil || opened.Opens != 1 || opened.FirstOpenedAt == nil || opened.LastOpenedAt == nil {
+			t.Errorf("Expected opened share, got %+v %v", opened, err)
+		}
+
+		// single use shares can only be opened once
+		var errUsed ResumeShareUsedError
+		if _, err := db.OpenResumeShare(ctx, share.Id); !errors.As(err, &errUsed) {
+			t.Errorf("Expected ResumeShareUsedError, got %v", err)
+		}
+		db.OpenResumeShare(ctx, reusable.Id)
+		if opened, err := db.OpenResumeShare(ctx, reusable.Id); err != nil || opened.Opens != 2 {
+			t.Errorf("Expected share opened twice, got %+v %v", opened, err)
+		}
+
+		var errNotFound ResumeShareNotFoundError
+		if _, err := db.OpenResumeShare(ctx, "missing"); !errors.As(err, &errNotFound) {
+			t.Errorf("Expected ResumeShareNotFoundError, got %v", err)
+		}
+
+		if got, err := db.GetResumeShare(ctx, share.Id); err != nil || got.Opens != 1 || got.Recipient != "recruiter" {
+			t.Errorf("Expected opened share, got %+v %v", got, err)
+		}
+		if _, err := db.GetResumeShare(ctx, "missing"); !errors.As(err, &errNotFound) {
+			t.Errorf("Expected ResumeShareNotFoundError, got %v", err)
+		}
+
+		shares, err := db.ListResumeShares(ctx)
+		if err != nil || len(shares) != 2 || shares[0].Id != reusable.Id || shares[1].Opens != 1 {
+			t.Errorf("Expected shares most recent first, got %+v %v", shares, err)
+		}
+	})
+
+	t.Run("Webhook Delivery Queue", func(t *testing.T) {
+		db := newTestSQLitePersistence(t)
+
+		deliveries := []WebhookDelivery{
+			{Subscription: "crm", EventId: "event1", EventType: EventContactCreated, Payload: []byte(`{"id":"event1"}`)},
+			{Subscription: "chat", EventId: "event1", EventType: EventContactCreated, Payload: []byte(`{"id":"event1"}`)},
+		}
+		if err := db.CreateWebhookDeliveries(ctx, deliveries); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		claimed, err := db.ClaimWebhookDeliveries(ctx, 1, time.Minute)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(claimed) != 1 || string(claimed[0].Payload) != `{"id":"event1"}` {
+			t.Fatalf("Expected 1 claimed delivery, got %+v", claimed)
+		}
+
+		// claimed deliveries are leased and cannot be claimed again
+		remaining, _ := db.ClaimWebhookDeliveries(ctx, 10, time.Minute)
+		if len(remaining) != 1 || remaining[0].Id == claimed[0].Id {
+			t.Errorf("Expected the other delivery to be claimed, got %+v", remaining)
+		}
+
+		now := time.Now()
+		delivery := claimed[0]
+		delivery.Status = WebhookDeliveryDelivered
+		delivery.Attempts = 1
+		delivery.ResponseStatus = 204
+		delivery.LastAttemptAt = &now
+		if err := db.UpdateWebhookDelivery(ctx, delivery); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		page, next, err := db.QueryWebhookDeliveries(ctx, ListQuery{Limit: 10})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(page) != 2 || next != nil {
+			t.Fatalf("Expected 2 deliveries on a single page, got %d", len(page))
+		}
+
+		for _, d := range page {
+			if d.Id == delivery.Id && (d.Status != WebhookDeliveryDelivered || d.ResponseStatus != 204 || d.LastAttemptAt == nil) {
+				t.Errorf("Expected delivered delivery, got %+v", d)
+			}
+		}
+	})
+
+	t.Run("Request Stats", func(t *testing.T) {
+		db := newTestSQLitePersistence(t)
+
+		var requests []LoggedRequest
+		var responses []LoggedResponse
+		for i, ip := range []string{"1.1.1.1", "1.1.1.1", "2.2.2.2"} {
+			id := fmt.Sprintf("request-%d", i)
+			requests = append(requests, LoggedRequest{ID: id, Method: "GET", Path: "/health", IPAddress: ip, RequestTs: time.Now()})
+			responses = append(responses, LoggedResponse{RequestId: id, Status: 200, ResponseTs: time.Now()})
+		}
+		if err := db.LogRequests(ctx, requests, responses); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		stats, err := db.GetRequestStats(ctx)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if stats.TotalRequests != 3 || stats.UniqueIPCount != 2 {
+			t.Errorf("Expected 3 requests from 2 IPs, got %+v", stats)
+		}
+
+		if stats.PathCounts["/health"] != 3 || stats.StatusCounts[200] != 3 {
+			t.Errorf("Expected 3 requests to /health with status 200, got %+v", stats)
+		}
+	})
+
+	t.Run("Get API Key", func(t *testing.T) {
+		db := newTestSQLitePersistence(t)
+
+		expires := time.Now().Add(time.Hour).UTC()
+		if _, err := db.Conn.ExecContext(ctx,
+			"INSERT INTO api_keys (key, owner, expires_at) VALUES (?, ?, ?)", "key", "admin", expires); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		apiKey, err := db.GetAPIKey(ctx, "key")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if apiKey.Owner != "admin" || !apiKey.ExpiresAt.Equal(expires) {
+			t.Errorf("Expected key owned by admin expiring at %s, got %+v", expires, apiKey)
+		}
+
+		if _, err := db.GetAPIKey(ctx, "missing"); err == nil {
+			t.Errorf("Expected APIKeyNotFoundError")
+		}
+	})
+}
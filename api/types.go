@@ -1,6 +1,9 @@
 package main
 
 import (
+	"encoding/json"
+	"net/http"
+	"slices"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -9,11 +12,41 @@ import (
 type RESTResponse struct {
 	Code    int         `json:"code"`
 	Payload interface{} `json:"payload"`
+	// File is streamed instead of the payload if set
+	File *FileResponse `json:"-"`
+	// Body is sent instead of the payload if a
+	// content type is set
+	ContentType string `json:"-"`
+	Body        []byte `json:"-"`
+	// validators used to answer conditional requests
+	// with a 304 Not Modified if set
+	ETag         string    `json:"-"`
+	LastModified time.Time `json:"-"`
 }
 
 // Send writes the RESTResponse to the Gin context.
 func (r RESTResponse) Send(c *gin.Context) {
-	c.JSON(r.Code, r.Payload)
+	if r.File == nil && r.Code == http.StatusOK && (r.ETag != "" || !r.LastModified.IsZero()) {
+		if r.ETag != "" {
+			c.Header("ETag", r.ETag)
+		}
+		if !r.LastModified.IsZero() {
+			c.Header("Last-Modified", r.LastModified.UTC().Format(http.TimeFormat))
+		}
+		if notModified(c.Request, r.ETag, r.LastModified) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	switch {
+	case r.File != nil:
+		r.File.Send(c)
+	case r.ContentType != "":
+		c.Data(r.Code, r.ContentType, r.Body)
+	default:
+		c.JSON(r.Code, r.Payload)
+	}
 }
 
 type Contact struct {
@@ -23,12 +56,79 @@ type Contact struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// ContactUpdate contains the fields of a contact that can be
+// updated. Fields left as nil are not changed.
+type ContactUpdate struct {
+	Name  *string
+	Email *string
+}
+
 type ContactRequest struct {
-	Id        string    `json:"id"`
-	ContactId string    `json:"contact_id"`
-	Email     string    `json:"email"`
-	Message   string    `json:"message"`
-	CreatedAt time.Time `json:"created_at"`
+	Id        string               `json:"id"`
+	ContactId string               `json:"contact_id"`
+	Email     string               `json:"email"`
+	Message   string               `json:"message"`
+	Status    ContactRequestStatus `json:"status"`
+	Notes     string               `json:"notes"`
+	SpamScore int                  `json:"spam_score"`
+	CreatedAt time.Time            `json:"created_at"`
+}
+
+// ContactRequestUpdate contains the triage fields of a contact
+// request that can be updated. Fields left as nil are not changed.
+type ContactRequestUpdate struct {
+	Status *ContactRequestStatus
+	Notes  *string
+}
+
+// ContactRequestStatus is the triage status of a contact request.
+type ContactRequestStatus string
+
+const (
+	ContactRequestStatusNew      ContactRequestStatus = "new"
+	ContactRequestStatusRead     ContactRequestStatus = "read"
+	ContactRequestStatusReplied  ContactRequestStatus = "replied"
+	ContactRequestStatusArchived ContactRequestStatus = "archived"
+	ContactRequestStatusSpam     ContactRequestStatus = "spam"
+)
+
+// contactRequestTransitions maps each status to
+// the statuses a contact request can move to.
+var contactRequestTransitions = map[ContactRequestStatus][]ContactRequestStatus{
+	ContactRequestStatusNew: {
+		ContactRequestStatusRead,
+		ContactRequestStatusReplied,
+		ContactRequestStatusArchived,
+		ContactRequestStatusSpam,
+	},
+	ContactRequestStatusRead: {
+		ContactRequestStatusNew,
+		ContactRequestStatusReplied,
+		ContactRequestStatusArchived,
+		ContactRequestStatusSpam,
+	},
+	ContactRequestStatusReplied: {
+		ContactRequestStatusArchived,
+	},
+	ContactRequestStatusArchived: {
+		ContactRequestStatusRead,
+	},
+	ContactRequestStatusSpam: {
+		ContactRequestStatusNew,
+		ContactRequestStatusArchived,
+	},
+}
+
+// Valid returns true if the status is a known status.
+func (s ContactRequestStatus) Valid() bool {
+	_, exists := contactRequestTransitions[s]
+	return exists
+}
+
+// CanTransitionTo returns true if a contact request with the status
+// can be moved to the next status. Keeping the same status is allowed.
+func (s ContactRequestStatus) CanTransitionTo(next ContactRequestStatus) bool {
+	return s == next || slices.Contains(contactRequestTransitions[s], next)
 }
 
 type LoggedRequest struct {
@@ -58,11 +158,208 @@ type RequestStats struct {
 	UniqueIPCount int            `json:"unique_ip_count"`
 	PathCounts    map[string]int `json:"path_counts"`
 	StatusCounts  map[int]int    `json:"status_counts"`
+	// DroppedRequestLogs is the number of requests and resume
+	// downloads this instance dropped because the log queue was full
+	DroppedRequestLogs int64 `json:"dropped_request_logs"`
+}
+
+// ResumeDownload is a single successful fetch of the resume. Only the
+// host of the referrer is recorded, as referrer URLs can contain
+// personal data in their query strings.
+type ResumeDownload struct {
+	Format       ResumeFileFormat `json:"format"`
+	Variant      string           `json:"variant"`
+	Locale       string           `json:"locale"`
+	Referrer     string           `json:"referrer"`
+	UserAgent    string           `json:"user_agent"`
+	IPAddress    string           `json:"ip_address"`
+	DownloadedAt time.Time        `json:"downloaded_at"`
+}
+
+// ResumeStatsQuery restricts resume statistics to downloads in
+// [Since, Until), and limits the number of top referrers returned.
+type ResumeStatsQuery struct {
+	Since     time.Time
+	Until     time.Time
+	Referrers int
+}
+
+// ResumeDailyDownloads is the number of downloads
+// of each format of the resume on a single day
+type ResumeDailyDownloads struct {
+	// Date is the day in UTC, of the form YYYY-MM-DD
+	Date    string                   `json:"date"`
+	Formats map[ResumeFileFormat]int `json:"formats"`
+}
+
+type ReferrerCount struct {
+	Referrer string `json:"referrer"`
+	Count    int    `json:"count"`
+}
+
+type ResumeStats struct {
+	Since          time.Time `json:"since"`
+	Until          time.Time `json:"until"`
+	TotalDownloads int       `json:"total_downloads"`
+	// UniqueDownloaders is the number of distinct IP addresses
+	UniqueDownloaders int                    `json:"unique_downloaders"`
+	Daily             []ResumeDailyDownloads `json:"daily"`
+	TopReferrers      []ReferrerCount        `json:"top_referrers"`
 }
 
 type ResumeFileFormat string
 
+// mimePDF is the content type of PDF resumes
+const mimePDF = "application/pdf"
+
+const (
+	ResumeFormatPDF      ResumeFileFormat = "pdf"
+	ResumeFormatJSON     ResumeFileFormat = "json"
+	ResumeFormatHTML     ResumeFileFormat = "html"
+	ResumeFormatMarkdown ResumeFileFormat = "markdown"
+	ResumeFormatText     ResumeFileFormat = "txt"
+)
+
+// resumeFormats lists the supported resume formats in order
+// of preference, used to break ties when negotiating
+var resumeFormats = []ResumeFileFormat{
+	ResumeFormatJSON,
+	ResumeFormatPDF,
+	ResumeFormatHTML,
+	ResumeFormatMarkdown,
+	ResumeFormatText,
+}
+
+// MediaType returns the media type the format is served as.
+func (f ResumeFileFormat) MediaType() string {
+	switch f {
+	case ResumeFormatPDF:
+		return mimePDF
+	case ResumeFormatHTML:
+		return "text/html"
+	case ResumeFormatMarkdown:
+		return "text/markdown"
+	case ResumeFormatText:
+		return "text/plain"
+	default:
+		return "application/json"
+	}
+}
+
+// EventType is the type of an event sent to webhook subscriptions.
+type EventType string
+
+const (
+	EventContactCreated        EventType = "contact.created"
+	EventContactRequestCreated EventType = "contact_request.created"
+	EventAPIKeyUsed            EventType = "api_key.used"
+)
+
+// WebhookSubscription is a URL that receives the given
+// event types, signed using the subscription secret.
+type WebhookSubscription struct {
+	Name   string      `json:"name" validate:"required"`
+	URL    string      `json:"url" validate:"required,http_url"`
+	Secret string      `json:"secret,omitempty" validate:"required"`
+	Events []EventType `json:"events" validate:"required,min=1,dive,oneof=contact.created contact_request.created api_key.used"`
+}
+
+type WebhookDeliveryStatus string
+
 const (
-	ResumeFormatPDF  ResumeFileFormat = "pdf"
-	ResumeFormatJSON ResumeFileFormat = "json"
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
 )
+
+// WebhookDelivery is a single event queued for delivery to a
+// subscription, along with the outcome of the latest attempt.
+type WebhookDelivery struct {
+	Id             string                `json:"id"`
+	Subscription   string                `json:"subscription"`
+	EventId        string                `json:"event_id"`
+	EventType      EventType             `json:"event_type"`
+	Payload        json.RawMessage       `json:"payload"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	ResponseStatus int                   `json:"response_status"`
+	LastError      string                `json:"last_error"`
+	NextAttemptAt  time.Time             `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time            `json:"last_attempt_at"`
+	CreatedAt      time.Time             `json:"created_at"`
+}
+
+// ResumeVersion is a JSON or PDF resume uploaded by an admin. The
+// active version of each variant, locale and format is served instead
+// of the resume files.
+type ResumeVersion struct {
+	Id      string           `json:"id"`
+	Variant string           `json:"variant"`
+	Locale  string           `json:"locale"`
+	Format  ResumeFileFormat `json:"format"`
+	// Content is omitted when listing versions
+	Content   []byte    `json:"content,omitempty"`
+	Hash      string    `json:"hash"`
+	Size      int       `json:"size"`
+	Author    string    `json:"author"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	// ActivatedAt is when the version was last made active
+	ActivatedAt time.Time `json:"activated_at"`
+}
+
+// ResumeShare is a private link to a variant of the resume in a given
+// format, along with how often the link was opened.
+type ResumeShare struct {
+	Id      string           `json:"id"`
+	Variant string           `json:"variant"`
+	Locale  string           `json:"locale"`
+	Format  ResumeFileFormat `json:"format"`
+	// Recipient is a note of who the link was sent to
+	Recipient string `json:"recipient"`
+	// SingleUse links can only be opened once
+	SingleUse     bool       `json:"single_use"`
+	CreatedBy     string     `json:"created_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	Opens         int        `json:"opens"`
+	FirstOpenedAt *time.Time `json:"first_opened_at"`
+	LastOpenedAt  *time.Time `json:"last_opened_at"`
+	// Token is the signed token of the link, which is not stored
+	Token string `json:"token,omitempty"`
+}
+
+// withoutContent returns a copy of the version without its content
+func (v ResumeVersion) withoutContent() ResumeVersion {
+	v.Content = nil
+	return v
+}
+
+type PersistenceBackend string
+
+const (
+	PersistenceBackendPostgres PersistenceBackend = "postgres"
+	PersistenceBackendMemory   PersistenceBackend = "memory"
+	PersistenceBackendSQLite   PersistenceBackend = "sqlite"
+)
+
+// ListCursor identifies the position of the last item returned
+// in a page of results. Results are ordered by creation time,
+// with the ID used to break ties.
+type ListCursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	Id        string    `json:"id"`
+}
+
+// ListQuery contains the pagination, sorting and filtering
+// options used when listing contacts and contact requests.
+type ListQuery struct {
+	Limit      int
+	Cursor     *ListCursor
+	Descending bool
+	Email      string
+	ContactId  string
+	Status     ContactRequestStatus
+	Since      *time.Time
+	Until      *time.Time
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestContactRequestStatus(t *testing.T) {
+	cases := []struct {
+		from     ContactRequestStatus
+		to       ContactRequestStatus
+		expected bool
+	}{
+		{ContactRequestStatusNew, ContactRequestStatusRead, true},
+		{ContactRequestStatusNew, ContactRequestStatusSpam, true},
+		{ContactRequestStatusRead, ContactRequestStatusNew, true},
+		{ContactRequestStatusReplied, ContactRequestStatusArchived, true},
+		{ContactRequestStatusReplied, ContactRequestStatusNew, false},
+		{ContactRequestStatusArchived, ContactRequestStatusRead, true},
+		{ContactRequestStatusArchived, ContactRequestStatusSpam, false},
+		{ContactRequestStatusSpam, ContactRequestStatusNew, true},
+		{ContactRequestStatusSpam, ContactRequestStatusReplied, false},
+		{ContactRequestStatusArchived, ContactRequestStatusArchived, true},
+	}
+
+	for _, tc := range cases {
+		if actual := tc.from.CanTransitionTo(tc.to); actual != tc.expected {
+			t.Errorf("Expected transition from %s to %s to be %t, got %t", tc.from, tc.to, tc.expected, actual)
+		}
+	}
+
+	if ContactRequestStatus("deleted").Valid() {
+		t.Errorf("Expected deleted to be an invalid status")
+	}
+}
@@ -1,6 +1,14 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
 
 // PostgresDSNFromConfig constructs a PostgreSQL DSN from the given configuration.
 func PostgresDSNFromConfig(cfg *Config) string {
@@ -13,3 +21,59 @@ func PostgresDSNFromConfig(cfg *Config) string {
 	)
 	return dsn
 }
+
+// PostgresPoolConfigFromConfig constructs a pgxpool configuration from the
+// given configuration. Pool settings left at their zero value fall back
+// to the pgxpool defaults.
+func PostgresPoolConfigFromConfig(cfg *Config) (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(PostgresDSNFromConfig(cfg))
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.PostgresMaxConns > 0 {
+		poolConfig.MaxConns = int32(cfg.PostgresMaxConns)
+	}
+	if cfg.PostgresMaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.PostgresMaxConnIdleTime
+	}
+	if cfg.PostgresHealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = cfg.PostgresHealthCheckPeriod
+	}
+	return poolConfig, nil
+}
+
+// RequestContext returns the context of the HTTP request bound to the
+// given Gin context. Falls back to a background context if no request
+// is bound, which is the case for handlers invoked outside of a router.
+func RequestContext(c *gin.Context) context.Context {
+	if c == nil || c.Request == nil {
+		return context.Background()
+	}
+	return c.Request.Context()
+}
+
+// EncodeListCursor encodes a cursor into an opaque,
+// URL-safe string that can be returned to clients.
+func EncodeListCursor(cursor *ListCursor) string {
+	if cursor == nil {
+		return ""
+	}
+	encoded, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// DecodeListCursor decodes a cursor previously
+// encoded using EncodeListCursor.
+func DecodeListCursor(encoded string) (*ListCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, InvalidCursorError{Cursor: encoded}
+	}
+
+	var cursor ListCursor
+	if err := json.Unmarshal(decoded, &cursor); err != nil || cursor.Id == "" {
+		return nil, InvalidCursorError{Cursor: encoded}
+	}
+	return &cursor, nil
+}
@@ -1,6 +1,9 @@
 package main
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestPostgresDSNFromConfig(t *testing.T) {
 	config := &Config{
@@ -18,3 +21,33 @@ func TestPostgresDSNFromConfig(t *testing.T) {
 		t.Errorf("Expected DSN %s, but got %s", expectedDSN, actualDSN)
 	}
 }
+
+func TestPostgresPoolConfigFromConfig(t *testing.T) {
+	config := &Config{
+		PostgresUser:              "testuser",
+		PostgresPassword:          "testpass",
+		PostgresHost:              "localhost",
+		PostgresPort:              5432,
+		PostgresDatabase:          "testdb",
+		PostgresMaxConns:          4,
+		PostgresMaxConnIdleTime:   2 * time.Minute,
+		PostgresHealthCheckPeriod: 30 * time.Second,
+	}
+
+	poolConfig, err := PostgresPoolConfigFromConfig(config)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if poolConfig.MaxConns != 4 {
+		t.Errorf("Expected MaxConns 4, got %d", poolConfig.MaxConns)
+	}
+
+	if poolConfig.MaxConnIdleTime != 2*time.Minute {
+		t.Errorf("Expected MaxConnIdleTime 2m, got %s", poolConfig.MaxConnIdleTime)
+	}
+
+	if poolConfig.HealthCheckPeriod != 30*time.Second {
+		t.Errorf("Expected HealthCheckPeriod 30s, got %s", poolConfig.HealthCheckPeriod)
+	}
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// webhookBatchSize is the number of deliveries
+	// claimed from the queue at a time
+	webhookBatchSize = 20
+	// webhookMaxBackoff caps the delay between retries
+	webhookMaxBackoff = time.Hour
+	// defaultWebhookPollInterval is used if no
+	// poll interval is set on the dispatcher
+	defaultWebhookPollInterval = 5 * time.Second
+	// webhookSignatureHeader contains the HMAC-SHA256
+	// signature of the timestamp and request body
+	webhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// EventPublisher publishes events to webhook subscriptions.
+// Publishing never fails the caller, errors are only logged.
+type EventPublisher interface {
+	Publish(ctx context.Context, eventType EventType, data any)
+	Subscriptions() []WebhookSubscription
+	// Close stops delivering events, waiting for in-flight
+	// deliveries to complete or for the context to be done.
+	Close(ctx context.Context) error
+}
+
+// NoopPublisher discards all events. It is used
+// when no webhook subscriptions are configured.
+type NoopPublisher struct{}
+
+func (NoopPublisher) Publish(ctx context.Context, eventType EventType, data any) {}
+
+func (NoopPublisher) Subscriptions() []WebhookSubscription {
+	return nil
+}
+
+func (NoopPublisher) Close(ctx context.Context) error {
+	return nil
+}
+
+// WebhookEvent is the JSON body sent to webhook subscriptions.
+type WebhookEvent struct {
+	Id        string    `json:"id"`
+	Type      EventType `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// LoadWebhookSubscriptions reads and validates the webhook
+// subscriptions defined in the given JSON file.
+func LoadWebhookSubscriptions(path string) ([]WebhookSubscription, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var subscriptions []WebhookSubscription
+	if err := json.Unmarshal(contents, &subscriptions); err != nil {
+		return nil, err
+	}
+
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	names := make(map[string]bool)
+	for _, subscription := range subscriptions {
+		if err := validate.Struct(subscription); err != nil {
+			return nil, fmt.Errorf("invalid webhook subscription %q: %w", subscription.Name, err)
+		}
+		// deliveries reference subscriptions by name
+		if names[subscription.Name] {
+			return nil, fmt.Errorf("duplicate webhook subscription %q", subscription.Name)
+		}
+		names[subscription.Name] = true
+	}
+	return subscriptions, nil
+}
+
+// SignWebhookPayload returns the signature sent with a webhook request.
+// The signature is the hex encoded HMAC-SHA256 of "{timestamp}.{body}",
+// keyed with the subscription secret.
+func SignWebhookPayload(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookDispatcher queues events for each matching subscription
+// in the persistent delivery queue, and delivers queued events from
+// a background worker. Failed deliveries are retried with an
+// exponential backoff until MaxAttempts is reached.
+type WebhookDispatcher struct {
+	DB           Persistence
+	Client       *http.Client
+	MaxAttempts  int
+	Backoff      time.Duration
+	PollInterval time.Duration
+
+	subscriptions []WebhookSubscription
+	wake          chan struct{}
+	stop          chan struct{}
+	done          chan struct{}
+	ctx           context.Context
+	cancel        context.CancelFunc
+}
+
+// NewEventPublisher creates the EventPublisher configured in the given
+// config. A NoopPublisher is returned if no subscriptions are configured.
+func NewEventPublisher(cfg *Config, db Persistence) (EventPublisher, error) {
+	if cfg.WebhooksPath == "" {
+		return NoopPublisher{}, nil
+	}
+
+	subscriptions, err := LoadWebhookSubscriptions(cfg.WebhooksPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook subscriptions: %w", err)
+	}
+
+	dispatcher := &WebhookDispatcher{
+		DB:           db,
+		Client:       &http.Client{Timeout: cfg.WebhookTimeout},
+		MaxAttempts:  cfg.WebhookMaxAttempts,
+		Backoff:      cfg.WebhookRetryBackoff,
+		PollInterval: cfg.WebhookPollInterval,
+	}
+	dispatcher.Start(subscriptions)
+	return dispatcher, nil
+}
+
+// Start starts the background worker delivering queued events
+// to the given subscriptions.
+func (d *WebhookDispatcher) Start(subscriptions []WebhookSubscription) {
+	d.subscriptions = subscriptions
+	d.wake = make(chan struct{}, 1)
+	d.stop = make(chan struct{})
+	d.done = make(chan struct{})
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+
+	go func() {
+		defer close(d.done)
+
+		interval := d.PollInterval
+		if interval <= 0 {
+			interval = defaultWebhookPollInterval
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			d.dispatch()
+			select {
+			case <-d.stop:
+				return
+			case <-ticker.C:
+			case <-d.wake:
+			}
+		}
+	}()
+}
+
+// Subscriptions returns the configured subscriptions.
+func (d *WebhookDispatcher) Subscriptions() []WebhookSubscription {
+	return d.subscriptions
+}
+
+// Publish queues an event for every subscription to the event type,
+// and wakes the worker to deliver it.
+func (d *WebhookDispatcher) Publish(ctx context.Context, eventType EventType, data any) {
+	event := WebhookEvent{
+		Id:        strings.ReplaceAll(uuid.New().String(), "-", ""),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+
+	var deliveries []WebhookDelivery
+	var payload []byte
+	for _, subscription := range d.subscriptions {
+		if !slices.Contains(subscription.Events, eventType) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(event); err != nil {
+				log.Error(fmt.Sprintf("failed to encode %s event: %v", eventType, err))
+				return
+			}
+		}
+		deliveries = append(deliveries, WebhookDelivery{
+			Subscription: subscription.Name,
+			EventId:      event.Id,
+			EventType:    eventType,
+			Payload:      payload,
+		})
+	}
+	if len(deliveries) == 0 {
+		return
+	}
+
+	if err := d.DB.CreateWebhookDeliveries(ctx, deliveries); err != nil {
+		log.Error(fmt.Sprintf("failed to queue %s event: %v", eventType, err))
+		return
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close stops the worker once the current batch of deliveries has been
+// attempted. In-flight deliveries are cancelled once the context is done,
+// and are retried by the next worker to start.
+func (d *WebhookDispatcher) Close(ctx context.Context) error {
+	select {
+	case <-d.stop:
+	default:
+		close(d.stop)
+	}
+
+	select {
+	case <-d.done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-d.done
+		return ctx.Err()
+	}
+}
+
+// lease is the time a claimed delivery is hidden from other workers.
+// it must be long enough for a batch of deliveries to be attempted.
+func (d *WebhookDispatcher) lease() time.Duration {
+	return max(d.Client.Timeout*webhookBatchSize, time.Minute)
+}
+
+// dispatch delivers all due deliveries, one batch at a time.
+func (d *WebhookDispatcher) dispatch() {
+	for {
+		deliveries, err := d.DB.ClaimWebhookDeliveries(d.ctx, webhookBatchSize, d.lease())
+		if err != nil {
+			log.Error(fmt.Sprintf("failed to claim webhook deliveries: %v", err))
+			return
+		}
+
+		for _, delivery := range deliveries {
+			delivery = d.deliver(delivery)
+			// record the outcome even if the dispatcher is closing
+			if err := d.DB.UpdateWebhookDelivery(context.WithoutCancel(d.ctx), delivery); err != nil {
+				log.Error(fmt.Sprintf("failed to update webhook delivery %s: %v", delivery.Id, err))
+			}
+		}
+
+		if len(deliveries) < webhookBatchSize {
+			return
+		}
+		select {
+		case <-d.stop:
+			return
+		default:
+		}
+	}
+}
+
+// retryDelay returns the delay before the next attempt,
+// doubling the backoff after every failed attempt.
+func (d *WebhookDispatcher) retryDelay(attempts int) time.Duration {
+	delay := d.Backoff
+	for i := 1; i < attempts && delay < webhookMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookMaxBackoff)
+}
+
+// deliver attempts a single delivery and returns the
+// delivery updated with the outcome of the attempt.
+func (d *WebhookDispatcher) deliver(delivery WebhookDelivery) WebhookDelivery {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+
+	index := slices.IndexFunc(d.subscriptions, func(subscription WebhookSubscription) bool {
+		return subscription.Name == delivery.Subscription
+	})
+	if index < 0 {
+		delivery.Status = WebhookDeliveryFailed
+		delivery.LastError = "subscription is no longer configured"
+		return delivery
+	}
+	subscription := d.subscriptions[index]
+
+	status, err := d.send(subscription, delivery)
+	delivery.ResponseStatus = status
+	if err == nil {
+		log.Info(fmt.Sprintf("delivered %s event %s to %s", delivery.EventType, delivery.EventId, subscription.Name))
+		delivery.Status = WebhookDeliveryDelivered
+		delivery.LastError = ""
+		return delivery
+	}
+
+	log.Warn(fmt.Sprintf("attempt %d to deliver %s event %s to %s failed: %v",
+		delivery.Attempts, delivery.EventType, delivery.EventId, subscription.Name, err))
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= d.MaxAttempts {
+		delivery.Status = WebhookDeliveryFailed
+		return delivery
+	}
+	delivery.NextAttemptAt = now.Add(d.retryDelay(delivery.Attempts))
+	return delivery
+}
+
+// send POSTs a delivery to a subscription, returning the response status.
+// Any response other than a 2xx is treated as a failure.
+func (d *WebhookDispatcher) send(subscription WebhookSubscription, delivery WebhookDelivery) (int, error) {
+	request, err := http.NewRequestWithContext(d.ctx, "POST", subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "personal-website-webhooks")
+	request.Header.Set("X-Webhook-Id", delivery.EventId)
+	request.Header.Set("X-Webhook-Event", string(delivery.EventType))
+	request.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	request.Header.Set(webhookSignatureHeader, SignWebhookPayload(subscription.Secret, timestamp, delivery.Payload))
+
+	response, err := d.Client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	// drain the body so that the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(response.Body, 1<<16))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("unexpected response status %d", response.StatusCode)
+	}
+	return response.StatusCode, nil
+}
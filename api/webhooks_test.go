@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestPublisher records all events it receives.
+type TestPublisher struct {
+	mu     sync.Mutex
+	Events []WebhookEvent
+}
+
+func (t *TestPublisher) Publish(ctx context.Context, eventType EventType, data any) {
+	// deliveries cannot be stored once the context is cancelled
+	if ctx.Err() != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Events = append(t.Events, WebhookEvent{Type: eventType, Data: data})
+}
+
+func (t *TestPublisher) Subscriptions() []WebhookSubscription {
+	return []WebhookSubscription{{
+		Name:   "crm",
+		URL:    "https://example.com/hooks",
+		Secret: "secret",
+		Events: []EventType{EventContactCreated},
+	}}
+}
+
+func (t *TestPublisher) Close(ctx context.Context) error {
+	return nil
+}
+
+// testWebhookServer records the requests it receives. The
+// first failures requests are rejected with a 500.
+type testWebhookServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	failures int
+}
+
+func newTestWebhookServer(t *testing.T, failures int) *testWebhookServer {
+	t.Helper()
+
+	server := &testWebhookServer{failures: failures}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		server.requests = append(server.requests, r)
+		server.bodies = append(server.bodies, body)
+		if server.failures > 0 {
+			server.failures--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (s *testWebhookServer) Requests() ([]*http.Request, [][]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*http.Request{}, s.requests...), append([][]byte{}, s.bodies...)
+}
+
+func newTestWebhookDispatcher(t *testing.T, db Persistence, subscriptions []WebhookSubscription) *WebhookDispatcher {
+	t.Helper()
+
+	dispatcher := &WebhookDispatcher{
+		DB:           db,
+		Client:       &http.Client{Timeout: time.Second},
+		MaxAttempts:  3,
+		Backoff:      time.Millisecond,
+		PollInterval: 10 * time.Millisecond,
+	}
+	dispatcher.Start(subscriptions)
+	t.Cleanup(func() { dispatcher.Close(context.Background()) })
+	return dispatcher
+}
+
+// waitForDeliveries waits until no deliveries are pending,
+// and returns all deliveries.
+func waitForDeliveries(t *testing.T, db Persistence) []WebhookDelivery {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		deliveries, _, err := db.QueryWebhookDeliveries(context.Background(), ListQuery{Limit: 100})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		pending := false
+		for _, delivery := range deliveries {
+			pending = pending || delivery.Status == WebhookDeliveryPending
+		}
+		if len(deliveries) > 0 && !pending {
+			return deliveries
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected deliveries to complete, got %+v", deliveries)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWebhookDispatcher(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Delivers Signed Events", func(t *testing.T) {
+		server := newTestWebhookServer(t, 0)
+		db, _ := NewMemoryPersistence("")
+		dispatcher := newTestWebhookDispatcher(t, db, []WebhookSubscription{
+			{Name: "crm", URL: server.URL, Secret: "secret", Events: []EventType{EventContactCreated}},
+			{Name: "audit", URL: server.URL, Secret: "other", Events: []EventType{EventAPIKeyUsed}},
+		})
+
+		dispatcher.Publish(ctx, EventContactCreated, Contact{Id: "1", Name: "Alice", Email: "alice@example.com"})
+
+		deliveries := waitForDeliveries(t, db)
+		if len(deliveries) != 1 || deliveries[0].Subscription != "crm" {
+			t.Fatalf("Expected 1 delivery to crm, got %+v", deliveries)
+		}
+
+		if deliveries[0].Status != WebhookDeliveryDelivered || deliveries[0].ResponseStatus != 204 {
+			t.Errorf("Expected delivered with status 204, got %+v", deliveries[0])
+		}
+
+		requests, bodies := server.Requests()
+		if len(requests) != 1 {
+			t.Fatalf("Expected 1 request, got %d", len(requests))
+		}
+
+		request := requests[0]
+		if request.Header.Get("X-Webhook-Event") != string(EventContactCreated) {
+			t.Errorf("Expected event header %s, got %s", EventContactCreated, request.Header.Get("X-Webhook-Event"))
+		}
+
+		timestamp, err := strconv.ParseInt(request.Header.Get("X-Webhook-Timestamp"), 10, 64)
+		if err != nil {
+			t.Fatalf("Expected timestamp header, got %v", err)
+		}
+
+		expected := SignWebhookPayload("secret", timestamp, bodies[0])
+		if signature := request.Header.Get(webhookSignatureHeader); signature != expected {
+			t.Errorf("Expected signature %s, got %s", expected, signature)
+		}
+
+		var event WebhookEvent
+		if err := json.Unmarshal(bodies[0], &event); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if event.Id != request.Header.Get("X-Webhook-Id") || event.Type != EventContactCreated {
+			t.Errorf("Expected %s event with id header, got %+v", EventContactCreated, event)
+		}
+	})
+
+	t.Run("Retries Failed Deliveries", func(t *testing.T) {
+		server := newTestWebhookServer(t, 2)
+		db, _ := NewMemoryPersistence("")
+		dispatcher := newTestWebhookDispatcher(t, db, []WebhookSubscription{
+			{Name: "crm", URL: server.URL, Secret: "secret", Events: []EventType{EventContactCreated}},
+		})
+
+		dispatcher.Publish(ctx, EventContactCreated, Contact{Id: "1"})
+
+		deliveries := waitForDeliveries(t, db)
+		if deliveries[0].Status != WebhookDeliveryDelivered || deliveries[0].Attempts != 3 {
+			t.Errorf("Expected delivered after 3 attempts, got %+v", deliveries[0])
+		}
+
+		if deliveries[0].LastError != "" {
+			t.Errorf("Expected last error to be cleared, got %s", deliveries[0].LastError)
+		}
+	})
+
+	t.Run("Gives Up After Max Attempts", func(t *testing.T) {
+		server := newTestWebhookServer(t, 5)
+		db, _ := NewMemoryPersistence("")
+		dispatcher := newTestWebhookDispatcher(t, db, []WebhookSubscription{
+			{Name: "crm", URL: server.URL, Secret: "secret", Events: []EventType{EventContactCreated}},
+		})
+
+		dispatcher.Publish(ctx, EventContactCreated, Contact{Id: "1"})
+
+		deliveries := waitForDeliveries(t, db)
+		if deliveries[0].Status != WebhookDeliveryFailed || deliveries[0].Attempts != 3 {
+			t.Errorf("Expected failed after 3 attempts, got %+v", deliveries[0])
+		}
+
+		if deliveries[0].ResponseStatus != 500 || deliveries[0].LastError == "" {
+			t.Errorf("Expected last response status 500 with error, got %+v", deliveries[0])
+		}
+	})
+
+	t.Run("Removed Subscription", func(t *testing.T) {
+		db, _ := NewMemoryPersistence("")
+		if err := db.CreateWebhookDeliveries(ctx, []WebhookDelivery{
+			{Subscription: "removed", EventId: "1", EventType: EventContactCreated, Payload: []byte(`{}`)},
+		}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		newTestWebhookDispatcher(t, db, nil)
+
+		deliveries := waitForDeliveries(t, db)
+		if deliveries[0].Status != WebhookDeliveryFailed {
+			t.Errorf("Expected delivery to removed subscription to fail, got %+v", deliveries[0])
+		}
+	})
+}
+
+func TestRetryDelay(t *testing.T) {
+	dispatcher := &WebhookDispatcher{Backoff: 30 * time.Second}
+
+	tests := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		20: webhookMaxBackoff,
+	}
+	for attempts, expected := range tests {
+		if delay := dispatcher.retryDelay(attempts); delay != expected {
+			t.Errorf("Expected delay %s after %d attempts, got %s", expected, attempts, delay)
+		}
+	}
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	signature := SignWebhookPayload("secret", 1700000000, []byte(`{"id":"1"}`))
+
+	if signature != SignWebhookPayload("secret", 1700000000, []byte(`{"id":"1"}`)) {
+		t.Errorf("Expected signatures to be deterministic")
+	}
+
+	if signature == SignWebhookPayload("other", 1700000000, []byte(`{"id":"1"}`)) {
+		t.Errorf("Expected signature to depend on the secret")
+	}
+
+	if signature == SignWebhookPayload("secret", 1700000001, []byte(`{"id":"1"}`)) {
+		t.Errorf("Expected signature to depend on the timestamp")
+	}
+
+	if len(signature) != len("sha256=")+64 {
+		t.Errorf("Expected sha256 prefixed hex signature, got %s", signature)
+	}
+}
+
+func TestLoadWebhookSubscriptions(t *testing.T) {
+	write := func(t *testing.T, contents string) string {
+		path := filepath.Join(t.TempDir(), "webhooks.json")
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return path
+	}
+
+	t.Run("Valid Subscriptions", func(t *testing.T) {
+		path := write(t, `[{"name": "crm", "url": "https://example.com/hooks", "secret": "secret", "events": ["contact.created", "contact_request.created"]}]`)
+
+		subscriptions, err := LoadWebhookSubscriptions(path)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(subscriptions) != 1 || len(subscriptions[0].Events) != 2 {
+			t.Errorf("Expected 1 subscription with 2 events, got %+v", subscriptions)
+		}
+	})
+
+	invalid := map[string]string{
+		"Unknown Event":   `[{"name": "crm", "url": "https://example.com", "secret": "s", "events": ["contact.deleted"]}]`,
+		"Missing Secret":  `[{"name": "crm", "url": "https://example.com", "events": ["contact.created"]}]`,
+		"Invalid URL":     `[{"name": "crm", "url": "example", "secret": "s", "events": ["contact.created"]}]`,
+		"No Events":       `[{"name": "crm", "url": "https://example.com", "secret": "s", "events": []}]`,
+		"Duplicate Names": `[{"name": "crm", "url": "https://example.com", "secret": "s", "events": ["contact.created"]}, {"name": "crm", "url": "https://example.org", "secret": "s", "events": ["contact.created"]}]`,
+		"Invalid JSON":    `{`,
+	}
+	for name, contents := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadWebhookSubscriptions(write(t, contents)); err == nil {
+				t.Errorf("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestNewEventPublisher(t *testing.T) {
+	publisher, err := NewEventPublisher(&Config{}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, ok := publisher.(NoopPublisher); !ok {
+		t.Errorf("Expected NoopPublisher without subscriptions, got %T", publisher)
+	}
+}
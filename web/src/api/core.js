@@ -11,11 +11,68 @@ export const fetchCV = async (format) => {
   return apiClient.get(`/resume?format=${format}`)
 }
 
+export const fetchCVSection = async (name, params = {}) => {
+  return apiClient.get(`/resume/sections/${name}`, { params })
+}
+
+export const fetchCVFile = async () => {
+  return apiClient.get('/resume?format=pdf&download=true', {
+    responseType: 'blob',
+    headers: { Accept: 'application/pdf' }
+  })
+}
+
+export const fetchContactToken = async () => {
+  return apiClient.get('/contacts/token')
+}
+
+export const fetchChallenge = async () => {
+  return apiClient.get('/challenge')
+}
+
+// leadingZeroBits counts the zero bits at the start of a hash
+const leadingZeroBits = (hash) => {
+  let count = 0
+  for (const byte of hash) {
+    if (byte === 0) {
+      count += 8
+      continue
+    }
+    return count + Math.clz32(byte) - 24
+  }
+  return count
+}
+
+// solveChallenge finds a nonce for which the SHA-256 hash of
+// "{challenge}:{nonce}" starts with the required number of zero
+// bits. hashes are computed in batches to reduce the overhead
+// of the asynchronous digest calls
+export const solveChallenge = async (challenge, difficulty) => {
+  const encoder = new TextEncoder()
+  const batchSize = 256
+  for (let start = 0; ; start += batchSize) {
+    const nonces = Array.from({ length: batchSize }, (_, i) => String(start + i))
+    const hashes = await Promise.all(
+      nonces.map((nonce) =>
+        crypto.subtle.digest('SHA-256', encoder.encode(`${challenge}:${nonce}`)),
+      ),
+    )
+    const index = hashes.findIndex((hash) => leadingZeroBits(new Uint8Array(hash)) >= difficulty)
+    if (index !== -1) {
+      return nonces[index]
+    }
+  }
+}
+
 export const createContact = async (contactData) => {
   const payload = {
     email: contactData.email,
     name: contactData.name,
     message: contactData.message,
+    website: contactData.website,
+    token: contactData.token,
+    challenge: contactData.challenge,
+    nonce: contactData.nonce,
   }
   return apiClient.post('/contacts', payload)
 }
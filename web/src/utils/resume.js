@@ -0,0 +1,20 @@
+const MONTHS = ['Jan', 'Feb', 'Mar', 'Apr', 'May', 'Jun', 'Jul', 'Aug', 'Sep', 'Oct', 'Nov', 'Dec']
+
+// formats a JSON Resume date (YYYY, YYYY-MM or YYYY-MM-DD) as e.g. "Oct 2022"
+const formatDate = (date) => {
+  const [year, month] = date.split('-')
+  return month ? `${MONTHS[Number(month) - 1]} ${year}` : year
+}
+
+// formats start and end dates as e.g. "Oct 2022 - Present"
+export const formatDateRange = (startDate, endDate) => {
+  if (!startDate) {
+    return endDate ? formatDate(endDate) : ''
+  }
+  return `${formatDate(startDate)} - ${endDate ? formatDate(endDate) : 'Present'}`
+}
+
+// formats the type and area of study, e.g. "BSc Physics"
+export const formatDegree = (education) => {
+  return [education.studyType, education.area].filter(Boolean).join(' ')
+}